@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"database/sql"
+	"slices"
 	"strconv"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
@@ -21,6 +22,11 @@ var (
 	backupCleanOlderThenDays     uint
 	backupCleanParallelProcesses int
 	backupCleanCascade           bool
+	backupCleanDryRun            bool
+	backupCleanRetention         gpbckpconfig.RetentionPolicy
+	backupCleanYes               bool
+	backupCleanDatabase          []string
+	backupCleanExcludeDatabase   []string
 )
 
 var backupCleanCmd = &cobra.Command{
@@ -31,7 +37,22 @@ var backupCleanCmd = &cobra.Command{
 To delete backup sets older than the given timestamp, use the --before-timestamp option. 
 To delete backup sets older than the given number of days, use the --older-than-day option.
 To delete backup sets newer than the given timestamp, use the --after-timestamp option.
-Only --older-than-days, --before-timestamp or --after-timestamp option must be specified.
+
+To delete backup sets that are not covered by the retention policy, use the --keep-full, --keep-daily, --keep-weekly and --keep-monthly options.
+The retention policy is applied for each database and storage separately. Only local backups or only backups made with the storage plugin are taken into account:
+  * The --keep-full option keeps the given number of the latest full backups.
+  * The --keep-daily, --keep-weekly and --keep-monthly options keep the latest full backup of each of the given number of the latest days, weeks or months with full backups.
+  * The retention options can be combined, a full backup is kept if at least one of the options keeps it.
+  * Incremental backups are kept or deleted together with the full backup they are based on, so the backup chain is never cut in the middle.
+    For the retention policy, the --cascade option is not required.
+  * Other backups (metadata-only, data-only, failed) are deleted if they are older than the oldest kept full backup.
+  * If there is no successful full backup for the database, the backups of this database are not deleted.
+
+Only --older-than-days, --before-timestamp, --after-timestamp or retention options must be specified.
+
+To delete only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together.
 
 By default, the existence of dependent backups is checked and deletion process is not performed,
 unless the --cascade option is passed in.
@@ -47,6 +68,9 @@ For local backups the following logic are applied:
   * If backup is not local, the error will be returned.
 
 For control over the number of parallel processes and ssh connections to delete local backups, the --parallel-processes option can be used.
+For backups made with the storage plugin, the --parallel-processes option sets the number of backups deleted in parallel.
+Dependent backups are always deleted before the backups they depend on. If the deletion of a dependent backup fails,
+the backups it depends on are not deleted.
 
 The storage plugin config file location can be set using the --plugin-config option.
 The full path to the file is required. In this case, the deletion will be performed using the storage plugin.
@@ -55,6 +79,17 @@ For non local backups the following logic are applied:
   * If the --plugin-config option is specified, the deletion will be performed using the storage plugin.
   * If backup is local, the error will be returned.
 
+To display the deletion plan without deleting anything, use the --dry-run option.
+In this mode, the same checks are performed and the backups, dependent backups, hosts, paths and plugin commands
+that would be used for deletion are displayed. Neither the backup storage nor the history database is changed.
+
+Before the deletion, the backups that will be deleted, including dependent backups, their number
+and the affected databases are displayed and the confirmation is requested.
+To skip the confirmation, use the --yes option. If the command is run non-interactively,
+the --yes option is required, otherwise the deletion process is not performed.
+After the confirmation, the history database is locked and the backups for deletion are checked again.
+If the list of backups was changed by another command in the meantime, nothing is deleted.
+
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
 If the --history-db option is not specified, the history database will be searched in the current directory.`,
@@ -108,9 +143,58 @@ func init() {
 		&backupCleanParallelProcesses,
 		parallelProcessesFlagName,
 		1,
-		"the number of parallel processes to delete local backups",
+		"the number of parallel processes to delete backups",
+	)
+	backupCleanCmd.PersistentFlags().BoolVar(
+		&backupCleanDryRun,
+		dryRunFlagName,
+		false,
+		"show the deletion plan without deleting backups",
+	)
+	backupCleanCmd.PersistentFlags().UintVar(
+		&backupCleanRetention.KeepFull,
+		keepFullFlagName,
+		0,
+		"keep the given number of the latest full backups with their incremental backups",
+	)
+	backupCleanCmd.PersistentFlags().UintVar(
+		&backupCleanRetention.KeepDaily,
+		keepDailyFlagName,
+		0,
+		"keep the latest full backup for the given number of days",
+	)
+	backupCleanCmd.PersistentFlags().UintVar(
+		&backupCleanRetention.KeepWeekly,
+		keepWeeklyFlagName,
+		0,
+		"keep the latest full backup for the given number of weeks",
+	)
+	backupCleanCmd.PersistentFlags().UintVar(
+		&backupCleanRetention.KeepMonthly,
+		keepMonthlyFlagName,
+		0,
+		"keep the latest full backup for the given number of months",
+	)
+	backupCleanCmd.PersistentFlags().BoolVar(
+		&backupCleanYes,
+		yesFlagName,
+		false,
+		"delete backups without confirmation",
+	)
+	backupCleanCmd.PersistentFlags().StringArrayVar(
+		&backupCleanDatabase,
+		databaseFlagName,
+		[]string{},
+		"delete only backups of the specified database, could be specified multiple times",
+	)
+	backupCleanCmd.PersistentFlags().StringArrayVar(
+		&backupCleanExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not delete backups of the specified database, could be specified multiple times",
 	)
 	backupCleanCmd.MarkFlagsMutuallyExclusive(beforeTimestampFlagName, olderThenDaysFlagName, afterTimestampFlagName)
+	backupCleanCmd.MarkFlagsMutuallyExclusive(databaseFlagName, excludeDatabaseFlagName)
 }
 
 // These flag checks are applied only for backup-clean command.
@@ -137,6 +221,18 @@ func doCleanBackupFlagValidation(flags *pflag.FlagSet) {
 		}
 		afterTimestamp = backupCleanAfterTimestamp
 	}
+	// If retention flags are specified and have correct values.
+	for _, flagName := range []string{keepFullFlagName, keepDailyFlagName, keepWeeklyFlagName, keepMonthlyFlagName} {
+		if flags.Changed(flagName) && flags.Lookup(flagName).Value.String() == "0" {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag("0", flagName, textmsg.ErrorInvalidValueError()))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// Time condition flags and retention flags cannot be used together.
+	if (beforeTimestamp != "" || afterTimestamp != "") && backupCleanRetention.IsSet() {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(textmsg.ErrorIncompatibleFlagsError(), olderThenDaysFlagName, beforeTimestampFlagName, afterTimestampFlagName, keepFullFlagName, keepDailyFlagName, keepWeeklyFlagName, keepMonthlyFlagName))
+		execOSExit(exitErrorCode)
+	}
 	// backup-dir anf plugin-config flags cannot be used together.
 	err = checkCompatibleFlags(flags, backupDirFlagName, pluginConfigFileFlagName)
 	if err != nil {
@@ -148,12 +244,6 @@ func doCleanBackupFlagValidation(flags *pflag.FlagSet) {
 		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(backupCleanParallelProcesses), parallelProcessesFlagName, err))
 		execOSExit(exitErrorCode)
 	}
-	// plugin-config and parallel-precesses flags cannot be used together.
-	err = checkCompatibleFlags(flags, parallelProcessesFlagName, pluginConfigFileFlagName)
-	if err != nil {
-		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, parallelProcessesFlagName, pluginConfigFileFlagName))
-		execOSExit(exitErrorCode)
-	}
 	// If backup-dir flag is specified and it exists and the full path is specified.
 	if flags.Changed(backupDirFlagName) {
 		err = gpbckpconfig.CheckFullPath(backupCleanBackupDir, checkFileExistsConst)
@@ -170,8 +260,8 @@ func doCleanBackupFlagValidation(flags *pflag.FlagSet) {
 			execOSExit(exitErrorCode)
 		}
 	}
-	if beforeTimestamp == "" && afterTimestamp == "" {
-		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorValidationValue(), olderThenDaysFlagName, beforeTimestampFlagName, afterTimestampFlagName))
+	if beforeTimestamp == "" && afterTimestamp == "" && !backupCleanRetention.IsSet() {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorValidationValue(), olderThenDaysFlagName, beforeTimestampFlagName, afterTimestampFlagName, keepFullFlagName, keepDailyFlagName, keepWeeklyFlagName, keepMonthlyFlagName))
 		execOSExit(exitErrorCode)
 	}
 }
@@ -185,7 +275,7 @@ func doCleanBackup() {
 }
 
 func cleanBackup() error {
-	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB))
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
 		return err
@@ -196,18 +286,23 @@ func cleanBackup() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
 		}
 	}()
+	defer closeSSHConnections()
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: backupCleanDatabase,
+		Exclude: backupCleanExcludeDatabase,
+	}
 	if backupCleanPluginConfigFile != "" {
 		pluginConfig, err := utils.ReadPluginConfig(backupCleanPluginConfigFile)
 		if err != nil {
 			gplog.Error("%s", textmsg.ErrorTextUnableReadPluginConfigFile(err))
 			return err
 		}
-		err = backupCleanDBPlugin(backupCleanCascade, beforeTimestamp, afterTimestamp, backupCleanPluginConfigFile, pluginConfig, hDB)
+		err = backupCleanDBPlugin(backupCleanCascade, backupCleanDryRun, beforeTimestamp, afterTimestamp, backupCleanRetention, dbFilter, backupCleanPluginConfigFile, pluginConfig, backupCleanParallelProcesses, backupCleanYes, hDB)
 		if err != nil {
 			return err
 		}
 	} else {
-		err := backupCleanDBLocal(backupCleanCascade, beforeTimestamp, afterTimestamp, backupCleanBackupDir, backupCleanParallelProcesses, hDB)
+		err := backupCleanDBLocal(backupCleanCascade, backupCleanDryRun, beforeTimestamp, afterTimestamp, backupCleanRetention, dbFilter, backupCleanBackupDir, backupCleanParallelProcesses, backupCleanYes, hDB)
 		if err != nil {
 			return err
 		}
@@ -215,8 +310,8 @@ func cleanBackup() error {
 	return nil
 }
 
-func backupCleanDBPlugin(deleteCascade bool, cutOffTimestamp, cutOffAfterTimestamp, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB) error {
-	backupList, err := fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, hDB)
+func backupCleanDBPlugin(deleteCascade, dryRun bool, cutOffTimestamp, cutOffAfterTimestamp string, retention gpbckpconfig.RetentionPolicy, dbFilter gpbckpconfig.DatabaseFilter, pluginConfigPath string, pluginConfig *utils.PluginConfig, maxParallelProcesses int, assumeYes bool, hDB *sql.DB) error {
+	backupList, err := fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, retention, true, dbFilter, hDB)
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
 		return err
@@ -226,7 +321,29 @@ func backupCleanDBPlugin(deleteCascade bool, cutOffTimestamp, cutOffAfterTimesta
 		// Execute deletion for each backup.
 		// Use backupDeleteDBPlugin function from backup-delete command.
 		// Don't use force deletes and ignore errors for mass deletion.
-		err = backupDeleteDBPlugin(backupList, deleteCascade, false, false, pluginConfigPath, pluginConfig, hDB)
+		// The retention policy deletes backup chains entirely, so the cascade deletion is always used.
+		if !dryRun {
+			unlock, err := lockConfirmedBackupClean(backupList, deleteCascade || retention.IsSet(), true, assumeYes, func() ([]string, error) {
+				return fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, retention, true, dbFilter, hDB)
+			}, hDB)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+		if dryRun || maxParallelProcesses <= 1 {
+			err = backupDeleteDBPlugin(backupList, deleteCascade || retention.IsSet(), false, false, dryRun, pluginConfigPath, pluginConfig, hDB)
+			if err != nil {
+				return err
+			}
+			return nil
+		}
+		plan, err := getBackupDeletePlan(backupList, deleteCascade || retention.IsSet(), false, true, hDB)
+		if err != nil {
+			return err
+		}
+		deleter := &backupPluginDeleter{pluginConfigPath: pluginConfigPath, pluginConfig: pluginConfig}
+		err = backupDeleteDBParallel(plan, maxParallelProcesses, false, deleter, hDB)
 		if err != nil {
 			return err
 		}
@@ -236,15 +353,24 @@ func backupCleanDBPlugin(deleteCascade bool, cutOffTimestamp, cutOffAfterTimesta
 	return nil
 }
 
-func backupCleanDBLocal(deleteCascade bool, cutOffTimestamp, cutOffAfterTimestamp, backupDir string, maxParallelProcesses int, hDB *sql.DB) error {
-	backupList, err := fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, hDB)
+func backupCleanDBLocal(deleteCascade, dryRun bool, cutOffTimestamp, cutOffAfterTimestamp string, retention gpbckpconfig.RetentionPolicy, dbFilter gpbckpconfig.DatabaseFilter, backupDir string, maxParallelProcesses int, assumeYes bool, hDB *sql.DB) error {
+	backupList, err := fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, retention, false, dbFilter, hDB)
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
 		return err
 	}
 	if len(backupList) > 0 {
 		gplog.Debug("%s", textmsg.InfoTextBackupDeleteList(backupList))
-		err = backupDeleteDBLocal(backupList, backupDir, deleteCascade, false, false, maxParallelProcesses, hDB)
+		if !dryRun {
+			unlock, err := lockConfirmedBackupClean(backupList, deleteCascade || retention.IsSet(), false, assumeYes, func() ([]string, error) {
+				return fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp, retention, false, dbFilter, hDB)
+			}, hDB)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+		err = backupDeleteDBLocal(backupList, backupDir, deleteCascade || retention.IsSet(), false, false, dryRun, maxParallelProcesses, hDB)
 		if err != nil {
 			return err
 		}
@@ -254,18 +380,46 @@ func backupCleanDBLocal(deleteCascade bool, cutOffTimestamp, cutOffAfterTimestam
 	return nil
 }
 
+// lockConfirmedBackupClean Asks the confirmation for the deletion of the backups
+// and takes the lock of the history database.
+// Under the lock, the list of backups for deletion is fetched again and compared with the original one,
+// and the deletion plan is compared with the confirmed one.
+// The returned function releases the lock.
+func lockConfirmedBackupClean(backupList []string, deleteCascade, skipLocalBackup, assumeYes bool, fetchBackupList func() ([]string, error), hDB *sql.DB) (func(), error) {
+	confirmedList, err := confirmBackupDeletion(backupList, deleteCascade, false, skipLocalBackup, assumeYes, hDB)
+	if err != nil {
+		return nil, err
+	}
+	return lockHistoryDBForChange(getHistoryDBPath(rootHistoryDB), len(confirmedList) == 0, func() error {
+		currentList, err := fetchBackupList()
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+			return err
+		}
+		if !slices.Equal(currentList, backupList) {
+			gplog.Error("%s", textmsg.ErrorBackupDeleteListChangedError())
+			return textmsg.ErrorBackupDeleteListChangedError()
+		}
+		return checkBackupDeleteList(confirmedList, backupList, deleteCascade, false, skipLocalBackup, hDB)
+	})
+}
+
 // Get the list of backup names for deletion.
-func fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp string, hDB *sql.DB) ([]string, error) {
+// For the retention policy, only backups from the same storage are taken into account.
+func fetchBackupNamesForDeletion(cutOffTimestamp, cutOffAfterTimestamp string, retention gpbckpconfig.RetentionPolicy, skipLocalBackup bool, dbFilter gpbckpconfig.DatabaseFilter, hDB *sql.DB) ([]string, error) {
 	var backupList []string
 	var err error
+	if retention.IsSet() {
+		return gpbckpconfig.GetBackupNamesForRetention(retention, skipLocalBackup, dbFilter, hDB)
+	}
 	if cutOffTimestamp != "" {
-		backupList, err = gpbckpconfig.GetBackupNamesBeforeTimestamp(cutOffTimestamp, hDB)
+		backupList, err = gpbckpconfig.GetBackupNamesBeforeTimestamp(cutOffTimestamp, dbFilter, hDB)
 		if err != nil {
 			return nil, err
 		}
 	}
 	if cutOffAfterTimestamp != "" {
-		backupList, err = gpbckpconfig.GetBackupNamesAfterTimestamp(cutOffAfterTimestamp, hDB)
+		backupList, err = gpbckpconfig.GetBackupNamesAfterTimestamp(cutOffAfterTimestamp, dbFilter, hDB)
 		if err != nil {
 			return nil, err
 		}
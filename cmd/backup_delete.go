@@ -6,14 +6,13 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
-	"time"
-
-	"golang.org/x/crypto/ssh"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
-	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -30,6 +29,10 @@ var (
 	backupDeleteForce             bool
 	backupDeleteIgnoreErrors      bool
 	backupDeleteParallelProcesses int
+	backupDeleteDryRun            bool
+	backupDeleteYes               bool
+	backupDeleteDatabase          []string
+	backupDeleteExcludeDatabase   []string
 )
 var backupDeleteCmd = &cobra.Command{
 	Use:   "backup-delete",
@@ -64,6 +67,22 @@ For non local backups the following logic are applied:
   * If the --plugin-config option is specified, the deletion will be performed using the storage plugin.
   * If backup is local, the error will be returned.
 
+To make sure that only backups of the specific databases are deleted, use the --database option. It could be specified multiple times.
+To make sure that backups of the specific databases are not deleted, use the --exclude-database option. It could be specified multiple times.
+If at least one of the specified backups does not match the database filter, the deletion process is not performed.
+The --database and --exclude-database options cannot be used together.
+
+To display the deletion plan without deleting anything, use the --dry-run option.
+In this mode, the same checks are performed and the backups, dependent backups, hosts, paths and plugin commands
+that would be used for deletion are displayed. Neither the backup storage nor the history database is changed.
+
+Before the deletion, the backups that will be deleted, including dependent backups, their number
+and the affected databases are displayed and the confirmation is requested.
+To skip the confirmation, use the --yes option. If the command is run non-interactively,
+the --yes option is required, otherwise the deletion process is not performed.
+After the confirmation, the history database is locked and the backups for deletion are checked again.
+If the list of backups was changed by another command in the meantime, nothing is deleted.
+
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
 If the --history-db option is not specified, the history database will be searched in the current directory.`,
@@ -121,6 +140,31 @@ func init() {
 		false,
 		"ignore errors when deleting backups",
 	)
+	backupDeleteCmd.PersistentFlags().BoolVar(
+		&backupDeleteDryRun,
+		dryRunFlagName,
+		false,
+		"show the deletion plan without deleting backups",
+	)
+	backupDeleteCmd.PersistentFlags().BoolVar(
+		&backupDeleteYes,
+		yesFlagName,
+		false,
+		"delete backups without confirmation",
+	)
+	backupDeleteCmd.PersistentFlags().StringArrayVar(
+		&backupDeleteDatabase,
+		databaseFlagName,
+		[]string{},
+		"delete only backups of the specified database, could be specified multiple times",
+	)
+	backupDeleteCmd.PersistentFlags().StringArrayVar(
+		&backupDeleteExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not delete backups of the specified database, could be specified multiple times",
+	)
+	backupDeleteCmd.MarkFlagsMutuallyExclusive(databaseFlagName, excludeDatabaseFlagName)
 	_ = backupDeleteCmd.MarkPersistentFlagRequired(timestampFlagName)
 }
 
@@ -187,7 +231,7 @@ func doDeleteBackup() {
 }
 
 func deleteBackup() error {
-	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB))
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
 		return err
@@ -198,17 +242,44 @@ func deleteBackup() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
 		}
 	}()
+	defer closeSSHConnections()
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: backupDeleteDatabase,
+		Exclude: backupDeleteExcludeDatabase,
+	}
+	err = checkBackupDatabaseFilter(backupDeleteTimestamp, dbFilter, hDB)
+	if err != nil {
+		return err
+	}
+	if !backupDeleteDryRun {
+		skipLocalBackup := backupDeletePluginConfigFile != ""
+		backupList, err := confirmBackupDeletion(backupDeleteTimestamp, backupDeleteCascade, backupDeleteForce, skipLocalBackup, backupDeleteYes, hDB)
+		if err != nil {
+			return err
+		}
+		unlock, err := lockHistoryDBForChange(getHistoryDBPath(rootHistoryDB), len(backupList) == 0, func() error {
+			err := checkBackupDatabaseFilter(backupDeleteTimestamp, dbFilter, hDB)
+			if err != nil {
+				return err
+			}
+			return checkBackupDeleteList(backupList, backupDeleteTimestamp, backupDeleteCascade, backupDeleteForce, skipLocalBackup, hDB)
+		})
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
 	if backupDeletePluginConfigFile != "" {
 		pluginConfig, err := utils.ReadPluginConfig(backupDeletePluginConfigFile)
 		if err != nil {
 			return err
 		}
-		err = backupDeleteDBPlugin(backupDeleteTimestamp, backupDeleteCascade, backupDeleteForce, backupDeleteIgnoreErrors, backupDeletePluginConfigFile, pluginConfig, hDB)
+		err = backupDeleteDBPlugin(backupDeleteTimestamp, backupDeleteCascade, backupDeleteForce, backupDeleteIgnoreErrors, backupDeleteDryRun, backupDeletePluginConfigFile, pluginConfig, hDB)
 		if err != nil {
 			return err
 		}
 	} else {
-		err := backupDeleteDBLocal(backupDeleteTimestamp, backupDeleteBackupDir, backupDeleteCascade, backupDeleteForce, backupDeleteIgnoreErrors, backupDeleteParallelProcesses, hDB)
+		err := backupDeleteDBLocal(backupDeleteTimestamp, backupDeleteBackupDir, backupDeleteCascade, backupDeleteForce, backupDeleteIgnoreErrors, backupDeleteDryRun, backupDeleteParallelProcesses, hDB)
 		if err != nil {
 			return err
 		}
@@ -216,21 +287,55 @@ func deleteBackup() error {
 	return nil
 }
 
-func backupDeleteDBPlugin(backupListForDeletion []string, deleteCascade, deleteForce, ignoreErrors bool, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB) error {
+// Check that all specified backups match the database filter.
+func checkBackupDatabaseFilter(backupList []string, dbFilter gpbckpconfig.DatabaseFilter, hDB *sql.DB) error {
+	if !dbFilter.IsSet() {
+		return nil
+	}
+	for _, backupName := range backupList {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return err
+		}
+		if !dbFilter.Match(backupData.DatabaseName) {
+			gplog.Error("%s", textmsg.ErrorTextUnableWorkBackup(backupName, textmsg.ErrorBackupDatabaseFilterError()))
+			return textmsg.ErrorBackupDatabaseFilterError()
+		}
+	}
+	return nil
+}
+
+func backupDeleteDBPlugin(backupListForDeletion []string, deleteCascade, deleteForce, ignoreErrors, dryRun bool, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB) error {
+	// Skip local backups.
+	skipLocalBackup := true
+	if dryRun {
+		deleter := &backupPluginDryRunDeleter{
+			pluginConfigPath: pluginConfigPath,
+			pluginConfig:     pluginConfig}
+		err := backupDeleteDB(backupListForDeletion, deleteCascade, deleteForce, ignoreErrors, skipLocalBackup, deleter, hDB)
+		logDryRunPlan(deleter.backupList)
+		return err
+	}
 	deleter := &backupPluginDeleter{
 		pluginConfigPath: pluginConfigPath,
 		pluginConfig:     pluginConfig}
-	// Skip local backups.
-	skipLocalBackup := true
 	return backupDeleteDB(backupListForDeletion, deleteCascade, deleteForce, ignoreErrors, skipLocalBackup, deleter, hDB)
 }
 
-func backupDeleteDBLocal(backupListForDeletion []string, backupDir string, deleteCascade, deleteForce, ignoreErrors bool, maxParallelProcesses int, hDB *sql.DB) error {
+func backupDeleteDBLocal(backupListForDeletion []string, backupDir string, deleteCascade, deleteForce, ignoreErrors, dryRun bool, maxParallelProcesses int, hDB *sql.DB) error {
+	// Include local backups.
+	skipLocalBackups := false
+	if dryRun {
+		deleter := &backupLocalDryRunDeleter{
+			backupDir: backupDir}
+		err := backupDeleteDB(backupListForDeletion, deleteCascade, deleteForce, ignoreErrors, skipLocalBackups, deleter, hDB)
+		logDryRunPlan(deleter.backupList)
+		return err
+	}
 	deleter := &backupLocalDeleter{
 		backupDir:            backupDir,
 		maxParallelProcesses: maxParallelProcesses}
-	// Include local backups.
-	skipLocalBackups := false
 	return backupDeleteDB(backupListForDeletion, deleteCascade, deleteForce, ignoreErrors, skipLocalBackups, deleter, hDB)
 }
 
@@ -297,11 +402,235 @@ func backupDeleteDBCascade(backupList []string, deleteForce, ignoreErrors, skipL
 	return nil
 }
 
-func backupDeleteDBPluginFunc(backupName, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB, ignoreErrors bool) error {
+// backupDeletePlan contains the backups for deletion in the order of the sequential deletion
+// and, for each backup, the backups from the plan that depend on it.
+// The dependent backups must be deleted before the backup.
+type backupDeletePlan struct {
+	backupList []string
+	dependents map[string][]string
+}
+
+// getBackupDeletePlan Returns the deletion plan for the list of backups.
+// The same checks as in backupDeleteDB function are performed, but nothing is deleted.
+func getBackupDeletePlan(backupListForDeletion []string, deleteCascade, deleteForce, skipLocalBackup bool, hDB *sql.DB) (backupDeletePlan, error) {
+	plan := backupDeletePlan{dependents: make(map[string][]string)}
+	planned := make(map[string]bool)
+	addToPlan := func(backupName string) {
+		if !planned[backupName] {
+			planned[backupName] = true
+			plan.backupList = append(plan.backupList, backupName)
+		}
+	}
+	canBeDeleted := func(backupName string) (bool, error) {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return false, err
+		}
+		return checkBackupCanBeUsed(deleteForce, skipLocalBackup, backupData)
+	}
+	for _, backupName := range backupListForDeletion {
+		if planned[backupName] {
+			continue
+		}
+		ok, err := canBeDeleted(backupName)
+		if err != nil {
+			return plan, err
+		}
+		if !ok {
+			continue
+		}
+		backupDependencies, err := gpbckpconfig.GetBackupDependencies(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("dependencies", backupName, err))
+			return plan, err
+		}
+		if len(backupDependencies) > 0 {
+			gplog.Info("%s", textmsg.InfoTextBackupDependenciesList(backupName, backupDependencies))
+			if !deleteCascade {
+				gplog.Error("%s", textmsg.ErrorTextUnableDeleteBackupUseCascade(backupName, textmsg.ErrorBackupDeleteCascadeOptionError()))
+				return plan, textmsg.ErrorBackupDeleteCascadeOptionError()
+			}
+			for _, backup := range backupDependencies {
+				if planned[backup] {
+					continue
+				}
+				ok, err := canBeDeleted(backup)
+				if err != nil {
+					return plan, err
+				}
+				if ok {
+					addToPlan(backup)
+				}
+			}
+		}
+		addToPlan(backupName)
+	}
+	for _, backupName := range plan.backupList {
+		backupDependencies, err := gpbckpconfig.GetBackupDependencies(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("dependencies", backupName, err))
+			return plan, err
+		}
+		for _, backup := range backupDependencies {
+			if planned[backup] {
+				plan.dependents[backupName] = append(plan.dependents[backupName], backup)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// confirmBackupDeletion Displays the backups that will be deleted, including dependent backups,
+// their number and the affected databases, and asks for the confirmation.
+// If assumeYes is true, the confirmation is not asked.
+// If the standard input is not a terminal, the deletion is refused without assumeYes.
+// Returns the confirmed list of backups, that will be deleted.
+func confirmBackupDeletion(backupListForDeletion []string, deleteCascade, deleteForce, skipLocalBackup, assumeYes bool, hDB *sql.DB) ([]string, error) {
+	plan, err := getBackupDeletePlan(backupListForDeletion, deleteCascade, deleteForce, skipLocalBackup, hDB)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.backupList) == 0 {
+		return nil, nil
+	}
+	databases := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, backupName := range plan.backupList {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return nil, err
+		}
+		if !seen[backupData.DatabaseName] {
+			seen[backupData.DatabaseName] = true
+			databases = append(databases, backupData.DatabaseName)
+		}
+	}
+	sort.Strings(databases)
+	count := strconv.Itoa(len(plan.backupList))
+	gplog.Info("%s", textmsg.InfoTextBackupDeleteList(plan.backupList))
+	gplog.Info("%s", textmsg.InfoTextBackupDeleteTotal(count))
+	gplog.Info("%s", textmsg.InfoTextBackupDeleteAffectedDatabases(databases))
+	if assumeYes {
+		return plan.backupList, nil
+	}
+	if !isInteractiveInput() {
+		gplog.Error("%s", textmsg.ErrorConfirmationRequiredError())
+		return nil, textmsg.ErrorConfirmationRequiredError()
+	}
+	confirmed, err := askConfirmation(confirmationInput, os.Stdout, textmsg.InfoTextBackupDeleteConfirmation(count))
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		gplog.Error("%s", textmsg.ErrorBackupDeleteNotConfirmedError())
+		return nil, textmsg.ErrorBackupDeleteNotConfirmedError()
+	}
+	return plan.backupList, nil
+}
+
+// checkBackupDeleteList Checks that the list of backups from the deletion plan is the same as the confirmed one.
+// Between the confirmation and the lock of the history database, the backups could be added or deleted by another command.
+func checkBackupDeleteList(confirmedList, backupListForDeletion []string, deleteCascade, deleteForce, skipLocalBackup bool, hDB *sql.DB) error {
+	plan, err := getBackupDeletePlan(backupListForDeletion, deleteCascade, deleteForce, skipLocalBackup, hDB)
+	if err != nil {
+		return err
+	}
+	if !slices.Equal(plan.backupList, confirmedList) {
+		gplog.Error("%s", textmsg.ErrorBackupDeleteListChangedError())
+		return textmsg.ErrorBackupDeleteListChangedError()
+	}
+	return nil
+}
+
+// backupDeleteDBParallel Deletes the backups from the plan in parallel.
+// The backup is deleted only after all its dependent backups from the plan are deleted.
+// If the deletion of the dependent backup fails, the backup is not deleted.
+// If ignoreErrors is false, no new deletions are started after the first error.
+// The number of parallel deletions is limited by maxParallelProcesses.
+func backupDeleteDBParallel(plan backupDeletePlan, maxParallelProcesses int, ignoreErrors bool, deleter backupDeleteInterface, hDB *sql.DB) error {
+	type deleteResult struct {
+		backupName string
+		err        error
+	}
+	// The number of not deleted dependent backups for each backup.
+	pending := make(map[string]int)
+	// The backups, that wait for the deletion of each backup.
+	waiting := make(map[string][]string)
+	for _, backupName := range plan.backupList {
+		pending[backupName] = len(plan.dependents[backupName])
+		for _, backup := range plan.dependents[backupName] {
+			waiting[backup] = append(waiting[backup], backupName)
+		}
+	}
+	var ready []string
+	for _, backupName := range plan.backupList {
+		if pending[backupName] == 0 {
+			ready = append(ready, backupName)
+		}
+	}
+	skipped := make(map[string]bool)
+	var skipWaiting func(backupName string, err error)
+	skipWaiting = func(backupName string, err error) {
+		for _, backup := range waiting[backupName] {
+			if skipped[backup] {
+				continue
+			}
+			skipped[backup] = true
+			gplog.Error("%s", textmsg.ErrorTextUnableDeleteBackupCascade(backup, err))
+			skipWaiting(backup, err)
+		}
+	}
+	results := make(chan deleteResult)
+	running := 0
+	stop := false
+	var deleteErr error
+	for {
+		for !stop && running < maxParallelProcesses && len(ready) > 0 {
+			backupName := ready[0]
+			ready = ready[1:]
+			running++
+			go func(backupName string) {
+				results <- deleteResult{backupName: backupName, err: deleter.backupDeleteDB(backupName, hDB, ignoreErrors)}
+			}(backupName)
+		}
+		if running == 0 {
+			break
+		}
+		result := <-results
+		running--
+		if result.err != nil {
+			deleteErr = result.err
+			stop = !ignoreErrors
+			skipWaiting(result.backupName, result.err)
+			continue
+		}
+		for _, backupName := range waiting[result.backupName] {
+			pending[backupName]--
+			if pending[backupName] == 0 && !skipped[backupName] {
+				ready = append(ready, backupName)
+			}
+		}
+	}
+	return deleteErr
+}
+
+// backupDeleteDBPluginFunc deletes the backup using the storage plugin.
+// The history db operations are performed under historyDBMutex,
+// so the function could be called for several backups in parallel.
+func backupDeleteDBPluginFunc(backupName, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB, ignoreErrors bool, historyDBMutex *sync.Mutex) error {
 	var err error
+	handleError := func(errorMessage string) {
+		historyDBMutex.Lock()
+		defer historyDBMutex.Unlock()
+		handleErrorDB(backupName, errorMessage, gpbckpconfig.DateDeletedPluginFailed, hDB)
+	}
 	dateDeleted := getCurrentTimestamp()
 	gplog.Info("%s", textmsg.InfoTextBackupDeleteStart(backupName))
+	historyDBMutex.Lock()
 	err = gpbckpconfig.UpdateDeleteStatus(backupName, gpbckpconfig.DateDeletedInProgress, hDB)
+	historyDBMutex.Unlock()
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableSetBackupStatus(gpbckpconfig.DateDeletedInProgress, backupName, err))
 		return err
@@ -312,22 +641,24 @@ func backupDeleteDBPluginFunc(backupName, pluginConfigPath string, pluginConfig
 		gplog.Error("%s", stderr)
 	}
 	if errdel != nil {
-		handleErrorDB(backupName, textmsg.ErrorTextUnableDeleteBackup(backupName, errdel), gpbckpconfig.DateDeletedPluginFailed, hDB)
+		handleError(textmsg.ErrorTextUnableDeleteBackup(backupName, errdel))
 		if !ignoreErrors {
 			return errdel
 		}
 	}
 	gplog.Info("%s", stdout)
+	historyDBMutex.Lock()
 	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	historyDBMutex.Unlock()
 	if err != nil {
-		handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupInfo(backupName, err), gpbckpconfig.DateDeletedPluginFailed, hDB)
+		handleError(textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
 		if !ignoreErrors {
 			return err
 		}
 	}
 	bckpDir, _, _, err := getBackupMasterDir("", backupData.BackupDir, backupData.DatabaseName)
 	if err != nil {
-		handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err), gpbckpconfig.DateDeletedPluginFailed, hDB)
+		handleError(textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err))
 		if !ignoreErrors {
 			return err
 		}
@@ -336,12 +667,14 @@ func backupDeleteDBPluginFunc(backupName, pluginConfigPath string, pluginConfig
 	// Delete local files on master.
 	err = os.RemoveAll(gpbckpconfig.BackupDirPath(bckpDir, backupName))
 	if err != nil {
-		handleErrorDB(backupName, textmsg.ErrorTextUnableDeleteBackup(backupName, err), gpbckpconfig.DateDeletedPluginFailed, hDB)
+		handleError(textmsg.ErrorTextUnableDeleteBackup(backupName, err))
 		if !ignoreErrors {
 			return err
 		}
 	}
+	historyDBMutex.Lock()
 	err = gpbckpconfig.UpdateDeleteStatus(backupName, dateDeleted, hDB)
+	historyDBMutex.Unlock()
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableSetBackupStatus(dateDeleted, backupName, err))
 		return err
@@ -414,6 +747,79 @@ func backupDeleteDBLocalFunc(backupName, backupDir string, maxParallelProcesses
 	return nil
 }
 
+// backupDeleteDBPluginDryRunFunc displays the plan of backup deletion using the storage plugin.
+// It resolves the same values as backupDeleteDBPluginFunc, but neither the backup storage nor the history db is changed.
+func backupDeleteDBPluginDryRunFunc(backupName, pluginConfigPath string, pluginConfig *utils.PluginConfig, hDB *sql.DB, ignoreErrors bool) error {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextDryRunBackupDelete(backupName))
+	gplog.Info("%s", textmsg.InfoTextDryRunCommandExecution(pluginConfig.ExecutablePath, deleteBackupPluginCommand, pluginConfigPath, backupName))
+	bckpDir, _, _, err := getBackupMasterDir("", backupData.BackupDir, backupData.DatabaseName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err))
+		if !ignoreErrors {
+			return err
+		}
+	}
+	gplog.Info("%s", textmsg.InfoTextDryRunCommandExecution("delete directory", gpbckpconfig.BackupDirPath(bckpDir, backupName)))
+	return nil
+}
+
+// backupDeleteDBLocalDryRunFunc displays the plan of local backup deletion.
+// It resolves the same values as backupDeleteDBLocalFunc, but neither the backup storage nor the history db is changed.
+func backupDeleteDBLocalDryRunFunc(backupName, backupDir string, hDB *sql.DB, ignoreErrors bool) error {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextDryRunBackupDelete(backupName))
+	bckpDir, segPrefix, isSingleBackupDir, err := getBackupMasterDir(backupDir, backupData.BackupDir, backupData.DatabaseName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err))
+		return err
+	}
+	gplog.Debug("%s", textmsg.InfoTextBackupDirPath(bckpDir))
+	gplog.Debug("%s", textmsg.InfoTextSegmentPrefix(segPrefix))
+	backupType, err := backupData.GetBackupType()
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("type", backupName, err))
+		return err
+	}
+	// For "metadata-only" backups files are deleted only on master.
+	if backupType != gpbckpconfig.BackupTypeMetadataOnly {
+		segConfig, errSeg := getSegmentConfigurationClusterInfo(backupData.DatabaseName)
+		if errSeg != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("segment configuration", backupName, errSeg))
+			if !ignoreErrors {
+				return errSeg
+			}
+		}
+		for _, config := range segConfig {
+			backupPath, errSeg := getBackupSegmentDir(backupDir, backupData.BackupDir, config.DataDir, segPrefix, config.ContentID, isSingleBackupDir)
+			if errSeg != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("segment backup directory", backupName, errSeg))
+				return errSeg
+			}
+			gplog.Info("%s", textmsg.InfoTextDryRunCommandExecution("rm -rf", gpbckpconfig.BackupDirPath(backupPath, backupName), "on host", config.Hostname))
+		}
+	}
+	gplog.Info("%s", textmsg.InfoTextDryRunCommandExecution("delete directory", gpbckpconfig.BackupDirPath(bckpDir, backupName)))
+	return nil
+}
+
+// logDryRunPlan displays the summary of the deletion plan in dry run mode.
+func logDryRunPlan(backupList []string) {
+	if len(backupList) == 0 {
+		gplog.Info("%s", textmsg.InfoTextNothingToDo())
+		return
+	}
+	gplog.Info("%s", textmsg.InfoTextDryRunBackupDeleteList(backupList))
+}
+
 func execDeleteBackupPlugin(executablePath, deleteBackupPluginCommand, pluginConfigFile, timestamp string) (string, string, error) {
 	cmd := execCommand(executablePath, deleteBackupPluginCommand, pluginConfigFile, timestamp)
 	var stdout, stderr bytes.Buffer
@@ -426,27 +832,70 @@ func execDeleteBackupPlugin(executablePath, deleteBackupPluginCommand, pluginCon
 // ExecuteCommandsOnHosts Delete backup dir on all segment hosts in parallel.
 // The function checks that the directories exists on all segment hosts before deletion.
 func executeDeleteBackupOnSegments(backupDir, backupDataBackupDir, backupName, segPrefix string, isSingleBackupDir, ignoreErrors bool, configs []gpbckpconfig.SegmentConfig, maxParallelProcesses int) error {
-	var once sync.Once
-	limit := make(chan bool, maxParallelProcesses)
-	wg := &sync.WaitGroup{}
-	errCh := make(chan error, len(configs))
-	sshClientConf, err := getSSHConfig()
+	sshClientDialer, err := getSSHDialer()
 	if err != nil {
 		return err
 	}
 	// Check that the directory exists on all segment hosts.
+	errList, err := executeOnSegments(backupDir, backupDataBackupDir, backupName, segPrefix, isSingleBackupDir, configs, maxParallelProcesses, sshClientDialer,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			checkBackupDirExistsOnSegments(paths, host, sshDial, errCh)
+		})
+	if err != nil {
+		return err
+	}
+	for _, err := range errList {
+		if err != nil && !ignoreErrors {
+			return err
+		}
+	}
+	// If all checks passed, delete the directory on all segment hosts.
+	errList, err = executeOnSegments(backupDir, backupDataBackupDir, backupName, segPrefix, isSingleBackupDir, configs, maxParallelProcesses, sshClientDialer,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			deleteBackupDirOnSegments(paths, host, sshDial, errCh)
+		})
+	if err != nil {
+		return err
+	}
+	for _, err := range errList {
+		if err != nil && !ignoreErrors {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeOnSegments Runs the action for the backup directory on all segment hosts in parallel.
+// The number of parallel processes and ssh connections is limited by maxParallelProcesses.
+// The errors sent by the actions are returned after all actions are completed.
+func executeOnSegments(backupDir, backupDataBackupDir, backupName, segPrefix string, isSingleBackupDir bool, configs []gpbckpconfig.SegmentConfig, maxParallelProcesses int, sshClientDialer sshDialer, action segmentAction) ([]error, error) {
+	paths := make([]string, 0, len(configs))
 	for _, config := range configs {
-		wg.Add(1)
-		limit <- true
 		backupPath, err := getBackupSegmentDir(backupDir, backupDataBackupDir, config.DataDir, segPrefix, config.ContentID, isSingleBackupDir)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		go func(backupPath, host string) {
+		paths = append(paths, gpbckpconfig.BackupDirPath(backupPath, backupName))
+	}
+	return runOnSegments(configs, paths, maxParallelProcesses, sshClientDialer, action), nil
+}
+
+// runOnSegments Runs the action for the segments and the corresponding paths in parallel.
+// The segments are grouped by host, the action is run once for all segments of the host.
+// The configs and paths slices must have the same length.
+func runOnSegments(configs []gpbckpconfig.SegmentConfig, paths []string, maxParallelProcesses int, sshClientDialer sshDialer, action segmentAction) []error {
+	hosts, hostConfigs, hostPaths := groupSegmentsByHost(configs, paths)
+	limit := make(chan bool, maxParallelProcesses)
+	wg := &sync.WaitGroup{}
+	errCh := make(chan error, len(hosts))
+	for _, host := range hosts {
+		wg.Add(1)
+		limit <- true
+		go func(host string) {
 			defer func() { <-limit }()
 			defer wg.Done()
-			checkBackupDirExistsOnSegments(gpbckpconfig.BackupDirPath(backupPath, backupName), host, sshClientConf, errCh)
-		}(backupPath, config.Hostname)
+			action(host, hostConfigs[host], hostPaths[host], sshClientDialer, errCh)
+		}(host)
 	}
 	// We should block the main function and wait for the WaitGroup to complete.
 	// It is necessary to strictly verify that all checks are performed for a specific backup
@@ -456,117 +905,53 @@ func executeDeleteBackupOnSegments(backupDir, backupDataBackupDir, backupName, s
 	// and deletion occurs for another.
 	//
 	// Don't use code like
-	// go func() { wg.Wait(); once.Do(func() { close(errCh) }) }()
+	// go func() { wg.Wait(); close(errCh) }()
 	//
 	wg.Wait()
-	// Fix error like "panic: close of closed channel".
-	once.Do(func() {
-		close(errCh)
-	})
-	for err := range errCh {
-		if err != nil && !ignoreErrors {
-			return err
-		}
-	}
-	// If all checks passed, delete the directory on all segment hosts.
-	// Reset the wait group.
-	wg = &sync.WaitGroup{}
-	for _, config := range configs {
-		wg.Add(1)
-		limit <- true
-		backupPath, err := getBackupSegmentDir(backupDir, backupDataBackupDir, config.DataDir, segPrefix, config.ContentID, isSingleBackupDir)
-		if err != nil {
-			return err
-		}
-		go func(backupPath, host string) {
-			defer func() { <-limit }()
-			defer wg.Done()
-			deleteBackupDirOnSegments(gpbckpconfig.BackupDirPath(backupPath, backupName), host, sshClientConf, errCh)
-		}(backupPath, config.Hostname)
-	}
-	wg.Wait()
-	// Fix error like "panic: close of closed channel".
-	once.Do(func() {
-		close(errCh)
-	})
+	close(errCh)
+	var errList []error
 	for err := range errCh {
-		if err != nil && !ignoreErrors {
-			return err
-		}
+		errList = append(errList, err)
 	}
-	return nil
+	return errList
 }
-func checkBackupDirExistsOnSegments(path, host string, sshConf *ssh.ClientConfig, errCh chan error) {
-	connection, err := ssh.Dial("tcp", host+":22", sshConf)
-	if err != nil {
-		errCh <- err
-		return
-	}
-	defer connection.Close()
 
-	session, err := connection.NewSession()
-	if err != nil {
-		errCh <- err
-		return
-	}
-	defer session.Close()
-	command := fmt.Sprintf("test -d %s", path)
-	gplog.Debug("%s", textmsg.InfoTextCommandExecution(command, "on host", host))
-	if err := session.Run(command); err != nil {
-		gplog.Error("%s", textmsg.ErrorTextCommandExecutionFailed(err, command, "on host", host))
-		errCh <- textmsg.ErrorNotFoundBackupDirIn(fmt.Sprintf("%s on host %s", path, host))
-		return
+// groupSegmentsByHost Returns the hosts in the order of the first appearance
+// and the segments and paths of each host.
+func groupSegmentsByHost(configs []gpbckpconfig.SegmentConfig, paths []string) ([]string, map[string][]gpbckpconfig.SegmentConfig, map[string][]string) {
+	var hosts []string
+	hostConfigs := make(map[string][]gpbckpconfig.SegmentConfig)
+	hostPaths := make(map[string][]string)
+	for i, config := range configs {
+		if _, ok := hostConfigs[config.Hostname]; !ok {
+			hosts = append(hosts, config.Hostname)
+		}
+		hostConfigs[config.Hostname] = append(hostConfigs[config.Hostname], config)
+		hostPaths[config.Hostname] = append(hostPaths[config.Hostname], paths[i])
 	}
-	gplog.Debug("%s", textmsg.InfoTextCommandExecutionSucceeded(command, "on host", host))
+	return hosts, hostConfigs, hostPaths
 }
 
-func deleteBackupDirOnSegments(path, host string, sshConf *ssh.ClientConfig, errCh chan error) {
-	connection, err := ssh.Dial("tcp", host+":22", sshConf)
-	if err != nil {
-		errCh <- err
-		return
-	}
-	defer connection.Close()
-
-	session, err := connection.NewSession()
+// checkBackupDirExistsOnSegments Checks that all directories exist on the host.
+// All directories are checked by one command, the missing directories are printed by the command.
+func checkBackupDirExistsOnSegments(paths []string, host string, sshDial sshDialer, errCh chan error) {
+	command := fmt.Sprintf("for path in %s; do test -d \"$path\" || echo \"$path\"; done", shellQuoteList(paths))
+	output, err := runCommandOnHost(command, host, sshDial)
 	if err != nil {
 		errCh <- err
 		return
 	}
-	defer session.Close()
-	command := fmt.Sprintf("rm -rf %s", path)
-	gplog.Debug("%s", textmsg.InfoTextCommandExecution(command, "on host", host))
-	if err := session.Run(command); err != nil {
+	if missing := strings.TrimSpace(output); missing != "" {
+		err = textmsg.ErrorNotFoundBackupDirIn(fmt.Sprintf("%s on host %s", strings.ReplaceAll(missing, "\n", ", "), host))
 		gplog.Error("%s", textmsg.ErrorTextCommandExecutionFailed(err, command, "on host", host))
 		errCh <- err
-		return
 	}
-	gplog.Debug("%s", textmsg.InfoTextCommandExecutionSucceeded(command, "on host", host))
 }
 
-func getSSHConfig() (*ssh.ClientConfig, error) {
-	currentUser, _ := operating.System.CurrentUser()
-	key, err := os.ReadFile(currentUser.HomeDir + "/.ssh/id_rsa")
-	if err != nil {
-		return nil, err
-	}
-	signer, err := ssh.ParsePrivateKey(key)
-	if err != nil {
-		return nil, err
+// deleteBackupDirOnSegments Deletes all directories on the host by one command.
+func deleteBackupDirOnSegments(paths []string, host string, sshDial sshDialer, errCh chan error) {
+	command := fmt.Sprintf("rm -rf %s", shellQuoteList(paths))
+	if _, err := runCommandOnHost(command, host, sshDial); err != nil {
+		errCh <- err
 	}
-	// sshConfig is a configuration object for establishing an SSH connection.
-	// It contains the user's username, authentication method using public keys,
-	// and a host key callback that ignores insecure host keys.
-	sshConfig := &ssh.ClientConfig{
-		User: currentUser.Username,
-		Auth: []ssh.AuthMethod{
-			ssh.PublicKeys(signer),
-		},
-		// Disable known_hosts check.
-		// This check also disables in gpbackup utility.
-		// #nosec G106
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         30 * time.Second,
-	}
-	return sshConfig, nil
 }
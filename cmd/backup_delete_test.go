@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+)
+
+type testBackupDeleter struct {
+	mu         sync.Mutex
+	failed     map[string]bool
+	deleted    []string
+	running    int
+	maxRunning int
+}
+
+func (d *testBackupDeleter) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
+	d.mu.Lock()
+	d.running++
+	if d.running > d.maxRunning {
+		d.maxRunning = d.running
+	}
+	d.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.running--
+	if d.failed[backupName] {
+		return errors.New("test error")
+	}
+	d.deleted = append(d.deleted, backupName)
+	return nil
+}
+
+func TestBackupDeleteDBParallel(t *testing.T) {
+	testhelper.SetupTestLogger()
+	// 20240101000000 - full backup, 20240102000000 and 20240103000000 - its incremental backups.
+	// 20240104000000 and 20240105000000 - independent backups.
+	plan := backupDeletePlan{
+		backupList: []string{"20240103000000", "20240102000000", "20240101000000", "20240104000000", "20240105000000"},
+		dependents: map[string][]string{
+			"20240101000000": {"20240103000000", "20240102000000"},
+			"20240102000000": {"20240103000000"},
+		},
+	}
+	tests := []struct {
+		name           string
+		maxParallel    int
+		ignoreErrors   bool
+		failed         map[string]bool
+		wantDeleted    []string
+		wantErr        bool
+		wantMaxRunning int
+	}{
+		{
+			name:           "Test sequential deletion",
+			maxParallel:    1,
+			wantDeleted:    []string{"20240101000000", "20240102000000", "20240103000000", "20240104000000", "20240105000000"},
+			wantMaxRunning: 1,
+		},
+		{
+			name:           "Test parallel deletion",
+			maxParallel:    2,
+			wantDeleted:    []string{"20240101000000", "20240102000000", "20240103000000", "20240104000000", "20240105000000"},
+			wantMaxRunning: 2,
+		},
+		{
+			name:           "Test failed dependent backup with ignore errors",
+			maxParallel:    3,
+			ignoreErrors:   true,
+			failed:         map[string]bool{"20240102000000": true},
+			wantDeleted:    []string{"20240103000000", "20240104000000", "20240105000000"},
+			wantErr:        true,
+			wantMaxRunning: 3,
+		},
+		{
+			name:           "Test failed backup without ignore errors",
+			maxParallel:    1,
+			failed:         map[string]bool{"20240103000000": true},
+			wantDeleted:    nil,
+			wantErr:        true,
+			wantMaxRunning: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleter := &testBackupDeleter{failed: tt.failed}
+			err := backupDeleteDBParallel(plan, tt.maxParallel, tt.ignoreErrors, deleter, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("backupDeleteDBParallel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if deleter.maxRunning != tt.wantMaxRunning {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", deleter.maxRunning, tt.wantMaxRunning)
+			}
+			// Dependent backups must be deleted before the backups they depend on.
+			position := make(map[string]int)
+			for i, backupName := range deleter.deleted {
+				position[backupName] = i
+			}
+			for backupName, dependents := range plan.dependents {
+				for _, dependent := range dependents {
+					pos, ok := position[backupName]
+					if ok && position[dependent] > pos {
+						t.Errorf("backup %s is deleted before dependent backup %s", backupName, dependent)
+					}
+				}
+			}
+			got := append([]string(nil), deleter.deleted...)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantDeleted) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman backup-fetch command (backupFetchCmd)
+var (
+	backupFetchTimestamp            string
+	backupFetchArtifact             string
+	backupFetchPluginConfigFile     string
+	backupFetchReportFilePluginPath string
+	backupFetchBackupDir            string
+	backupFetchOutputFile           string
+)
+
+var backupFetchCmd = &cobra.Command{
+	Use:   "backup-fetch",
+	Short: "Fetch the backup file from master backup directory",
+	Long: `Fetch the backup file from master backup directory for a specific backup.
+
+The --timestamp option must be specified.
+
+The file could be fetched only for active backups.
+
+The backup file is set using the --artifact option. The following files are supported:
+  * report - gpbackup_<YYYYMMDDHHMMSS>_report (default);
+  * config - gpbackup_<YYYYMMDDHHMMSS>_config.yaml;
+  * toc - gpbackup_<YYYYMMDDHHMMSS>_toc.yaml;
+  * metadata - gpbackup_<YYYYMMDDHHMMSS>_metadata.sql.
+
+The full path to the backup directory can be set using the --backup-dir option.
+The full path to the data directory is required.
+
+For local backups the following logic are applied:
+  * If the --backup-dir option is specified, the file will be searched in provided path.
+  * If the --backup-dir option is not specified, but the backup was made with --backup-dir flag for gpbackup, the file will be searched in provided path from backup manifest.
+  * If the --backup-dir option is not specified and backup directory is not specified in backup manifest, the utility try to connect to local cluster and get master data directory.
+    If this information is available, the file will be in master data directory.
+  * If backup is not local, the error will be returned.
+
+The storage plugin config file location can be set using the --plugin-config option.
+The full path to the file is required.
+
+For non local backups the following logic are applied:
+  * If the --plugin-config option is specified, the file will be searched in provided location.
+  * If backup is local, the error will be returned.
+
+Only --backup-dir or --plugin-config option can be specified, not both.
+
+The backup files on master are stored in the same directory as the report file.
+The path to the directory is resolved in the same way as for the report-info command.
+If a custom plugin is used, it is required to specify the path to the directory with the report file using the --plugin-report-file-path option
+or using the gpbackman_report_path_template option in the plugin config file. See the report-info command for details.
+
+By default, the file is written to stdout.
+To save the file, use the --output-file option. The full path to the file is required.
+The file must not exist.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doBackupFetchFlagValidation(cmd.Flags())
+		doBackupFetch()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupFetchCmd)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchTimestamp,
+		timestampFlagName,
+		"",
+		"the backup timestamp for fetching the file",
+	)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchArtifact,
+		artifactFlagName,
+		fetchArtifactReport,
+		"the backup file to fetch (report, config, toc, metadata)",
+	)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchPluginConfigFile,
+		pluginConfigFileFlagName,
+		"",
+		"the full path to plugin config file",
+	)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchReportFilePluginPath,
+		reportFilePluginPathFlagName,
+		"",
+		"the full path to plugin report file",
+	)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchBackupDir,
+		backupDirFlagName,
+		"",
+		"the full path to backup directory",
+	)
+	backupFetchCmd.PersistentFlags().StringVar(
+		&backupFetchOutputFile,
+		outputFileFlagName,
+		"",
+		"the full path to the file to save the fetched file",
+	)
+	_ = backupFetchCmd.MarkPersistentFlagRequired(timestampFlagName)
+}
+
+// These flag checks are applied only for backup-fetch command.
+func doBackupFetchFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If timestamps are specified and have correct values.
+	if flags.Changed(timestampFlagName) {
+		err = gpbckpconfig.CheckTimestamp(backupFetchTimestamp)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchTimestamp, timestampFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If artifact flag is specified and have correct values.
+	if flags.Changed(artifactFlagName) {
+		err = checkFetchArtifact(backupFetchArtifact)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchArtifact, artifactFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// backup-dir anf plugin-config flags cannot be used together.
+	err = checkCompatibleFlags(flags, backupDirFlagName, pluginConfigFileFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, backupDirFlagName, pluginConfigFileFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If backup-dir flag is specified and it exists and the full path is specified.
+	if flags.Changed(backupDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupFetchBackupDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchBackupDir, backupDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-config flag is specified and it exists and the full path is specified.
+	if flags.Changed(pluginConfigFileFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupFetchPluginConfigFile, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchPluginConfigFile, pluginConfigFileFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-report-file-path flag is specified.
+	if flags.Changed(reportFilePluginPathFlagName) {
+		// But plugin-config flag is not specified.
+		if !flags.Changed(pluginConfigFileFlagName) {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), reportFilePluginPathFlagName, pluginConfigFileFlagName))
+			execOSExit(exitErrorCode)
+		}
+		// Check full path.
+		err = gpbckpconfig.CheckFullPath(backupFetchReportFilePluginPath, false)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchReportFilePluginPath, reportFilePluginPathFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output-file flag is specified, the full path is specified and the file doesn't exist.
+	if flags.Changed(outputFileFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupFetchOutputFile, false)
+		if err == nil {
+			if _, statErr := os.Stat(backupFetchOutputFile); statErr == nil {
+				err = textmsg.ErrorFileAlreadyExist()
+			}
+		}
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupFetchOutputFile, outputFileFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doBackupFetch() {
+	logHeadersDebug()
+	err := backupFetch()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func backupFetch() error {
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	var pluginConfig *utils.PluginConfig
+	if backupFetchPluginConfigFile != "" {
+		pluginConfig, err = utils.ReadPluginConfig(backupFetchPluginConfigFile)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableReadPluginConfigFile(err))
+			return err
+		}
+	}
+	return backupFetchDB(backupFetchTimestamp, backupFetchArtifact, pluginConfig, hDB)
+}
+
+func backupFetchDB(backupName, artifact string, pluginConfig *utils.PluginConfig, hDB *sql.DB) error {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return err
+	}
+	fileName := getFetchArtifactFileName(artifact, backupName)
+	var content string
+	var canFetch bool
+	if pluginConfig != nil {
+		content, canFetch, err = getBackupFilePlugin(backupData, fileName, backupFetchReportFilePluginPath, backupFetchPluginConfigFile, pluginConfig)
+	} else {
+		content, canFetch, err = getBackupFileLocal(backupData, fileName, backupFetchBackupDir)
+	}
+	if err != nil || !canFetch {
+		return err
+	}
+	if backupFetchOutputFile == "" {
+		_, err = os.Stdout.WriteString(content)
+		return err
+	}
+	err = writeBackupFetchFile(backupFetchOutputFile, content)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableSaveBackupFile(fileName, backupName, err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextBackupFileSaved(fileName, backupName, backupFetchOutputFile))
+	return nil
+}
+
+// getFetchArtifactFileName Returns the name of the backup file on master for the artifact.
+func getFetchArtifactFileName(artifact, timestamp string) string {
+	switch artifact {
+	case fetchArtifactConfig:
+		return gpbckpconfig.ConfigFileName(timestamp)
+	case fetchArtifactToc:
+		return gpbckpconfig.TocFileName(timestamp)
+	case fetchArtifactMetadata:
+		return gpbckpconfig.MetadataFileName(timestamp)
+	default:
+		return gpbckpconfig.ReportFileName(timestamp)
+	}
+}
+
+// getBackupFilePlugin Returns the content of the backup file on master from the plugin storage.
+// If the file can't be fetched for the backup, the empty content and false are returned.
+func getBackupFilePlugin(backupData gpbckpconfig.BackupConfig, fileName, customReportPath, pluginConfigPath string, pluginConfig *utils.PluginConfig) (string, bool, error) {
+	// Skip local backup.
+	canFetch, err := checkBackupCanBeUsed(false, true, backupData)
+	if err != nil || !canFetch {
+		return "", canFetch, err
+	}
+	backupFile, err := backupData.GetBackupFilePathPlugin(fileName, customReportPath, pluginConfig.Options)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath(fileName, backupData.Timestamp, err))
+		return "", true, err
+	}
+	gplog.Debug("%s", textmsg.InfoTextCommandExecution(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, backupFile))
+	stdout, stderr, err := execReportInfo(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, backupFile)
+	if stderr != "" {
+		gplog.Error("%s", stderr)
+	}
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupFile(fileName, backupData.Timestamp, err))
+		return "", true, err
+	}
+	return stdout, true, nil
+}
+
+// getBackupFileLocal Returns the content of the backup file on master from the local storage.
+// If the file can't be fetched for the backup, the empty content and false are returned.
+func getBackupFileLocal(backupData gpbckpconfig.BackupConfig, fileName, backupDir string) (string, bool, error) {
+	// Include local backup.
+	canFetch, err := checkBackupCanBeUsed(false, false, backupData)
+	if err != nil || !canFetch {
+		return "", canFetch, err
+	}
+	timestamp := backupData.Timestamp
+	bckpDir, segPrefix, _, err := getBackupMasterDir(backupDir, backupData.BackupDir, backupData.DatabaseName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("backup directory", timestamp, err))
+		return "", true, err
+	}
+	gplog.Debug("%s", textmsg.InfoTextBackupDirPath(bckpDir))
+	gplog.Debug("%s", textmsg.InfoTextSegmentPrefix(segPrefix))
+	backupFile := filepath.Join(gpbckpconfig.BackupDirPath(bckpDir, timestamp), fileName)
+	// Sanitize the file path
+	backupFile = filepath.Clean(backupFile)
+	gplog.Debug("%s", textmsg.InfoTextCommandExecution("read file", backupFile))
+	content, err := os.ReadFile(backupFile)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupFile(fileName, timestamp, err))
+		return "", true, err
+	}
+	return string(content), true, nil
+}
+
+// writeBackupFetchFile writes the fetched backup file to the new file.
+// If the file already exists, an error is returned.
+func writeBackupFetchFile(filename, content string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(content)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetFetchArtifactFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact string
+		want     string
+	}{
+		{
+			name:     "Test report",
+			artifact: fetchArtifactReport,
+			want:     "gpbackup_20240101100000_report",
+		},
+		{
+			name:     "Test config",
+			artifact: fetchArtifactConfig,
+			want:     "gpbackup_20240101100000_config.yaml",
+		},
+		{
+			name:     "Test toc",
+			artifact: fetchArtifactToc,
+			want:     "gpbackup_20240101100000_toc.yaml",
+		},
+		{
+			name:     "Test metadata",
+			artifact: fetchArtifactMetadata,
+			want:     "gpbackup_20240101100000_metadata.sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getFetchArtifactFileName(tt.artifact, "20240101100000"); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBackupFileLocal(t *testing.T) {
+	testhelper.SetupTestLogger()
+	backupDir := t.TempDir()
+	timestamp := "20240101100000"
+	fileName := gpbckpconfig.TocFileName(timestamp)
+	filePath := filepath.Join(gpbckpconfig.BackupDirPath(backupDir, timestamp), fileName)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		t.Fatalf("Failed to create backup dir: %v", err)
+	}
+	content := "globalentries: []\n"
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create backup file: %v", err)
+	}
+	tests := []struct {
+		name        string
+		backupData  gpbckpconfig.BackupConfig
+		fileName    string
+		want        string
+		wantFetched bool
+		wantErr     bool
+	}{
+		{
+			name:        "Test existing file",
+			backupData:  gpbckpconfig.BackupConfig{Timestamp: timestamp, Status: gpbckpconfig.BackupStatusSuccess},
+			fileName:    fileName,
+			want:        content,
+			wantFetched: true,
+		},
+		{
+			name:        "Test missing file",
+			backupData:  gpbckpconfig.BackupConfig{Timestamp: timestamp, Status: gpbckpconfig.BackupStatusSuccess},
+			fileName:    gpbckpconfig.MetadataFileName(timestamp),
+			wantFetched: true,
+			wantErr:     true,
+		},
+		{
+			name:        "Test deleted backup",
+			backupData:  gpbckpconfig.BackupConfig{Timestamp: timestamp, Status: gpbckpconfig.BackupStatusSuccess, DateDeleted: "20240102100000"},
+			fileName:    fileName,
+			wantFetched: false,
+		},
+		{
+			name:        "Test plugin backup",
+			backupData:  gpbckpconfig.BackupConfig{Timestamp: timestamp, Status: gpbckpconfig.BackupStatusSuccess, Plugin: gpbckpconfig.BackupS3Plugin},
+			fileName:    fileName,
+			wantFetched: false,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, fetched, err := getBackupFileLocal(tt.backupData, tt.fileName, backupDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getBackupFileLocal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || fetched != tt.wantFetched {
+				t.Errorf("\nVariables do not match:\n%v %v\nwant:\n%v %v", got, fetched, tt.want, tt.wantFetched)
+			}
+		})
+	}
+}
+
+func TestWriteBackupFetchFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "gpbackup_20240101100000_config.yaml")
+	content := "backupdir: \"\"\n"
+	if err := writeBackupFetchFile(filename, content); err != nil {
+		t.Fatalf("writeBackupFetchFile() error = %v", err)
+	}
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", string(got), content)
+	}
+	// The existing file is not overwritten.
+	if err := writeBackupFetchFile(filename, "new content"); err == nil {
+		t.Errorf("writeBackupFetchFile() error = %v, want error", err)
+	}
+}
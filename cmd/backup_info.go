@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"database/sql"
+	"encoding/csv"
+	"io"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -22,6 +26,9 @@ var (
 	backupInfoExcludeFilter    bool
 	backupInfoTimestamp        string
 	backupInfoShowDetails      bool
+	backupInfoOutputFormat     string
+	backupInfoDatabase         []string
+	backupInfoExcludeDatabase  []string
 )
 
 // Options for the backup-info command.
@@ -34,6 +41,55 @@ type BackupInfoOptions struct {
 	ExcludeFilter    bool
 	Timestamp        string
 	ShowDetails      bool
+	OutputFormat     string
+	DatabaseFilter   gpbckpconfig.DatabaseFilter
+}
+
+// backupInfoRecord contains all fields of the backup and the values calculated from them.
+// It defines a stable schema for the machine-readable output formats.
+// The object filtering details are used only for the table format,
+// for other formats the same data is presented in the filter lists.
+type backupInfoRecord struct {
+	Timestamp              string                  `json:"timestamp" yaml:"timestamp"`
+	EndTime                string                  `json:"end_time" yaml:"end_time"`
+	Status                 string                  `json:"status" yaml:"status"`
+	DatabaseName           string                  `json:"database_name" yaml:"database_name"`
+	DatabaseVersion        string                  `json:"database_version" yaml:"database_version"`
+	BackupVersion          string                  `json:"backup_version" yaml:"backup_version"`
+	BackupDir              string                  `json:"backup_dir" yaml:"backup_dir"`
+	Plugin                 string                  `json:"plugin" yaml:"plugin"`
+	PluginVersion          string                  `json:"plugin_version" yaml:"plugin_version"`
+	Compressed             bool                    `json:"compressed" yaml:"compressed"`
+	CompressionType        string                  `json:"compression_type" yaml:"compression_type"`
+	DataOnly               bool                    `json:"data_only" yaml:"data_only"`
+	MetadataOnly           bool                    `json:"metadata_only" yaml:"metadata_only"`
+	Incremental            bool                    `json:"incremental" yaml:"incremental"`
+	LeafPartitionData      bool                    `json:"leaf_partition_data" yaml:"leaf_partition_data"`
+	SingleDataFile         bool                    `json:"single_data_file" yaml:"single_data_file"`
+	WithoutGlobals         bool                    `json:"without_globals" yaml:"without_globals"`
+	WithStatistics         bool                    `json:"with_statistics" yaml:"with_statistics"`
+	IncludeSchemaFiltered  bool                    `json:"include_schema_filtered" yaml:"include_schema_filtered"`
+	IncludeSchemas         []string                `json:"include_schemas" yaml:"include_schemas"`
+	ExcludeSchemaFiltered  bool                    `json:"exclude_schema_filtered" yaml:"exclude_schema_filtered"`
+	ExcludeSchemas         []string                `json:"exclude_schemas" yaml:"exclude_schemas"`
+	IncludeTableFiltered   bool                    `json:"include_table_filtered" yaml:"include_table_filtered"`
+	IncludeRelations       []string                `json:"include_relations" yaml:"include_relations"`
+	ExcludeTableFiltered   bool                    `json:"exclude_table_filtered" yaml:"exclude_table_filtered"`
+	ExcludeRelations       []string                `json:"exclude_relations" yaml:"exclude_relations"`
+	RestorePlan            []backupInfoRestorePlan `json:"restore_plan" yaml:"restore_plan"`
+	DateDeleted            string                  `json:"date_deleted" yaml:"date_deleted"`
+	BackupDate             string                  `json:"backup_date" yaml:"backup_date"`
+	BackupType             string                  `json:"backup_type" yaml:"backup_type"`
+	ObjectFiltering        string                  `json:"object_filtering" yaml:"object_filtering"`
+	BackupDuration         float64                 `json:"backup_duration" yaml:"backup_duration"`
+	BackupDateDeleted      string                  `json:"backup_date_deleted" yaml:"backup_date_deleted"`
+	ObjectFilteringDetails string                  `json:"-" yaml:"-"`
+}
+
+// backupInfoRestorePlan is a restore plan entry for the machine-readable output formats.
+type backupInfoRestorePlan struct {
+	Timestamp string   `json:"timestamp" yaml:"timestamp"`
+	TableFQNs []string `json:"table_fqns" yaml:"table_fqns"`
 }
 
 var backupInfoCmd = &cobra.Command{
@@ -51,6 +107,8 @@ To display backups of a specific type, use the --type option.
 
 To display backups that include the specified table, use the --table option. 
 The formatting rules for <schema>.<table> match those of the --include-table option in gpbackup.
+The option matches only the object filtering options of the backup.
+To search backups that contain the table, use the backup-search command.
 
 To display backups that include the specified schema, use the --schema option. 
 The formatting rules for <schema> match those of the --include-schema option in gpbackup.
@@ -75,6 +133,19 @@ When --timestamp is set, the following options cannot be used: --type, --table,
 
 To display the "object filtering details" column for all backups without using --timestamp, use the --detail option.
 
+To display only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together and cannot be used with --timestamp.
+
+To change the output format, use the --output option. The following formats are supported:
+  * table - human-readable table (default);
+  * json - JSON array, one object per backup;
+  * yaml - YAML list, one item per backup;
+  * csv - CSV with header, one row per backup.
+The json, yaml and csv formats contain all backup fields from the history database and the calculated values
+(backup date, type, object filtering, duration in seconds and date deleted). The --detail option is applied only to the table format.
+In the csv format, the list values are joined with commas, and the restore plan contains only backup timestamps.
+
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
 If the --history-db option is not specified, the history database will be searched in the current directory.`,
@@ -136,6 +207,24 @@ func init() {
 		false,
 		"show object filtering details",
 	)
+	backupInfoCmd.Flags().StringVar(
+		&backupInfoOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml, csv)",
+	)
+	backupInfoCmd.Flags().StringArrayVar(
+		&backupInfoDatabase,
+		databaseFlagName,
+		[]string{},
+		"show backups only for the specified database, could be specified multiple times",
+	)
+	backupInfoCmd.Flags().StringArrayVar(
+		&backupInfoExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not show backups for the specified database, could be specified multiple times",
+	)
 }
 
 // These flag checks are applied only for backup-info commands.
@@ -147,14 +236,20 @@ func doBackupInfoFlagValidation(flags *pflag.FlagSet) {
 			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupInfoTimestamp, timestampFlagName, err))
 			execOSExit(exitErrorCode)
 		}
-		// --timestamp is not compatible with --type, --table, --schema, --exclude, --failed, --deleted
+		// --timestamp is not compatible with --type, --table, --schema, --exclude, --failed, --deleted, --database, --exclude-database
 		err = checkCompatibleFlags(flags, timestampFlagName,
-			typeFlagName, tableFlagName, schemaFlagName, excludeFlagName, failedFlagName, deletedFlagName)
+			typeFlagName, tableFlagName, schemaFlagName, excludeFlagName, failedFlagName, deletedFlagName, databaseFlagName, excludeDatabaseFlagName)
 		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, timestampFlagName, typeFlagName, tableFlagName, schemaFlagName, excludeFlagName, failedFlagName, deletedFlagName))
+			gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, timestampFlagName, typeFlagName, tableFlagName, schemaFlagName, excludeFlagName, failedFlagName, deletedFlagName, databaseFlagName, excludeDatabaseFlagName))
 			execOSExit(exitErrorCode)
 		}
 	}
+	// database and exclude-database flags cannot be used together.
+	err = checkCompatibleFlags(flags, databaseFlagName, excludeDatabaseFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, databaseFlagName, excludeDatabaseFlagName))
+		execOSExit(exitErrorCode)
+	}
 	// If type is specified and have correct values.
 	if flags.Changed(typeFlagName) {
 		err = checkBackupType(backupInfoBackupTypeFilter)
@@ -177,6 +272,14 @@ func doBackupInfoFlagValidation(flags *pflag.FlagSet) {
 			execOSExit(exitErrorCode)
 		}
 	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(backupInfoOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML, outputFormatCSV)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupInfoOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
 	// If exclude flag is specified, but table or schema flag is not.
 	if flags.Changed(excludeFlagName) && !flags.Changed(tableFlagName) && !flags.Changed(schemaFlagName) {
 		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), tableFlagName, schemaFlagName))
@@ -193,7 +296,6 @@ func doBackupInfo() {
 }
 
 func backupInfo() error {
-	t := table.NewWriter()
 	opts := BackupInfoOptions{
 		ShowDeleted:      backupInfoShowDeleted,
 		ShowFailed:       backupInfoShowFailed,
@@ -203,9 +305,13 @@ func backupInfo() error {
 		ExcludeFilter:    backupInfoExcludeFilter,
 		Timestamp:        backupInfoTimestamp,
 		ShowDetails:      backupInfoShowDetails,
+		OutputFormat:     backupInfoOutputFormat,
+		DatabaseFilter: gpbckpconfig.DatabaseFilter{
+			Include: backupInfoDatabase,
+			Exclude: backupInfoExcludeDatabase,
+		},
 	}
-	initTable(t, opts.ShowDetails)
-	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB))
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
 		return err
@@ -216,58 +322,82 @@ func backupInfo() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
 		}
 	}()
-	err = backupInfoDB(opts, hDB, t)
+	backupList, err := backupInfoDB(opts, hDB)
 	if err != nil {
 		return err
 	}
-	t.Render()
+	err = printBackupInfo(opts.OutputFormat, opts.ShowDetails, backupList, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(opts.OutputFormat, err))
+		return err
+	}
 	return nil
 }
 
-func backupInfoDB(opts BackupInfoOptions, hDB *sql.DB, t table.Writer) error {
+func backupInfoDB(opts BackupInfoOptions, hDB *sql.DB) ([]backupInfoRecord, error) {
+	backupList := make([]backupInfoRecord, 0)
 	// List all according to showDeleted/showFailed
 	if opts.Timestamp == "" {
-		backupList, err := gpbckpconfig.GetBackupNamesDB(opts.ShowDeleted, opts.ShowFailed, hDB)
+		backupNames, err := gpbckpconfig.GetBackupNamesDB(opts.ShowDeleted, opts.ShowFailed, opts.DatabaseFilter, hDB)
 		if err != nil {
 			gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
-			return err
+			return nil, err
 		}
-		for _, backupName := range backupList {
+		for _, backupName := range backupNames {
 			backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
 			if err != nil {
 				gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
-				return err
+				return nil, err
 			}
-			addBackupToTable(opts.BackupTypeFilter, opts.TableNameFilter, opts.SchemaNameFilter, opts.ExcludeFilter, opts.ShowDetails, backupData, t)
+			backupList = addBackupToList(opts.BackupTypeFilter, opts.TableNameFilter, opts.SchemaNameFilter, opts.ExcludeFilter, backupData, backupList)
 		}
-		return nil
+		return backupList, nil
 	}
 	// Timestamp mode: show base backup and only its dependent backups
 	// Verify base backup exists
 	baseBackupData, err := gpbckpconfig.GetBackupDataDB(opts.Timestamp, hDB)
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(opts.Timestamp, err))
-		return err
+		return nil, err
 	}
-	addBackupToTable("", "", "", false, opts.ShowDetails, baseBackupData, t)
+	backupList = addBackupToList("", "", "", false, baseBackupData, backupList)
 	backupDependenciesList, err := gpbckpconfig.GetBackupDependencies(opts.Timestamp, hDB)
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
-		return err
+		return nil, err
 	}
 	for _, depTimestamp := range backupDependenciesList {
 		backupData, err := gpbckpconfig.GetBackupDataDB(depTimestamp, hDB)
 		if err != nil {
 			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(depTimestamp, err))
-			return err
+			return nil, err
 		}
-		addBackupToTable("", "", "", false, opts.ShowDetails, backupData, t)
+		backupList = addBackupToList("", "", "", false, backupData, backupList)
+	}
+	return backupList, nil
+}
+
+// printBackupInfo writes the list of backups to w in the specified format.
+// Backups are written in the order of the list for all formats.
+func printBackupInfo(outputFormat string, includeDetails bool, backupList []backupInfoRecord, w io.Writer) error {
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, backupList)
+	case outputFormatCSV:
+		return writeBackupInfoCSV(backupList, w)
+	default:
+		t := table.NewWriter()
+		initTable(t, includeDetails, w)
+		for _, backupData := range backupList {
+			addBackupToTable(includeDetails, backupData, t)
+		}
+		t.Render()
+		return nil
 	}
-	return nil
 }
 
-func initTable(t table.Writer, includeDetails bool) {
-	t.SetOutputMirror(os.Stdout)
+func initTable(t table.Writer, includeDetails bool, w io.Writer) {
+	t.SetOutputMirror(w)
 	t.SetStyle(table.StyleDefault)
 	t.Style().Options.DrawBorder = false
 	header := table.Row{
@@ -289,12 +419,42 @@ func initTable(t table.Writer, includeDetails bool) {
 }
 
 // addBackupToTable adds a backup to the table for displaying.
+func addBackupToTable(includeDetails bool, backupData backupInfoRecord, t table.Writer) {
+	row := []interface{}{
+		backupData.Timestamp,
+		backupData.BackupDate,
+		backupData.Status,
+		backupData.DatabaseName,
+		backupData.BackupType,
+		backupData.ObjectFiltering,
+		backupData.Plugin,
+		formatBackupDuration(backupData.BackupDuration),
+		backupData.BackupDateDeleted,
+	}
+	if includeDetails {
+		row = append(row, backupData.ObjectFilteringDetails)
+	}
+	t.AppendRow(row)
+}
+
+// addBackupToList adds a backup to the list for displaying, if the backup matches the filters.
 //
 // If errors occur, they are logged, but they are not returned.
 // The main idea is to show the maximum available information and display all errors that occur.
 // But do not fall when errors occur. So, display anyway.
-func addBackupToTable(backupTypeFilter, backupTableFilter, backupSchemaFilter string, backupExcludeFilter, includeDetails bool, backupData gpbckpconfig.BackupConfig, t table.Writer) {
-	var matchToObjectFilter bool
+func addBackupToList(backupTypeFilter, backupTableFilter, backupSchemaFilter string, backupExcludeFilter bool, backupData gpbckpconfig.BackupConfig, backupList []backupInfoRecord) []backupInfoRecord {
+	record := getBackupInfoRecord(backupData)
+	matchToObjectFilter := backupData.CheckObjectFilteringExists(backupTableFilter, backupSchemaFilter, record.ObjectFiltering, backupExcludeFilter)
+	if (backupTypeFilter == "" || backupTypeFilter == record.BackupType) && matchToObjectFilter {
+		backupList = append(backupList, record)
+	}
+	return backupList
+}
+
+// getBackupInfoRecord converts the backup to the record for displaying and calculates the derived values.
+//
+// If errors occur, they are logged, but they are not returned.
+func getBackupInfoRecord(backupData gpbckpconfig.BackupConfig) backupInfoRecord {
 	backupDate, err := backupData.GetBackupDate()
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("date", backupData.Timestamp, err))
@@ -315,22 +475,149 @@ func addBackupToTable(backupTypeFilter, backupTableFilter, backupSchemaFilter st
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("date deletion", backupData.Timestamp, err))
 	}
-	matchToObjectFilter = backupData.CheckObjectFilteringExists(backupTableFilter, backupSchemaFilter, backupFilter, backupExcludeFilter)
-	if (backupTypeFilter == "" || backupTypeFilter == backupType) && matchToObjectFilter {
-		row := []interface{}{
-			backupData.Timestamp,
-			backupDate,
-			backupData.Status,
-			backupData.DatabaseName,
-			backupType,
-			backupFilter,
-			backupData.Plugin,
-			formatBackupDuration(backupDuration),
-			backupDateDeleted,
+	restorePlan := make([]backupInfoRestorePlan, 0, len(backupData.RestorePlan))
+	for _, entry := range backupData.RestorePlan {
+		restorePlan = append(restorePlan, backupInfoRestorePlan{
+			Timestamp: entry.Timestamp,
+			TableFQNs: nonNilList(entry.TableFQNs),
+		})
+	}
+	return backupInfoRecord{
+		Timestamp:              backupData.Timestamp,
+		EndTime:                backupData.EndTime,
+		Status:                 backupData.Status,
+		DatabaseName:           backupData.DatabaseName,
+		DatabaseVersion:        backupData.DatabaseVersion,
+		BackupVersion:          backupData.BackupVersion,
+		BackupDir:              backupData.BackupDir,
+		Plugin:                 backupData.Plugin,
+		PluginVersion:          backupData.PluginVersion,
+		Compressed:             backupData.Compressed,
+		CompressionType:        backupData.CompressionType,
+		DataOnly:               backupData.DataOnly,
+		MetadataOnly:           backupData.MetadataOnly,
+		Incremental:            backupData.Incremental,
+		LeafPartitionData:      backupData.LeafPartitionData,
+		SingleDataFile:         backupData.SingleDataFile,
+		WithoutGlobals:         backupData.WithoutGlobals,
+		WithStatistics:         backupData.WithStatistics,
+		IncludeSchemaFiltered:  backupData.IncludeSchemaFiltered,
+		IncludeSchemas:         nonNilList(backupData.IncludeSchemas),
+		ExcludeSchemaFiltered:  backupData.ExcludeSchemaFiltered,
+		ExcludeSchemas:         nonNilList(backupData.ExcludeSchemas),
+		IncludeTableFiltered:   backupData.IncludeTableFiltered,
+		IncludeRelations:       nonNilList(backupData.IncludeRelations),
+		ExcludeTableFiltered:   backupData.ExcludeTableFiltered,
+		ExcludeRelations:       nonNilList(backupData.ExcludeRelations),
+		RestorePlan:            restorePlan,
+		DateDeleted:            backupData.DateDeleted,
+		BackupDate:             backupDate,
+		BackupType:             backupType,
+		ObjectFiltering:        backupFilter,
+		BackupDuration:         backupDuration,
+		BackupDateDeleted:      backupDateDeleted,
+		ObjectFilteringDetails: backupData.GetObjectFilteringDetails(),
+	}
+}
+
+// writeBackupInfoCSV writes the list of backups to w in csv format with header.
+// The list values are joined with commas.
+// The restore plan contains only backup timestamps.
+func writeBackupInfoCSV(backupList []backupInfoRecord, w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	header := []string{
+		"timestamp",
+		"end_time",
+		"status",
+		"database_name",
+		"database_version",
+		"backup_version",
+		"backup_dir",
+		"plugin",
+		"plugin_version",
+		"compressed",
+		"compression_type",
+		"data_only",
+		"metadata_only",
+		"incremental",
+		"leaf_partition_data",
+		"single_data_file",
+		"without_globals",
+		"with_statistics",
+		"include_schema_filtered",
+		"include_schemas",
+		"exclude_schema_filtered",
+		"exclude_schemas",
+		"include_table_filtered",
+		"include_relations",
+		"exclude_table_filtered",
+		"exclude_relations",
+		"restore_plan",
+		"date_deleted",
+		"backup_date",
+		"backup_type",
+		"object_filtering",
+		"backup_duration",
+		"backup_date_deleted",
+	}
+	err := csvWriter.Write(header)
+	if err != nil {
+		return err
+	}
+	for _, b := range backupList {
+		restorePlan := make([]string, 0, len(b.RestorePlan))
+		for _, entry := range b.RestorePlan {
+			restorePlan = append(restorePlan, entry.Timestamp)
+		}
+		row := []string{
+			b.Timestamp,
+			b.EndTime,
+			b.Status,
+			b.DatabaseName,
+			b.DatabaseVersion,
+			b.BackupVersion,
+			b.BackupDir,
+			b.Plugin,
+			b.PluginVersion,
+			strconv.FormatBool(b.Compressed),
+			b.CompressionType,
+			strconv.FormatBool(b.DataOnly),
+			strconv.FormatBool(b.MetadataOnly),
+			strconv.FormatBool(b.Incremental),
+			strconv.FormatBool(b.LeafPartitionData),
+			strconv.FormatBool(b.SingleDataFile),
+			strconv.FormatBool(b.WithoutGlobals),
+			strconv.FormatBool(b.WithStatistics),
+			strconv.FormatBool(b.IncludeSchemaFiltered),
+			strings.Join(b.IncludeSchemas, ","),
+			strconv.FormatBool(b.ExcludeSchemaFiltered),
+			strings.Join(b.ExcludeSchemas, ","),
+			strconv.FormatBool(b.IncludeTableFiltered),
+			strings.Join(b.IncludeRelations, ","),
+			strconv.FormatBool(b.ExcludeTableFiltered),
+			strings.Join(b.ExcludeRelations, ","),
+			strings.Join(restorePlan, ","),
+			b.DateDeleted,
+			b.BackupDate,
+			b.BackupType,
+			b.ObjectFiltering,
+			strconv.FormatFloat(b.BackupDuration, 'f', -1, 64),
+			b.BackupDateDeleted,
 		}
-		if includeDetails {
-			row = append(row, backupData.GetObjectFilteringDetails())
+		err = csvWriter.Write(row)
+		if err != nil {
+			return err
 		}
-		t.AppendRow(row)
 	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// nonNilList returns an empty list instead of nil,
+// so the list values are always displayed as lists in machine-readable formats.
+func nonNilList(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
 }
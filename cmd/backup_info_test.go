@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetBackupInfoRecord(t *testing.T) {
+	testhelper.SetupTestLogger()
+	backupData := gpbckpconfig.BackupConfig{
+		Timestamp:            "20240101120000",
+		EndTime:              "20240101120130",
+		Status:               gpbckpconfig.BackupStatusSuccess,
+		DatabaseName:         "demo",
+		Incremental:          true,
+		IncludeTableFiltered: true,
+		IncludeRelations:     []string{"sch1.tbl1", "sch1.tbl2"},
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{
+			{Timestamp: "20231231120000", TableFQNs: []string{"sch1.tbl1"}},
+		},
+		DateDeleted: "20240201120000",
+	}
+	got := getBackupInfoRecord(backupData)
+	if got.BackupType != gpbckpconfig.BackupTypeIncremental {
+		t.Errorf("getBackupInfoRecord() BackupType:\n%v\nwant:\n%v", got.BackupType, gpbckpconfig.BackupTypeIncremental)
+	}
+	if got.ObjectFiltering != "include-table" {
+		t.Errorf("getBackupInfoRecord() ObjectFiltering:\n%v\nwant:\n%v", got.ObjectFiltering, "include-table")
+	}
+	if got.BackupDuration != 90 {
+		t.Errorf("getBackupInfoRecord() BackupDuration:\n%v\nwant:\n%v", got.BackupDuration, 90)
+	}
+	if got.BackupDate != "Mon Jan 01 2024 12:00:00" {
+		t.Errorf("getBackupInfoRecord() BackupDate:\n%v\nwant:\n%v", got.BackupDate, "Mon Jan 01 2024 12:00:00")
+	}
+	if got.BackupDateDeleted != "Thu Feb 01 2024 12:00:00" {
+		t.Errorf("getBackupInfoRecord() BackupDateDeleted:\n%v\nwant:\n%v", got.BackupDateDeleted, "Thu Feb 01 2024 12:00:00")
+	}
+	if got.ObjectFilteringDetails != "sch1.tbl1, sch1.tbl2" {
+		t.Errorf("getBackupInfoRecord() ObjectFilteringDetails:\n%v\nwant:\n%v", got.ObjectFilteringDetails, "sch1.tbl1, sch1.tbl2")
+	}
+	if got.ExcludeSchemas == nil || got.ExcludeRelations == nil || got.IncludeSchemas == nil {
+		t.Errorf("getBackupInfoRecord() empty lists must not be nil")
+	}
+	if len(got.RestorePlan) != 1 || got.RestorePlan[0].Timestamp != "20231231120000" {
+		t.Errorf("getBackupInfoRecord() RestorePlan:\n%v", got.RestorePlan)
+	}
+}
+
+func TestPrintBackupInfo(t *testing.T) {
+	testhelper.SetupTestLogger()
+	backupList := []backupInfoRecord{
+		getBackupInfoRecord(gpbckpconfig.BackupConfig{
+			Timestamp:    "20240101120000",
+			EndTime:      "20240101120100",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			DatabaseName: "demo",
+		}),
+		getBackupInfoRecord(gpbckpconfig.BackupConfig{
+			Timestamp:      "20240102120000",
+			EndTime:        "20240102120100",
+			Status:         gpbckpconfig.BackupStatusSuccess,
+			DatabaseName:   "demo",
+			IncludeSchemas: []string{"sch1", "sch2"},
+			Plugin:         gpbckpconfig.BackupS3Plugin,
+		}),
+	}
+	t.Run("JSON format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := printBackupInfo(outputFormatJSON, false, backupList, &buf)
+		if err != nil {
+			t.Fatalf("printBackupInfo() error: %v", err)
+		}
+		var got []map[string]interface{}
+		err = json.Unmarshal(buf.Bytes(), &got)
+		if err != nil {
+			t.Fatalf("Unable to parse json output: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("printBackupInfo() got %d backups, want 2", len(got))
+		}
+		// The order of the list is kept.
+		if got[0]["timestamp"] != "20240101120000" {
+			t.Errorf("printBackupInfo() first timestamp:\n%v\nwant:\n%v", got[0]["timestamp"], "20240101120000")
+		}
+		for _, key := range []string{"backup_type", "object_filtering", "backup_duration", "backup_date_deleted", "restore_plan", "include_schemas"} {
+			if _, ok := got[0][key]; !ok {
+				t.Errorf("printBackupInfo() key %s not found in json output", key)
+			}
+		}
+	})
+	t.Run("CSV format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := printBackupInfo(outputFormatCSV, false, backupList, &buf)
+		if err != nil {
+			t.Fatalf("printBackupInfo() error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("printBackupInfo() got %d csv lines, want 3", len(lines))
+		}
+		if !strings.HasPrefix(lines[0], "timestamp,end_time,status,database_name") {
+			t.Errorf("printBackupInfo() unexpected csv header:\n%v", lines[0])
+		}
+		if !strings.Contains(lines[2], `"sch1,sch2"`) {
+			t.Errorf("printBackupInfo() list values are not joined in csv row:\n%v", lines[2])
+		}
+	})
+	t.Run("Table format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := printBackupInfo(outputFormatTable, false, backupList, &buf)
+		if err != nil {
+			t.Fatalf("printBackupInfo() error: %v", err)
+		}
+		if !strings.Contains(buf.String(), "TIMESTAMP") || !strings.Contains(buf.String(), "20240101120000") {
+			t.Errorf("printBackupInfo() unexpected table output:\n%v", buf.String())
+		}
+	})
+}
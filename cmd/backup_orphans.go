@@ -0,0 +1,556 @@
+package cmd
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman backup-orphans command (backupOrphansCmd)
+var (
+	backupOrphansBackupDir         string
+	backupOrphansDelete            bool
+	backupOrphansIgnoreErrors      bool
+	backupOrphansParallelProcesses int
+	backupOrphansOutputFormat      string
+	backupOrphansMinAgeHours       int
+	backupOrphansYes               bool
+)
+
+// backupOrphanDir is a backup directory on master or segment host, which is not registered in the history database.
+type backupOrphanDir struct {
+	Timestamp string `json:"timestamp" yaml:"timestamp"`
+	Host      string `json:"host" yaml:"host"`
+	ContentID string `json:"content_id" yaml:"content_id"`
+	Path      string `json:"path" yaml:"path"`
+}
+
+// backupOrphanDirs collects the backup directories found in parallel on segment hosts.
+type backupOrphanDirs struct {
+	mu   sync.Mutex
+	list []backupOrphanDir
+}
+
+func (bod *backupOrphanDirs) add(dirs ...backupOrphanDir) {
+	bod.mu.Lock()
+	defer bod.mu.Unlock()
+	bod.list = append(bod.list, dirs...)
+}
+
+var backupOrphansCmd = &cobra.Command{
+	Use:   "backup-orphans",
+	Short: "Find backup directories that are not registered in the history database",
+	Long: `Find backup directories that are not registered in the history database.
+
+Failed deletions, manual history cleaning and crashed gpbackup runs can leave backup directories
+in the format backups/YYYYMMDD/YYYYMMDDHHMMSS on master and segment hosts.
+The command scans the backup directories on master and segment hosts and displays the directories,
+for which there is no backup in the history database.
+Backups marked as deleted in the history database are registered, their directories are not displayed.
+
+gpbackup writes the backup to the history database only at the end of the backup,
+so the directory of the running backup is not registered yet.
+To avoid deleting such directories, only directories older than the newest backup in the history database
+and older than the number of hours set by the --min-age-hours option (24 by default) are displayed.
+If there are no backups in the history database, no directories are displayed.
+The backups made with the --no-history option of gpbackup are never registered in the history database,
+their directories are displayed as orphaned.
+
+The segment hosts are scanned using ssh.
+For control over the number of parallel processes and ssh connections, the --parallel-processes option can be used.
+
+The full path to the backup directory can be set using the --backup-dir option.
+If the --backup-dir option is not specified, the backup folders in the master and segments data directories are scanned.
+
+The cluster configuration is read from the local cluster.
+The connection parameters are taken from the PGUSER, PGHOST, PGPORT and PGDATABASE environment variables.
+If the PGDATABASE environment variable is not set, the postgres database is used.
+
+To delete the found directories, use the --delete option.
+Before deletion, the confirmation is asked. To delete directories without confirmation, use the --yes option.
+In non-interactive mode, the --yes option is required.
+After the confirmation, the history database is locked and the directories are checked again,
+the directories of the backups registered in the meantime are not deleted.
+Before deletion, it is checked that all found directories still exist.
+If the check fails for at least one directory, nothing is deleted.
+To ignore errors during the check and deletion, use the --ignore-errors option.
+
+To change the output format, use the --output option. The following formats are supported: table (default), json, yaml.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doBackupOrphansFlagValidation(cmd.Flags())
+		doBackupOrphans()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupOrphansCmd)
+	backupOrphansCmd.PersistentFlags().StringVar(
+		&backupOrphansBackupDir,
+		backupDirFlagName,
+		"",
+		"the full path to backup directory",
+	)
+	backupOrphansCmd.PersistentFlags().BoolVar(
+		&backupOrphansDelete,
+		deleteFlagName,
+		false,
+		"delete the found orphaned backup directories",
+	)
+	backupOrphansCmd.PersistentFlags().BoolVar(
+		&backupOrphansIgnoreErrors,
+		ignoreErrorsFlagName,
+		false,
+		"ignore errors when checking and deleting orphaned backup directories",
+	)
+	backupOrphansCmd.PersistentFlags().IntVar(
+		&backupOrphansParallelProcesses,
+		parallelProcessesFlagName,
+		1,
+		"the number of parallel processes to scan and delete backup directories on segments",
+	)
+	backupOrphansCmd.PersistentFlags().IntVar(
+		&backupOrphansMinAgeHours,
+		minAgeHoursFlagName,
+		24,
+		"the minimum age in hours of the backup directory to be considered orphaned",
+	)
+	backupOrphansCmd.PersistentFlags().BoolVar(
+		&backupOrphansYes,
+		yesFlagName,
+		false,
+		"delete orphaned backup directories without confirmation",
+	)
+	backupOrphansCmd.PersistentFlags().StringVar(
+		&backupOrphansOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+}
+
+// These flag checks are applied only for backup-orphans command.
+func doBackupOrphansFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If parallel-processes flag is specified and have correct values.
+	if flags.Changed(parallelProcessesFlagName) && !gpbckpconfig.IsPositiveValue(backupOrphansParallelProcesses) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(backupOrphansParallelProcesses), parallelProcessesFlagName, err))
+		execOSExit(exitErrorCode)
+	}
+	// If min-age-hours flag is specified and have correct values.
+	if flags.Changed(minAgeHoursFlagName) && backupOrphansMinAgeHours < 0 {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(backupOrphansMinAgeHours), minAgeHoursFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+	// If yes flag is specified, but delete flag is not.
+	if flags.Changed(yesFlagName) && !flags.Changed(deleteFlagName) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), yesFlagName, deleteFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If backup-dir flag is specified and it exists and the full path is specified.
+	if flags.Changed(backupDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupOrphansBackupDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupOrphansBackupDir, backupDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(backupOrphansOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupOrphansOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doBackupOrphans() {
+	logHeadersDebug()
+	err := backupOrphans()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func backupOrphans() error {
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	defer closeSSHConnections()
+	dbName := getClusterDatabase()
+	bckpDir, segPrefix, isSingleBackupDir, err := getBackupMasterDir(backupOrphansBackupDir, "", dbName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupDirLocalClusterConn(err))
+		return err
+	}
+	gplog.Debug("%s", textmsg.InfoTextBackupDirPath(bckpDir))
+	gplog.Debug("%s", textmsg.InfoTextSegmentPrefix(segPrefix))
+	segConfig, err := getSegmentConfigurationClusterInfo(dbName)
+	if err != nil {
+		return err
+	}
+	sshClientDialer, err := getSSHDialer()
+	if err != nil {
+		return err
+	}
+	foundDirs, err := scanBackupDirs(backupOrphansBackupDir, bckpDir, segPrefix, isSingleBackupDir, segConfig, backupOrphansParallelProcesses, sshClientDialer)
+	if err != nil && !backupOrphansIgnoreErrors {
+		return err
+	}
+	orphanDirs, err := getOrphanBackupDirs(foundDirs, backupOrphansMinAgeHours, hDB)
+	if err != nil {
+		return err
+	}
+	err = printBackupOrphans(backupOrphansOutputFormat, orphanDirs, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(backupOrphansOutputFormat, err))
+		return err
+	}
+	if len(orphanDirs) == 0 || !backupOrphansDelete {
+		return nil
+	}
+	// The confirmation is asked before locking the history database,
+	// so the unanswered prompt doesn't block other commands.
+	err = confirmOrphanBackupDirsDeletion(orphanDirs, backupOrphansYes)
+	if err != nil {
+		return err
+	}
+	unlock, err := lockHistoryDB(getHistoryDBPath(rootHistoryDB))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// The backups could be registered, while the confirmation was asked.
+	orphanDirs, err = getOrphanBackupDirs(orphanDirs, backupOrphansMinAgeHours, hDB)
+	if err != nil || len(orphanDirs) == 0 {
+		return err
+	}
+	return deleteOrphanBackupDirs(orphanDirs, backupOrphansParallelProcesses, backupOrphansIgnoreErrors, sshClientDialer)
+}
+
+// getOrphanBackupDirs Returns backup directories, for which there is no backup in the history database,
+// and which are old enough to be considered orphaned, see getOrphanMaxTimestamp.
+func getOrphanBackupDirs(foundDirs []backupOrphanDir, minAgeHours int, hDB *sql.DB) ([]backupOrphanDir, error) {
+	backupNames, err := gpbckpconfig.GetBackupNamesDB(true, true, gpbckpconfig.DatabaseFilter{}, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+		return nil, err
+	}
+	return filterOrphanBackupDirs(foundDirs, backupNames, getOrphanMaxTimestamp(backupNames, minAgeHours, time.Now())), nil
+}
+
+// getOrphanMaxTimestamp Returns the timestamp, before which the unregistered backup directories are considered orphaned.
+// gpbackup writes the backup to the history database only at the end of the backup,
+// so the directories of running backups are newer than the newest registered backup.
+// The timestamp is the earliest of the newest backup timestamp and the current time minus the minimum age.
+// If there are no backups in the history database, the empty timestamp is returned.
+// The backup names must be sorted by timestamp in descending order.
+func getOrphanMaxTimestamp(backupNames []string, minAgeHours int, now time.Time) string {
+	if len(backupNames) == 0 {
+		return ""
+	}
+	maxTimestamp := now.Add(-time.Duration(minAgeHours) * time.Hour).Format(gpbckpconfig.Layout)
+	if backupNames[0] < maxTimestamp {
+		return backupNames[0]
+	}
+	return maxTimestamp
+}
+
+// confirmOrphanBackupDirsDeletion Asks for the confirmation of the deletion of the orphaned backup directories.
+// If assumeYes is true, the confirmation is not asked.
+// If the standard input is not a terminal, the deletion is refused without assumeYes.
+func confirmOrphanBackupDirsDeletion(orphanDirs []backupOrphanDir, assumeYes bool) error {
+	if assumeYes {
+		return nil
+	}
+	if !isInteractiveInput() {
+		gplog.Error("%s", textmsg.ErrorConfirmationRequiredError())
+		return textmsg.ErrorConfirmationRequiredError()
+	}
+	confirmed, err := askConfirmation(confirmationInput, os.Stdout, textmsg.InfoTextOrphanBackupDirDeleteConfirmation(strconv.Itoa(len(orphanDirs))))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		gplog.Error("%s", textmsg.ErrorOrphanBackupDirDeleteNotConfirmedError())
+		return textmsg.ErrorOrphanBackupDirDeleteNotConfirmedError()
+	}
+	return nil
+}
+
+// getClusterDatabase Returns the database name for connection to the local cluster.
+func getClusterDatabase() string {
+	dbName := operating.System.Getenv("PGDATABASE")
+	if dbName == "" {
+		return defaultClusterDatabase
+	}
+	return dbName
+}
+
+// scanBackupDirs Returns all backup directories found on master and segment hosts.
+// The backupDir is the value of the --backup-dir option, bckpDir is the resolved backup directory on master.
+// If the same directory is used by several segments on the host, it's scanned only once.
+func scanBackupDirs(backupDir, bckpDir, segPrefix string, isSingleBackupDir bool, segConfig []gpbckpconfig.SegmentConfig, maxParallelProcesses int, sshClientDialer sshDialer) ([]backupOrphanDir, error) {
+	var scanErr error
+	masterHost, err := os.Hostname()
+	if err != nil {
+		masterHost = "localhost"
+	}
+	foundDirs := &backupOrphanDirs{}
+	masterPaths, err := filepath.Glob(filepath.Join(bckpDir, "backups", "*", "*"))
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableScanBackupDir(bckpDir, masterHost, err))
+		scanErr = err
+	}
+	foundDirs.add(getBackupDirsFromPaths(masterPaths, masterHost, "-1")...)
+	var configs []gpbckpconfig.SegmentConfig
+	var paths []string
+	scannedPaths := make(map[string]int)
+	for _, config := range segConfig {
+		backupPath, err := getBackupSegmentDir(backupDir, "", config.DataDir, segPrefix, config.ContentID, isSingleBackupDir)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupDirLocalClusterConn(err))
+			return nil, err
+		}
+		key := config.Hostname + ":" + backupPath
+		if i, ok := scannedPaths[key]; ok {
+			configs[i].ContentID += "," + config.ContentID
+			continue
+		}
+		scannedPaths[key] = len(configs)
+		configs = append(configs, config)
+		paths = append(paths, filepath.Join(backupPath, "backups"))
+	}
+	errList := runOnSegments(configs, paths, maxParallelProcesses, sshClientDialer,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			segmentPaths, err := listBackupDirsOnSegment(paths, host, sshDial)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableScanBackupDir(strings.Join(paths, ", "), host, err))
+				errCh <- err
+				return
+			}
+			for i, config := range configs {
+				foundDirs.add(getBackupDirsFromPaths(filterPathsByPrefix(segmentPaths, paths[i]), host, config.ContentID)...)
+			}
+		})
+	for _, err := range errList {
+		if err != nil {
+			scanErr = err
+		}
+	}
+	return foundDirs.list, scanErr
+}
+
+// listBackupDirsOnSegment Returns the list of directories at the second level of the backups directories on the segment host.
+// All directories are scanned by one command.
+func listBackupDirsOnSegment(paths []string, host string, sshDial sshDialer) ([]string, error) {
+	command := fmt.Sprintf("for path in %s; do if [ -d \"$path\" ]; then find \"$path\" -mindepth 2 -maxdepth 2 -type d; fi; done", shellQuoteList(paths))
+	output, err := runCommandOnHost(command, host, sshDial)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result, scanner.Err()
+}
+
+// filterPathsByPrefix Returns the paths inside the directory.
+func filterPathsByPrefix(paths []string, dir string) []string {
+	var result []string
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	for _, path := range paths {
+		if strings.HasPrefix(path, prefix) {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// getBackupDirsFromPaths Returns backup directories from the list of paths.
+// Paths that are not in the format backups/YYYYMMDD/YYYYMMDDHHMMSS are skipped.
+func getBackupDirsFromPaths(paths []string, host, contentID string) []backupOrphanDir {
+	var result []backupOrphanDir
+	for _, path := range paths {
+		timestamp, err := gpbckpconfig.GetTimestampFromBackupDirPath(path)
+		if err != nil {
+			continue
+		}
+		result = append(result, backupOrphanDir{
+			Timestamp: timestamp,
+			Host:      host,
+			ContentID: contentID,
+			Path:      path,
+		})
+	}
+	return result
+}
+
+// filterOrphanBackupDirs Returns backup directories, for which there is no backup in the history database.
+// The directories with the timestamp not earlier than maxTimestamp are skipped, they could belong to running backups.
+// The result is sorted by timestamp in descending order, then by content id and path.
+func filterOrphanBackupDirs(foundDirs []backupOrphanDir, backupNames []string, maxTimestamp string) []backupOrphanDir {
+	registered := make(map[string]bool, len(backupNames))
+	for _, backupName := range backupNames {
+		registered[backupName] = true
+	}
+	result := make([]backupOrphanDir, 0)
+	for _, dir := range foundDirs {
+		if registered[dir.Timestamp] {
+			continue
+		}
+		if dir.Timestamp >= maxTimestamp {
+			gplog.Debug("%s", textmsg.InfoTextOrphanBackupDirSkipped(dir.Path, dir.Host))
+			continue
+		}
+		result = append(result, dir)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Timestamp != result[j].Timestamp {
+			return result[i].Timestamp > result[j].Timestamp
+		}
+		if result[i].ContentID != result[j].ContentID {
+			return compareContentID(result[i].ContentID, result[j].ContentID)
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+// compareContentID Returns true, if the first content id is less than the second one.
+// For the list of content ids the first one is compared.
+func compareContentID(a, b string) bool {
+	contentA, _ := strconv.Atoi(strings.Split(a, ",")[0])
+	contentB, _ := strconv.Atoi(strings.Split(b, ",")[0])
+	return contentA < contentB
+}
+
+// deleteOrphanBackupDirs Deletes orphaned backup directories on master and segment hosts.
+// At first, it's checked that all directories exist, and only if all checks passed, the directories are deleted.
+func deleteOrphanBackupDirs(orphanDirs []backupOrphanDir, maxParallelProcesses int, ignoreErrors bool, sshClientDialer sshDialer) error {
+	var masterDirs []backupOrphanDir
+	var configs []gpbckpconfig.SegmentConfig
+	var paths []string
+	for _, dir := range orphanDirs {
+		if dir.ContentID == "-1" {
+			masterDirs = append(masterDirs, dir)
+			continue
+		}
+		configs = append(configs, gpbckpconfig.SegmentConfig{ContentID: dir.ContentID, Hostname: dir.Host})
+		paths = append(paths, dir.Path)
+	}
+	// Check that the directories exist on master and all segment hosts.
+	for _, dir := range masterDirs {
+		info, err := os.Stat(dir.Path)
+		if err == nil && !info.IsDir() {
+			err = textmsg.ErrorNotFoundBackupDirIn(dir.Path)
+		}
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableDeleteOrphanBackupDir(dir.Path, dir.Host, err))
+			if !ignoreErrors {
+				return err
+			}
+		}
+	}
+	errList := runOnSegments(configs, paths, maxParallelProcesses, sshClientDialer,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			checkBackupDirExistsOnSegments(paths, host, sshDial, errCh)
+		})
+	for _, err := range errList {
+		if err != nil && !ignoreErrors {
+			return err
+		}
+	}
+	// If all checks passed, delete the directories.
+	var deleteErr error
+	errList = runOnSegments(configs, paths, maxParallelProcesses, sshClientDialer,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			segErrCh := make(chan error, 1)
+			deleteBackupDirOnSegments(paths, host, sshDial, segErrCh)
+			close(segErrCh)
+			if err := <-segErrCh; err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableDeleteOrphanBackupDir(strings.Join(paths, ", "), host, err))
+				errCh <- err
+				return
+			}
+			for _, path := range paths {
+				gplog.Info("%s", textmsg.InfoTextOrphanBackupDirDeleteSuccess(path, host))
+			}
+		})
+	for _, err := range errList {
+		if err != nil {
+			deleteErr = err
+		}
+	}
+	for _, dir := range masterDirs {
+		gplog.Debug("%s", textmsg.InfoTextCommandExecution("delete directory", dir.Path))
+		err := os.RemoveAll(dir.Path)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableDeleteOrphanBackupDir(dir.Path, dir.Host, err))
+			deleteErr = err
+			continue
+		}
+		gplog.Info("%s", textmsg.InfoTextOrphanBackupDirDeleteSuccess(dir.Path, dir.Host))
+	}
+	if deleteErr != nil && !ignoreErrors {
+		return deleteErr
+	}
+	return nil
+}
+
+// printBackupOrphans writes the orphaned backup directories to w in the specified format.
+func printBackupOrphans(outputFormat string, orphanDirs []backupOrphanDir, w io.Writer) error {
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, orphanDirs)
+	default:
+		if len(orphanDirs) == 0 {
+			gplog.Info("%s", textmsg.InfoTextNothingToDo())
+			return nil
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"timestamp", "host", "content", "path"})
+		for _, dir := range orphanDirs {
+			t.AppendRow(table.Row{dir.Timestamp, dir.Host, dir.ContentID, dir.Path})
+		}
+		t.Render()
+		return nil
+	}
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+)
+
+func TestGetBackupDirsFromPaths(t *testing.T) {
+	paths := []string{
+		"/data/primary/gpseg0/backups/20240101/20240101120000",
+		"/data/primary/gpseg0/backups/20240101/some_dir",
+		"/data/primary/gpseg0/backups/20240102/20240101120000",
+	}
+	want := []backupOrphanDir{
+		{Timestamp: "20240101120000", Host: "sdw1", ContentID: "0", Path: "/data/primary/gpseg0/backups/20240101/20240101120000"},
+	}
+	if got := getBackupDirsFromPaths(paths, "sdw1", "0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestFilterOrphanBackupDirs(t *testing.T) {
+	testhelper.SetupTestLogger()
+	foundDirs := []backupOrphanDir{
+		{Timestamp: "20240101120000", Host: "sdw1", ContentID: "10", Path: "/data/primary/gpseg10/backups/20240101/20240101120000"},
+		{Timestamp: "20240101120000", Host: "sdw1", ContentID: "2", Path: "/data/primary/gpseg2/backups/20240101/20240101120000"},
+		{Timestamp: "20240102120000", Host: "mdw", ContentID: "-1", Path: "/data/master/gpseg-1/backups/20240102/20240102120000"},
+		{Timestamp: "20240103120000", Host: "mdw", ContentID: "-1", Path: "/data/master/gpseg-1/backups/20240103/20240103120000"},
+	}
+	tests := []struct {
+		name         string
+		backupNames  []string
+		maxTimestamp string
+		want         []backupOrphanDir
+	}{
+		{
+			name:         "Test all directories are registered",
+			backupNames:  []string{"20240101120000", "20240102120000", "20240103120000"},
+			maxTimestamp: "20240104000000",
+			want:         []backupOrphanDir{},
+		},
+		{
+			name:         "Test orphaned directories",
+			backupNames:  []string{"20240102120000"},
+			maxTimestamp: "20240104000000",
+			want: []backupOrphanDir{
+				foundDirs[3],
+				foundDirs[1],
+				foundDirs[0],
+			},
+		},
+		{
+			name:         "Test directories of backups in progress",
+			backupNames:  []string{"20240102120000"},
+			maxTimestamp: "20240102120000",
+			want: []backupOrphanDir{
+				foundDirs[1],
+				foundDirs[0],
+			},
+		},
+		{
+			name:         "Test empty history database",
+			maxTimestamp: "",
+			want:         []backupOrphanDir{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterOrphanBackupDirs(foundDirs, tt.backupNames, tt.maxTimestamp); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOrphanMaxTimestamp(t *testing.T) {
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name        string
+		backupNames []string
+		minAgeHours int
+		want        string
+	}{
+		{
+			name:        "Test newest backup is older than minimum age",
+			backupNames: []string{"20240103120000", "20240101120000"},
+			minAgeHours: 24,
+			want:        "20240103120000",
+		},
+		{
+			name:        "Test newest backup is newer than minimum age",
+			backupNames: []string{"20240105110000", "20240101120000"},
+			minAgeHours: 24,
+			want:        "20240104120000",
+		},
+		{
+			name:        "Test zero minimum age",
+			backupNames: []string{"20240105110000"},
+			minAgeHours: 0,
+			want:        "20240105110000",
+		},
+		{
+			name:        "Test empty history database",
+			minAgeHours: 24,
+			want:        "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getOrphanMaxTimestamp(tt.backupNames, tt.minAgeHours, now); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmOrphanBackupDirsDeletion(t *testing.T) {
+	testhelper.SetupTestLogger()
+	defer func(input io.Reader, interactive func() bool) {
+		confirmationInput = input
+		isInteractiveInput = interactive
+	}(confirmationInput, isInteractiveInput)
+	orphanDirs := []backupOrphanDir{{Timestamp: "20240101120000", Host: "mdw", ContentID: "-1", Path: "/data/backups/20240101/20240101120000"}}
+	tests := []struct {
+		name        string
+		assumeYes   bool
+		interactive bool
+		input       string
+		wantErr     bool
+	}{
+		{
+			name:      "Test assume yes",
+			assumeYes: true,
+		},
+		{
+			name:        "Test confirmed",
+			interactive: true,
+			input:       "y\n",
+		},
+		{
+			name:        "Test not confirmed",
+			interactive: true,
+			input:       "n\n",
+			wantErr:     true,
+		},
+		{
+			name:    "Test non-interactive mode",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmationInput = strings.NewReader(tt.input)
+			isInteractiveInput = func() bool { return tt.interactive }
+			err := confirmOrphanBackupDirsDeletion(orphanDirs, tt.assumeYes)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("confirmOrphanBackupDirsDeletion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScanAndDeleteOrphanBackupDirsOnMaster(t *testing.T) {
+	testhelper.SetupTestLogger()
+	tempDir := t.TempDir()
+	orphanPath := filepath.Join(tempDir, "backups", "20240101", "20240101120000")
+	registeredPath := filepath.Join(tempDir, "backups", "20240102", "20240102120000")
+	for _, path := range []string{orphanPath, registeredPath} {
+		if err := os.MkdirAll(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	foundDirs, err := scanBackupDirs(tempDir, tempDir, "", true, nil, 1, nil)
+	if err != nil {
+		t.Fatalf("scanBackupDirs() error: %v", err)
+	}
+	orphanDirs := filterOrphanBackupDirs(foundDirs, []string{"20240102120000"}, "20240102120000")
+	if len(orphanDirs) != 1 || orphanDirs[0].Path != orphanPath || orphanDirs[0].ContentID != "-1" {
+		t.Fatalf("\nVariables do not match:\n%v\nwant:\n%v", orphanDirs, orphanPath)
+	}
+	if err := deleteOrphanBackupDirs(orphanDirs, 1, false, nil); err != nil {
+		t.Fatalf("deleteOrphanBackupDirs() error: %v", err)
+	}
+	if _, err := os.Stat(orphanPath); !os.IsNotExist(err) {
+		t.Errorf("deleteOrphanBackupDirs() directory %s was not deleted", orphanPath)
+	}
+	if _, err := os.Stat(registeredPath); err != nil {
+		t.Errorf("deleteOrphanBackupDirs() directory %s was deleted", registeredPath)
+	}
+	// The directory does not exist anymore, the check must fail.
+	if err := deleteOrphanBackupDirs(orphanDirs, 1, false, nil); err == nil {
+		t.Errorf("deleteOrphanBackupDirs() expected error for missing directory")
+	}
+}
+
+func TestPrintBackupOrphans(t *testing.T) {
+	testhelper.SetupTestLogger()
+	orphanDirs := []backupOrphanDir{
+		{Timestamp: "20240101120000", Host: "sdw1", ContentID: "0", Path: "/data/primary/gpseg0/backups/20240101/20240101120000"},
+	}
+	var buf bytes.Buffer
+	if err := printBackupOrphans(outputFormatJSON, orphanDirs, &buf); err != nil {
+		t.Fatalf("printBackupOrphans() error: %v", err)
+	}
+	var got []backupOrphanDir
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printBackupOrphans() invalid json: %v", err)
+	}
+	if !reflect.DeepEqual(got, orphanDirs) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, orphanDirs)
+	}
+}
+
+func TestListBackupDirsOnSegment(t *testing.T) {
+	testhelper.SetupTestLogger()
+	server := newTestSSHServer(t)
+	host, port := server.hostPort(t)
+	transport, err := newSSHTransport(sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("newSSHTransport() error: %v", err)
+	}
+	tempDir := t.TempDir()
+	seg0Path := filepath.Join(tempDir, "gpseg0 dir", "backups", "20240101", "20240101120000")
+	seg1Path := filepath.Join(tempDir, "gpseg1", "backups", "20240102", "20240102120000")
+	for _, path := range []string{seg0Path, seg1Path} {
+		if err := os.MkdirAll(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backupsPaths := []string{
+		filepath.Join(tempDir, "gpseg0 dir", "backups"),
+		filepath.Join(tempDir, "gpseg1", "backups"),
+		filepath.Join(tempDir, "gpseg2", "backups"),
+	}
+	got, err := listBackupDirsOnSegment(backupsPaths, host, transport)
+	if err != nil {
+		t.Fatalf("listBackupDirsOnSegment() error: %v", err)
+	}
+	if want := []string{seg0Path, seg1Path}; !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	if want := []string{seg1Path}; !reflect.DeepEqual(filterPathsByPrefix(got, backupsPaths[1]), want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", filterPathsByPrefix(got, backupsPaths[1]), want)
+	}
+}
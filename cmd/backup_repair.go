@@ -0,0 +1,522 @@
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"strconv"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman backup-repair command (backupRepairCmd)
+var (
+	backupRepairTimestamp            []string
+	backupRepairPluginConfigFile     string
+	backupRepairReportFilePluginPath string
+	backupRepairBackupDir            string
+	backupRepairParallelProcesses    int
+	backupRepairDelete               bool
+	backupRepairDryRun               bool
+	backupRepairIgnoreErrors         bool
+	backupRepairDatabase             []string
+	backupRepairExcludeDatabase      []string
+)
+
+var backupRepairCmd = &cobra.Command{
+	Use:   "backup-repair",
+	Short: "Repair backups with unfinished deletion",
+	Long: `Repair backups with unfinished deletion.
+
+If the deletion process was interrupted or failed, the backup remains in the history database
+with "In progress", "Plugin Backup Delete Failed" or "Local Delete Failed" deletion status.
+The command finds such backups, checks the state of the backup files in the storage and repairs the backups:
+  * If all backup files exist, the backup is restored to active state.
+    To finish the deletion instead, use the --delete option.
+  * If some of the backup files exist, the deletion is finished.
+  * If no backup files exist, the backup is marked as deleted.
+
+For local backups, the same directories and files as in the backup-verify command are checked on master and segment hosts.
+For backups made with the storage plugin, the backup report is read using the storage plugin.
+The storage plugin doesn't provide the way to check that all backup files exist,
+the report could remain in the storage after the data files have been deleted.
+So such backups are never restored to active state, the deletion is always finished using the storage plugin.
+
+To repair specific backups, use the --timestamp option. It could be specified multiple times.
+If the --timestamp option is not specified, all backups with unfinished deletion are repaired.
+
+To repair only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together and cannot be used with --timestamp.
+
+By default, the repair will be performed for local backup.
+
+The full path to the backup directory can be set using the --backup-dir option.
+
+For local backups the following logic are applied:
+  * If the --backup-dir option is specified, the files will be searched in provided path.
+  * If the --backup-dir option is not specified, but the backup was made with --backup-dir flag for gpbackup, the files will be searched in the backup manifest path.
+  * If the --backup-dir option is not specified and backup directory is not specified in backup manifest, the files will be searched in backup folder in the master and segments data directories.
+
+For control over the number of parallel processes and ssh connections for local backups, the --parallel-processes option can be used.
+
+The storage plugin config file location can be set using the --plugin-config option.
+The full path to the file is required. In this case, the repair will be performed using the storage plugin.
+For plugins with unknown report path, the path to the report file must be set using the --plugin-report-file-path option
+or using the gpbackman_report_path_template option in the plugin config file. See the report-info command for details.
+
+To display the repair plan without changing anything, use the --dry-run option.
+
+To continue with other backups if the repair of the backup fails, use the --ignore-errors option.
+
+Do not run the command while the backups are being deleted by another gpbackman process.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doBackupRepairFlagValidation(cmd.Flags())
+		doBackupRepair()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupRepairCmd)
+	backupRepairCmd.PersistentFlags().StringArrayVar(
+		&backupRepairTimestamp,
+		timestampFlagName,
+		[]string{},
+		"the backup timestamp for repair, could be specified multiple times",
+	)
+	backupRepairCmd.PersistentFlags().StringVar(
+		&backupRepairPluginConfigFile,
+		pluginConfigFileFlagName,
+		"",
+		"the full path to plugin config file",
+	)
+	backupRepairCmd.PersistentFlags().StringVar(
+		&backupRepairReportFilePluginPath,
+		reportFilePluginPathFlagName,
+		"",
+		"the full path to plugin report file",
+	)
+	backupRepairCmd.PersistentFlags().StringVar(
+		&backupRepairBackupDir,
+		backupDirFlagName,
+		"",
+		"the full path to backup directory for local backups",
+	)
+	backupRepairCmd.PersistentFlags().IntVar(
+		&backupRepairParallelProcesses,
+		parallelProcessesFlagName,
+		1,
+		"the number of parallel processes to check and delete local backups",
+	)
+	backupRepairCmd.PersistentFlags().BoolVar(
+		&backupRepairDelete,
+		deleteFlagName,
+		false,
+		"finish the deletion even if all backup files exist",
+	)
+	backupRepairCmd.PersistentFlags().BoolVar(
+		&backupRepairDryRun,
+		dryRunFlagName,
+		false,
+		"show the repair plan without changing anything",
+	)
+	backupRepairCmd.PersistentFlags().BoolVar(
+		&backupRepairIgnoreErrors,
+		ignoreErrorsFlagName,
+		false,
+		"continue with other backups if the repair of the backup fails",
+	)
+	backupRepairCmd.PersistentFlags().StringArrayVar(
+		&backupRepairDatabase,
+		databaseFlagName,
+		[]string{},
+		"repair only backups of the specified database, could be specified multiple times",
+	)
+	backupRepairCmd.PersistentFlags().StringArrayVar(
+		&backupRepairExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not repair backups of the specified database, could be specified multiple times",
+	)
+	backupRepairCmd.MarkFlagsMutuallyExclusive(databaseFlagName, excludeDatabaseFlagName)
+}
+
+// These flag checks are applied only for backup-repair command.
+func doBackupRepairFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If timestamps are specified and have correct values.
+	if flags.Changed(timestampFlagName) {
+		for _, timestamp := range backupRepairTimestamp {
+			err = gpbckpconfig.CheckTimestamp(timestamp)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(timestamp, timestampFlagName, err))
+				execOSExit(exitErrorCode)
+			}
+		}
+		// --timestamp is not compatible with --database, --exclude-database
+		err = checkCompatibleFlags(flags, timestampFlagName, databaseFlagName, excludeDatabaseFlagName)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, timestampFlagName, databaseFlagName, excludeDatabaseFlagName))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// backup-dir anf plugin-config flags cannot be used together.
+	err = checkCompatibleFlags(flags, backupDirFlagName, pluginConfigFileFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, backupDirFlagName, pluginConfigFileFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If parallel-processes flag is specified and have correct values.
+	if flags.Changed(parallelProcessesFlagName) && !gpbckpconfig.IsPositiveValue(backupRepairParallelProcesses) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(backupRepairParallelProcesses), parallelProcessesFlagName, err))
+		execOSExit(exitErrorCode)
+	}
+	// plugin-config and parallel-precesses flags cannot be used together.
+	err = checkCompatibleFlags(flags, parallelProcessesFlagName, pluginConfigFileFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, parallelProcessesFlagName, pluginConfigFileFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If backup-dir flag is specified and it exists and the full path is specified.
+	if flags.Changed(backupDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupRepairBackupDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupRepairBackupDir, backupDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-config flag is specified and it exists and the full path is specified.
+	if flags.Changed(pluginConfigFileFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupRepairPluginConfigFile, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupRepairPluginConfigFile, pluginConfigFileFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-report-file-path flag is specified.
+	if flags.Changed(reportFilePluginPathFlagName) {
+		// But plugin-config flag is not specified.
+		if !flags.Changed(pluginConfigFileFlagName) {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), reportFilePluginPathFlagName, pluginConfigFileFlagName))
+			execOSExit(exitErrorCode)
+		}
+		// Check full path.
+		err = gpbckpconfig.CheckFullPath(backupRepairReportFilePluginPath, false)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupRepairReportFilePluginPath, reportFilePluginPathFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doBackupRepair() {
+	logHeadersDebug()
+	err := backupRepair()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func backupRepair() error {
+	if !backupRepairDryRun {
+		unlock, err := lockHistoryDB(getHistoryDBPath(rootHistoryDB))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	defer closeSSHConnections()
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: backupRepairDatabase,
+		Exclude: backupRepairExcludeDatabase,
+	}
+	var repairer backupRepairInterface
+	skipLocalBackup := false
+	if backupRepairPluginConfigFile != "" {
+		pluginConfig, err := utils.ReadPluginConfig(backupRepairPluginConfigFile)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableReadPluginConfigFile(err))
+			return err
+		}
+		// Skip local backups.
+		skipLocalBackup = true
+		repairer = &backupPluginRepairer{
+			backupPluginDeleter: backupPluginDeleter{
+				pluginConfigPath: backupRepairPluginConfigFile,
+				pluginConfig:     pluginConfig,
+			},
+			reportFilePath: backupRepairReportFilePluginPath,
+		}
+	} else {
+		repairer = &backupLocalRepairer{
+			backupDir:            backupRepairBackupDir,
+			maxParallelProcesses: backupRepairParallelProcesses,
+		}
+	}
+	backupList, err := fetchBackupNamesForRepair(backupRepairTimestamp, skipLocalBackup, dbFilter, hDB)
+	if err != nil {
+		return err
+	}
+	if len(backupList) == 0 {
+		gplog.Info("%s", textmsg.InfoTextNothingToDo())
+		return nil
+	}
+	if !backupRepairDryRun {
+		err = snapshotHistoryDB(getHistoryDBPath(rootHistoryDB))
+		if err != nil {
+			return err
+		}
+	}
+	var repairErr error
+	for _, backupName := range backupList {
+		err = backupRepairDB(backupName, backupRepairDelete, backupRepairDryRun, backupRepairIgnoreErrors, repairer, hDB)
+		if err != nil {
+			if !backupRepairIgnoreErrors {
+				return err
+			}
+			repairErr = err
+		}
+	}
+	return repairErr
+}
+
+// Get the list of backup names for repair.
+// If timestamps are not specified, all backups with unfinished deletion are returned.
+func fetchBackupNamesForRepair(timestamps []string, skipLocalBackup bool, dbFilter gpbckpconfig.DatabaseFilter, hDB *sql.DB) ([]string, error) {
+	var backupList []string
+	if len(timestamps) > 0 {
+		for _, backupName := range timestamps {
+			backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+				return nil, err
+			}
+			err = checkLocalBackupStatus(skipLocalBackup, backupData.IsLocal())
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableWorkBackup(backupName, err))
+				return nil, err
+			}
+			if !gpbckpconfig.IsBackupDeletionUnfinished(backupData.DateDeleted) {
+				gplog.Error("%s", textmsg.ErrorTextUnableRepairBackup(backupName, textmsg.ErrorBackupNotRepairableError()))
+				return nil, textmsg.ErrorBackupNotRepairableError()
+			}
+			backupList = append(backupList, backupName)
+		}
+		return backupList, nil
+	}
+	backupNames, err := gpbckpconfig.GetBackupNamesForRepair(dbFilter, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+		return nil, err
+	}
+	for _, backupName := range backupNames {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return nil, err
+		}
+		if backupData.IsLocal() != skipLocalBackup {
+			backupList = append(backupList, backupName)
+		}
+	}
+	return backupList, nil
+}
+
+// backupRepairDB checks the state of the backup files and performs the repair action.
+func backupRepairDB(backupName string, deleteBackup, dryRun, ignoreErrors bool, repairer backupRepairInterface, hDB *sql.DB) error {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return err
+	}
+	state, err := repairer.backupStorageState(backupData, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableRepairBackup(backupName, err))
+		return err
+	}
+	action := getBackupRepairAction(state, deleteBackup)
+	if dryRun {
+		gplog.Info("%s", textmsg.InfoTextDryRunBackupRepair(backupName, state, action))
+		return nil
+	}
+	gplog.Info("%s", textmsg.InfoTextBackupRepair(backupName, state, action))
+	switch action {
+	case repairActionRestore:
+		err = gpbckpconfig.UpdateDeleteStatus(backupName, "", hDB)
+	case repairActionMarkDeleted:
+		err = gpbckpconfig.UpdateDeleteStatus(backupName, getCurrentTimestamp(), hDB)
+	case repairActionDelete:
+		// Errors are logged and the deletion status is set inside the deletion function.
+		err = repairer.backupDeleteDB(backupName, hDB, false)
+		if err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableRepairBackup(backupName, err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextBackupRepairSuccess(backupName))
+	return nil
+}
+
+// getBackupRepairAction Returns the repair action for the state of the backup files.
+func getBackupRepairAction(state string, deleteBackup bool) string {
+	switch state {
+	case storageStatePresent:
+		if deleteBackup {
+			return repairActionDelete
+		}
+		return repairActionRestore
+	case storageStateMissing:
+		return repairActionMarkDeleted
+	default:
+		return repairActionDelete
+	}
+}
+
+// getStorageStateFromVerify Returns the state of the backup files from the results of the backup verification.
+func getStorageStateFromVerify(results []backupVerifyResult) (string, error) {
+	var found, missing int
+	for _, result := range results {
+		switch result.Status {
+		case verifyStatusOK:
+			found++
+		case verifyStatusMissing:
+			missing++
+		default:
+			return "", textmsg.ErrorBackupStorageStateUnknownError()
+		}
+	}
+	switch {
+	case missing == 0 && found > 0:
+		return storageStatePresent, nil
+	case found == 0:
+		return storageStateMissing, nil
+	default:
+		return storageStatePartial, nil
+	}
+}
+
+// backupStorageStateLocal Returns the state of the local backup files on master and segment hosts.
+func backupStorageStateLocal(backupName, backupDir string, maxParallelProcesses int, hDB *sql.DB) (string, error) {
+	results, err := backupVerifyDB(backupName, backupDir, maxParallelProcesses, hDB)
+	if err != nil {
+		return "", err
+	}
+	return getStorageStateFromVerify(results)
+}
+
+// backupStorageStatePlugin Returns the state of the backup files in the plugin storage.
+// The storage plugin API doesn't provide the way to list the backup files.
+// The report is small and could outlive the data files of the interrupted deletion,
+// so if the report can be read, the state of other files is unknown,
+// otherwise some of the files could still exist.
+// The backup with any of these states is never restored to active state.
+func backupStorageStatePlugin(backupData gpbckpconfig.BackupConfig, reportFilePath, pluginConfigPath string, pluginConfig *utils.PluginConfig) (string, error) {
+	reportFile, err := backupData.GetReportFilePathPlugin(reportFilePath, pluginConfig.Options)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("report", backupData.Timestamp, err))
+		return "", err
+	}
+	gplog.Debug("%s", textmsg.InfoTextCommandExecution(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, reportFile))
+	_, stderr, err := execReportInfo(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, reportFile)
+	if err != nil {
+		if stderr != "" {
+			gplog.Debug("%s", stderr)
+		}
+		return storageStatePartial, nil
+	}
+	return storageStateUnknown, nil
+}
+
+// backupRepairDeleteLocalFunc finishes the deletion of the local backup.
+// Unlike backupDeleteDBLocalFunc, the existence of the backup directories on segments is not checked,
+// because some of them could already be deleted.
+func backupRepairDeleteLocalFunc(backupName, backupDir string, maxParallelProcesses int, hDB *sql.DB) error {
+	dateDeleted := getCurrentTimestamp()
+	gplog.Info("%s", textmsg.InfoTextBackupDeleteStart(backupName))
+	err := gpbckpconfig.UpdateDeleteStatus(backupName, gpbckpconfig.DateDeletedInProgress, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableSetBackupStatus(gpbckpconfig.DateDeletedInProgress, backupName, err))
+		return err
+	}
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupInfo(backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+		return err
+	}
+	bckpDir, segPrefix, isSingleBackupDir, err := getBackupMasterDir(backupDir, backupData.BackupDir, backupData.DatabaseName)
+	if err != nil {
+		handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+		return err
+	}
+	backupType, err := backupData.GetBackupType()
+	if err != nil {
+		handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupValue("type", backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+		return err
+	}
+	// For "metadata-only" backups files exist only on master.
+	if backupType != gpbckpconfig.BackupTypeMetadataOnly {
+		segConfig, err := getSegmentConfigurationClusterInfo(backupData.DatabaseName)
+		if err != nil {
+			handleErrorDB(backupName, textmsg.ErrorTextUnableGetBackupPath("segment configuration", backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+			return err
+		}
+		sshClientDialer, err := getSSHDialer()
+		if err != nil {
+			handleErrorDB(backupName, textmsg.ErrorTextUnableDeleteBackup(backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+			return err
+		}
+		errList, err := executeOnSegments(backupDir, backupData.BackupDir, backupName, segPrefix, isSingleBackupDir, segConfig, maxParallelProcesses, sshClientDialer,
+			func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+				deleteBackupDirOnSegments(paths, host, sshDial, errCh)
+			})
+		if err == nil {
+			for _, errSeg := range errList {
+				if errSeg != nil {
+					err = errSeg
+				}
+			}
+		}
+		if err != nil {
+			handleErrorDB(backupName, textmsg.ErrorTextUnableDeleteBackup(backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+			return err
+		}
+	}
+	// Delete files on master.
+	gplog.Debug("%s", textmsg.InfoTextCommandExecution("delete directory", gpbckpconfig.BackupDirPath(bckpDir, backupName)))
+	err = os.RemoveAll(gpbckpconfig.BackupDirPath(bckpDir, backupName))
+	if err != nil {
+		handleErrorDB(backupName, textmsg.ErrorTextUnableDeleteBackup(backupName, err), gpbckpconfig.DateDeletedLocalFailed, hDB)
+		return err
+	}
+	err = gpbckpconfig.UpdateDeleteStatus(backupName, dateDeleted, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableSetBackupStatus(dateDeleted, backupName, err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextBackupDeleteSuccess(backupName))
+	return nil
+}
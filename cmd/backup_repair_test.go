@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetBackupRepairAction(t *testing.T) {
+	tests := []struct {
+		name         string
+		state        string
+		deleteBackup bool
+		want         string
+	}{
+		{
+			name:  "Test all files exist",
+			state: storageStatePresent,
+			want:  repairActionRestore,
+		},
+		{
+			name:         "Test all files exist with delete",
+			state:        storageStatePresent,
+			deleteBackup: true,
+			want:         repairActionDelete,
+		},
+		{
+			name:  "Test some files exist",
+			state: storageStatePartial,
+			want:  repairActionDelete,
+		},
+		{
+			name:  "Test unknown state of files",
+			state: storageStateUnknown,
+			want:  repairActionDelete,
+		},
+		{
+			name:  "Test no files exist",
+			state: storageStateMissing,
+			want:  repairActionMarkDeleted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBackupRepairAction(tt.state, tt.deleteBackup); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetStorageStateFromVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []backupVerifyResult
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Test all files exist",
+			results: []backupVerifyResult{{Status: verifyStatusOK}, {Status: verifyStatusOK}},
+			want:    storageStatePresent,
+		},
+		{
+			name:    "Test some files exist",
+			results: []backupVerifyResult{{Status: verifyStatusOK}, {Status: verifyStatusMissing}},
+			want:    storageStatePartial,
+		},
+		{
+			name:    "Test no files exist",
+			results: []backupVerifyResult{{Status: verifyStatusMissing}, {Status: verifyStatusMissing}},
+			want:    storageStateMissing,
+		},
+		{
+			name:    "Test check error",
+			results: []backupVerifyResult{{Status: verifyStatusOK}, {Status: verifyStatusError}},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getStorageStateFromVerify(tt.results)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("getStorageStateFromVerify() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupStorageStatePlugin(t *testing.T) {
+	testhelper.SetupTestLogger()
+	defer func() { execCommand = exec.Command }()
+	backupData := gpbckpconfig.BackupConfig{Timestamp: "20240101100000", Plugin: "some_plugin"}
+	pluginConfig := &utils.PluginConfig{ExecutablePath: "/path/to/plugin"}
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{
+			name:    "Test report is read",
+			command: "true",
+			want:    storageStateUnknown,
+		},
+		{
+			name:    "Test report is not read",
+			command: "false",
+			want:    storageStatePartial,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			execCommand = func(string, ...string) *exec.Cmd {
+				return exec.Command(tt.command)
+			}
+			got, err := backupStorageStatePlugin(backupData, "/path/to/report", "/path/to/config.yaml", pluginConfig)
+			if err != nil {
+				t.Fatalf("backupStorageStatePlugin() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			// The backup made with the storage plugin is never restored to active state.
+			if action := getBackupRepairAction(got, false); action != repairActionDelete {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", action, repairActionDelete)
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package cmd
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman backup-search command (backupSearchCmd)
+var (
+	backupSearchSchemaPattern        string
+	backupSearchTablePattern         string
+	backupSearchRegex                bool
+	backupSearchAsOf                 string
+	backupSearchToc                  bool
+	backupSearchBackupDir            string
+	backupSearchPluginConfigFile     string
+	backupSearchReportFilePluginPath string
+	backupSearchOutputFormat         string
+	backupSearchDatabase             []string
+	backupSearchExcludeDatabase      []string
+)
+
+// backupSearchRecord contains the table found in the backup.
+// The data timestamp is the timestamp of the backup, from which the table data is restored.
+// For incremental backups, it could be the timestamp of the previous backup from the chain.
+// The data timestamp is empty, if only the table metadata is in the backup.
+type backupSearchRecord struct {
+	Table         string `json:"table" yaml:"table"`
+	Timestamp     string `json:"timestamp" yaml:"timestamp"`
+	BackupDate    string `json:"backup_date" yaml:"backup_date"`
+	DatabaseName  string `json:"database_name" yaml:"database_name"`
+	BackupType    string `json:"backup_type" yaml:"backup_type"`
+	Plugin        string `json:"plugin" yaml:"plugin"`
+	DataTimestamp string `json:"data_timestamp" yaml:"data_timestamp"`
+}
+
+// backupSearchTocFunc Returns the content of the toc file of the backup.
+// If the toc file can't be fetched for the backup, the empty content and false are returned.
+type backupSearchTocFunc func(gpbckpconfig.BackupConfig) (string, bool, error)
+
+var backupSearchCmd = &cobra.Command{
+	Use:   "backup-search",
+	Short: "Search backups that contain the tables",
+	Long: `Search backups that contain the tables.
+
+Unlike the --table option of the backup-info command, which matches only the object filtering options of the backup,
+the search uses the tables from the restore plan of the backup in the history database.
+Only successful active backups are searched.
+
+The tables are set using the --schema and --table options. At least one of the options must be specified.
+By default, the values are glob patterns for the schema and table names:
+'*' matches any sequence of characters, '?' matches any single character, '[...]' matches the character class.
+To use regular expressions instead of glob patterns, use the --regex option.
+The pattern must match the whole name. The names are compared without quotes.
+If the option is not specified, any name matches.
+
+By default, all backups that contain the tables are displayed.
+To display the newest backup, from which the table could be restored as of the specific time, use the --as-of option.
+In this mode, for each table, the newest backup with the timestamp not later than the specified one is displayed.
+The backup could be used only if all backups from its restore plan are active.
+Only the tables with data in the backup are taken into account.
+
+The "data timestamp" column contains the timestamp of the backup, from which the table data is restored.
+For incremental backups, the data of unchanged tables is restored from the previous backups of the chain.
+
+To search the tables also in the toc file on master, use the --toc option.
+The tables, which metadata is in the backup, but which are not in the restore plan (for example, for metadata-only backups),
+are displayed with the empty data timestamp.
+The toc file is fetched in the same way as in the backup-fetch command,
+the --backup-dir, --plugin-config and --plugin-report-file-path options could be used with the --toc option.
+If the toc file can't be fetched, only the tables from the restore plan are used for the backup.
+
+To search only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together.
+
+To change the output format, use the --output option. The following formats are supported:
+  * table - human-readable table (default);
+  * json - JSON array, one object per table and backup;
+  * yaml - YAML list, one item per table and backup.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doBackupSearchFlagValidation(cmd.Flags())
+		doBackupSearch()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupSearchCmd)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchSchemaPattern,
+		schemaFlagName,
+		"",
+		"the pattern for the schema name",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchTablePattern,
+		tableFlagName,
+		"",
+		"the pattern for the table name",
+	)
+	backupSearchCmd.PersistentFlags().BoolVar(
+		&backupSearchRegex,
+		regexFlagName,
+		false,
+		"use regular expressions instead of glob patterns",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchAsOf,
+		asOfFlagName,
+		"",
+		"show the newest backup for restoring the table as of the specified timestamp",
+	)
+	backupSearchCmd.PersistentFlags().BoolVar(
+		&backupSearchToc,
+		tocFlagName,
+		false,
+		"search the tables also in the toc file on master",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchBackupDir,
+		backupDirFlagName,
+		"",
+		"the full path to backup directory",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchPluginConfigFile,
+		pluginConfigFileFlagName,
+		"",
+		"the full path to plugin config file",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchReportFilePluginPath,
+		reportFilePluginPathFlagName,
+		"",
+		"the full path to plugin report file",
+	)
+	backupSearchCmd.PersistentFlags().StringVar(
+		&backupSearchOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+	backupSearchCmd.PersistentFlags().StringArrayVar(
+		&backupSearchDatabase,
+		databaseFlagName,
+		[]string{},
+		"search backups only for the specified database, could be specified multiple times",
+	)
+	backupSearchCmd.PersistentFlags().StringArrayVar(
+		&backupSearchExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not search backups for the specified database, could be specified multiple times",
+	)
+}
+
+// These flag checks are applied only for backup-search command.
+func doBackupSearchFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// At least one of table and schema flags must be specified.
+	if !flags.Changed(tableFlagName) && !flags.Changed(schemaFlagName) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorValidationValue(), tableFlagName, schemaFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// The patterns must be valid glob patterns or regular expressions.
+	_, err = gpbckpconfig.NewTablePattern(backupSearchSchemaPattern, "", backupSearchRegex)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchSchemaPattern, schemaFlagName, err))
+		execOSExit(exitErrorCode)
+	}
+	_, err = gpbckpconfig.NewTablePattern("", backupSearchTablePattern, backupSearchRegex)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchTablePattern, tableFlagName, err))
+		execOSExit(exitErrorCode)
+	}
+	// If as-of flag is specified and have correct values.
+	if flags.Changed(asOfFlagName) {
+		err = gpbckpconfig.CheckTimestamp(backupSearchAsOf)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchAsOf, asOfFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// database and exclude-database flags cannot be used together.
+	err = checkCompatibleFlags(flags, databaseFlagName, excludeDatabaseFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, databaseFlagName, excludeDatabaseFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// backup-dir, plugin-config and plugin-report-file-path flags are used only with toc flag.
+	for _, flagName := range []string{backupDirFlagName, pluginConfigFileFlagName, reportFilePluginPathFlagName} {
+		if flags.Changed(flagName) && !flags.Changed(tocFlagName) {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), flagName, tocFlagName))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// backup-dir anf plugin-config flags cannot be used together.
+	err = checkCompatibleFlags(flags, backupDirFlagName, pluginConfigFileFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, backupDirFlagName, pluginConfigFileFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If backup-dir flag is specified and it exists and the full path is specified.
+	if flags.Changed(backupDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupSearchBackupDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchBackupDir, backupDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-config flag is specified and it exists and the full path is specified.
+	if flags.Changed(pluginConfigFileFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupSearchPluginConfigFile, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchPluginConfigFile, pluginConfigFileFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If plugin-report-file-path flag is specified.
+	if flags.Changed(reportFilePluginPathFlagName) {
+		// But plugin-config flag is not specified.
+		if !flags.Changed(pluginConfigFileFlagName) {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), reportFilePluginPathFlagName, pluginConfigFileFlagName))
+			execOSExit(exitErrorCode)
+		}
+		// Check full path.
+		err = gpbckpconfig.CheckFullPath(backupSearchReportFilePluginPath, false)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchReportFilePluginPath, reportFilePluginPathFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(backupSearchOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupSearchOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doBackupSearch() {
+	logHeadersDebug()
+	err := backupSearch()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func backupSearch() error {
+	pattern, err := gpbckpconfig.NewTablePattern(backupSearchSchemaPattern, backupSearchTablePattern, backupSearchRegex)
+	if err != nil {
+		return err
+	}
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: backupSearchDatabase,
+		Exclude: backupSearchExcludeDatabase,
+	}
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	var getToc backupSearchTocFunc
+	if backupSearchToc {
+		getToc, err = getBackupSearchTocFunc()
+		if err != nil {
+			return err
+		}
+	}
+	records, err := backupSearchDB(pattern, backupSearchAsOf, dbFilter, getToc, hDB)
+	if err != nil {
+		return err
+	}
+	err = printBackupSearch(backupSearchOutputFormat, records, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(backupSearchOutputFormat, err))
+		return err
+	}
+	return nil
+}
+
+// getBackupSearchTocFunc Returns the function for fetching the toc file from the local or plugin storage.
+func getBackupSearchTocFunc() (backupSearchTocFunc, error) {
+	if backupSearchPluginConfigFile == "" {
+		return func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+			return getBackupFileLocal(backupData, gpbckpconfig.TocFileName(backupData.Timestamp), backupSearchBackupDir)
+		}, nil
+	}
+	pluginConfig, err := utils.ReadPluginConfig(backupSearchPluginConfigFile)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadPluginConfigFile(err))
+		return nil, err
+	}
+	return func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+		return getBackupFilePlugin(backupData, gpbckpconfig.TocFileName(backupData.Timestamp), backupSearchReportFilePluginPath, backupSearchPluginConfigFile, pluginConfig)
+	}, nil
+}
+
+// backupSearchDB Returns the tables matching the pattern from the successful active backups.
+// If the as-of timestamp is set, only the newest backup not later than the timestamp is returned for each table,
+// the backups with inactive backups in the restore plan are skipped.
+// If the toc function is set, the tables from the toc file are also searched.
+func backupSearchDB(pattern gpbckpconfig.TablePattern, asOf string, dbFilter gpbckpconfig.DatabaseFilter, getToc backupSearchTocFunc, hDB *sql.DB) ([]backupSearchRecord, error) {
+	backupNames, err := gpbckpconfig.GetBackupNamesDB(false, false, dbFilter, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+		return nil, err
+	}
+	activeBackups := make(map[string]gpbckpconfig.BackupConfig)
+	for _, backupName := range backupNames {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return nil, err
+		}
+		if backupData.Status == gpbckpconfig.BackupStatusSuccess && gpbckpconfig.IsBackupActive(backupData.DateDeleted) {
+			activeBackups[backupName] = backupData
+		}
+	}
+	records := make([]backupSearchRecord, 0)
+	found := make(map[string]bool)
+	// The backup names are sorted by timestamp in descending order,
+	// so in as-of mode the first found backup is the newest one for the table.
+	for _, backupName := range backupNames {
+		backupData, exists := activeBackups[backupName]
+		if !exists || (asOf != "" && backupName > asOf) {
+			continue
+		}
+		backupRecords := getBackupSearchRecords(pattern, asOf != "", backupData, getToc)
+		if asOf == "" {
+			records = append(records, backupRecords...)
+			continue
+		}
+		var newRecords []backupSearchRecord
+		for _, record := range backupRecords {
+			if !found[record.DatabaseName+"\x00"+record.Table] {
+				newRecords = append(newRecords, record)
+			}
+		}
+		if len(newRecords) == 0 {
+			continue
+		}
+		inactiveBackups := getBackupSearchInactiveBackups(backupData, activeBackups)
+		if len(inactiveBackups) > 0 {
+			gplog.Warn("%s", textmsg.WarnTextBackupChainNotActive(backupName, inactiveBackups))
+			continue
+		}
+		for _, record := range newRecords {
+			found[record.DatabaseName+"\x00"+record.Table] = true
+		}
+		records = append(records, newRecords...)
+	}
+	return records, nil
+}
+
+// getBackupSearchRecords Returns the tables matching the pattern from the backup.
+// If the data only flag is set, the tables without data in the backup are skipped.
+//
+// If errors occur, they are logged, but they are not returned.
+func getBackupSearchRecords(pattern gpbckpconfig.TablePattern, dataOnly bool, backupData gpbckpconfig.BackupConfig, getToc backupSearchTocFunc) []backupSearchRecord {
+	tables := backupData.GetRestorePlanTables()
+	if getToc != nil && !dataOnly {
+		for _, table := range getBackupSearchTocTables(backupData, getToc) {
+			if _, exists := tables[table]; !exists {
+				tables[table] = ""
+			}
+		}
+	}
+	backupDate, err := backupData.GetBackupDate()
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("date", backupData.Timestamp, err))
+	}
+	backupType, err := backupData.GetBackupType()
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("type", backupData.Timestamp, err))
+	}
+	records := make([]backupSearchRecord, 0)
+	for table, dataTimestamp := range tables {
+		if !pattern.Match(table) || (dataOnly && dataTimestamp == "") {
+			continue
+		}
+		records = append(records, backupSearchRecord{
+			Table:         table,
+			Timestamp:     backupData.Timestamp,
+			BackupDate:    backupDate,
+			DatabaseName:  backupData.DatabaseName,
+			BackupType:    backupType,
+			Plugin:        backupData.Plugin,
+			DataTimestamp: dataTimestamp,
+		})
+	}
+	return records
+}
+
+// getBackupSearchTocTables Returns the tables from the toc file of the backup.
+//
+// If errors occur, they are logged, but they are not returned.
+func getBackupSearchTocTables(backupData gpbckpconfig.BackupConfig, getToc backupSearchTocFunc) []string {
+	content, canFetch, err := getToc(backupData)
+	if err != nil || !canFetch {
+		gplog.Warn("%s", textmsg.WarnTextBackupUnableGetToc(backupData.Timestamp))
+		return nil
+	}
+	backupTOC, err := gpbckpconfig.ParseBackupTOC([]byte(content))
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("toc", backupData.Timestamp, err))
+		gplog.Warn("%s", textmsg.WarnTextBackupUnableGetToc(backupData.Timestamp))
+		return nil
+	}
+	return backupTOC.GetTables()
+}
+
+// getBackupSearchInactiveBackups Returns the backups from the restore plan of the backup,
+// which are not in the list of the active backups.
+func getBackupSearchInactiveBackups(backupData gpbckpconfig.BackupConfig, activeBackups map[string]gpbckpconfig.BackupConfig) []string {
+	var result []string
+	for _, entry := range backupData.RestorePlan {
+		if _, exists := activeBackups[entry.Timestamp]; !exists {
+			result = append(result, entry.Timestamp)
+		}
+	}
+	return result
+}
+
+// printBackupSearch writes the found tables to w in the specified format.
+// The tables are sorted by database and table name, the backups of the table are sorted by timestamp in descending order.
+func printBackupSearch(outputFormat string, records []backupSearchRecord, w io.Writer) error {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].DatabaseName != records[j].DatabaseName {
+			return records[i].DatabaseName < records[j].DatabaseName
+		}
+		if records[i].Table != records[j].Table {
+			return records[i].Table < records[j].Table
+		}
+		return records[i].Timestamp > records[j].Timestamp
+	})
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, records)
+	default:
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{
+			"database",
+			"table",
+			"timestamp",
+			"date",
+			"type",
+			"plugin",
+			"data timestamp",
+		})
+		for _, record := range records {
+			t.AppendRow(table.Row{
+				record.DatabaseName,
+				record.Table,
+				record.Timestamp,
+				record.BackupDate,
+				record.BackupType,
+				record.Plugin,
+				record.DataTimestamp,
+			})
+		}
+		t.Render()
+		return nil
+	}
+}
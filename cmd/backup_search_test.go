@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestBackupSearchDB(t *testing.T) {
+	testhelper.SetupTestLogger()
+	hDB := createTestHistoryDB(t, filepath.Join(t.TempDir(), "gpbackup_history.db"), []gpbckpconfig.BackupConfig{
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240101100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240101100000", TableFQNs: []string{"public.orders", "public.items"}},
+			},
+		},
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240102100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			Incremental:  true,
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240101100000", TableFQNs: []string{"public.items"}},
+				{Timestamp: "20240102100000", TableFQNs: []string{"public.orders"}},
+			},
+		},
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240103100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			DateDeleted:  "20240105100000",
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240103100000", TableFQNs: []string{"public.orders", "public.items"}},
+			},
+		},
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240104100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			Incremental:  true,
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240103100000", TableFQNs: []string{"public.items"}},
+				{Timestamp: "20240104100000", TableFQNs: []string{"public.orders"}},
+			},
+		},
+		{
+			DatabaseName: "other",
+			Timestamp:    "20240105100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240105100000", TableFQNs: []string{"public.orders"}},
+			},
+		},
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240106100000",
+			Status:       gpbckpconfig.BackupStatusFailure,
+			RestorePlan: []gpbckpconfig.RestorePlanEntry{
+				{Timestamp: "20240106100000", TableFQNs: []string{"public.orders"}},
+			},
+		},
+		{
+			DatabaseName: "demo",
+			Timestamp:    "20240107100000",
+			Status:       gpbckpconfig.BackupStatusSuccess,
+			MetadataOnly: true,
+		},
+	})
+	getToc := func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+		if backupData.Timestamp != "20240107100000" {
+			return "", false, nil
+		}
+		return "predataentries:\n- schema: public\n  name: orders\n  objecttype: TABLE\n", true, nil
+	}
+	tests := []struct {
+		name          string
+		schemaPattern string
+		tablePattern  string
+		isRegex       bool
+		asOf          string
+		dbFilter      gpbckpconfig.DatabaseFilter
+		getToc        backupSearchTocFunc
+		want          [][4]string
+	}{
+		{
+			name:         "Test active backups with table",
+			tablePattern: "orders",
+			want: [][4]string{
+				{"demo", "public.orders", "20240104100000", "20240104100000"},
+				{"demo", "public.orders", "20240102100000", "20240102100000"},
+				{"demo", "public.orders", "20240101100000", "20240101100000"},
+				{"other", "public.orders", "20240105100000", "20240105100000"},
+			},
+		},
+		{
+			name:          "Test regex with database filter",
+			schemaPattern: "pub.*",
+			tablePattern:  "ord.+",
+			isRegex:       true,
+			dbFilter:      gpbckpconfig.DatabaseFilter{Include: []string{"other"}},
+			want: [][4]string{
+				{"other", "public.orders", "20240105100000", "20240105100000"},
+			},
+		},
+		{
+			name:         "Test as of time with inactive restore plan",
+			tablePattern: "*",
+			asOf:         "20240104100000",
+			want: [][4]string{
+				{"demo", "public.items", "20240102100000", "20240101100000"},
+				{"demo", "public.orders", "20240102100000", "20240102100000"},
+			},
+		},
+		{
+			name:          "Test as of time skips tables without data",
+			schemaPattern: "public",
+			asOf:          "20240108100000",
+			getToc:        getToc,
+			want: [][4]string{
+				{"demo", "public.items", "20240102100000", "20240101100000"},
+				{"demo", "public.orders", "20240102100000", "20240102100000"},
+				{"other", "public.orders", "20240105100000", "20240105100000"},
+			},
+		},
+		{
+			name:         "Test tables from toc",
+			tablePattern: "orders",
+			dbFilter:     gpbckpconfig.DatabaseFilter{Exclude: []string{"other"}},
+			getToc:       getToc,
+			want: [][4]string{
+				{"demo", "public.orders", "20240107100000", ""},
+				{"demo", "public.orders", "20240104100000", "20240104100000"},
+				{"demo", "public.orders", "20240102100000", "20240102100000"},
+				{"demo", "public.orders", "20240101100000", "20240101100000"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := gpbckpconfig.NewTablePattern(tt.schemaPattern, tt.tablePattern, tt.isRegex)
+			if err != nil {
+				t.Fatalf("NewTablePattern() error = %v", err)
+			}
+			records, err := backupSearchDB(pattern, tt.asOf, tt.dbFilter, tt.getToc, hDB)
+			if err != nil {
+				t.Fatalf("backupSearchDB() error = %v", err)
+			}
+			// Sort the records in the same way as for displaying.
+			if err := printBackupSearch(outputFormatJSON, records, &bytes.Buffer{}); err != nil {
+				t.Fatalf("printBackupSearch() error = %v", err)
+			}
+			got := make([][4]string, 0, len(records))
+			for _, record := range records {
+				got = append(got, [4]string{record.DatabaseName, record.Table, record.Timestamp, record.DataTimestamp})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintBackupSearch(t *testing.T) {
+	records := []backupSearchRecord{
+		{Table: "public.orders", Timestamp: "20240101100000", DatabaseName: "demo", BackupType: gpbckpconfig.BackupTypeFull, DataTimestamp: "20240101100000"},
+		{Table: "public.orders", Timestamp: "20240102100000", DatabaseName: "demo", BackupType: gpbckpconfig.BackupTypeIncremental, DataTimestamp: "20240102100000"},
+	}
+	var buf bytes.Buffer
+	if err := printBackupSearch(outputFormatTable, records, &buf); err != nil {
+		t.Fatalf("printBackupSearch() error: %v", err)
+	}
+	for _, want := range []string{"DATA TIMESTAMP", "public.orders", gpbckpconfig.BackupTypeIncremental} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), want)
+		}
+	}
+	buf.Reset()
+	if err := printBackupSearch(outputFormatJSON, records, &buf); err != nil {
+		t.Fatalf("printBackupSearch() error: %v", err)
+	}
+	var got []backupSearchRecord
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printBackupSearch() invalid json: %v", err)
+	}
+	if len(got) != 2 || got[0].Timestamp != "20240102100000" {
+		t.Errorf("\nVariables do not match:\n%v\nwant the newest backup first", got)
+	}
+}
@@ -0,0 +1,495 @@
+package cmd
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman backup-verify command (backupVerifyCmd)
+var (
+	backupVerifyTimestamp         []string
+	backupVerifyBackupDir         string
+	backupVerifyParallelProcesses int
+	backupVerifyOutputFormat      string
+	backupVerifyDatabase          []string
+	backupVerifyExcludeDatabase   []string
+)
+
+// backupVerifyResult is the result of the existence check for one backup directory or file.
+type backupVerifyResult struct {
+	Timestamp string `json:"timestamp" yaml:"timestamp"`
+	Host      string `json:"host" yaml:"host"`
+	ContentID string `json:"content_id" yaml:"content_id"`
+	Path      string `json:"path" yaml:"path"`
+	Status    string `json:"status" yaml:"status"`
+}
+
+// backupVerifyPath is a directory or file that is expected to exist.
+type backupVerifyPath struct {
+	path  string
+	isDir bool
+}
+
+// backupVerifyResults collects the results from parallel checks on segment hosts.
+type backupVerifyResults struct {
+	mu   sync.Mutex
+	list []backupVerifyResult
+}
+
+func (bvr *backupVerifyResults) add(results ...backupVerifyResult) {
+	bvr.mu.Lock()
+	defer bvr.mu.Unlock()
+	bvr.list = append(bvr.list, results...)
+}
+
+var backupVerifyCmd = &cobra.Command{
+	Use:   "backup-verify",
+	Short: "Verify that backup files exist",
+	Long: `Verify that backup files exist.
+
+The existence of the following directories and files is checked:
+  * the backup directory on master;
+  * the config.yaml, report, toc and metadata.sql files on master; the metadata.sql file is not checked for data-only backups;
+  * the backup directory on every segment host; segments are not checked for metadata-only backups;
+  * the segment toc files for backups with single data file.
+The backup data files are not checked.
+
+The segment hosts are checked using ssh.
+For control over the number of parallel processes and ssh connections, the --parallel-processes option can be used.
+
+To verify specific backups, use the --timestamp option. It could be specified multiple times.
+If the --timestamp option is not specified, all active local backups are verified.
+
+To verify only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together and cannot be used with --timestamp.
+
+Only local backups can be verified.
+
+The full path to the backup directory can be set using the --backup-dir option.
+
+For local backups the following logic are applied:
+  * If the --backup-dir option is specified, the files will be searched in provided path.
+  * If the --backup-dir option is not specified, but the backup was made with --backup-dir flag for gpbackup, the files will be searched in the backup manifest path.
+  * If the --backup-dir option is not specified and backup directory is not specified in backup manifest, the files will be searched in backup folder in the master and segments data directories.
+
+The result is displayed for each host and each directory or file with one of the statuses:
+  * ok - the directory or file exists;
+  * missing - the directory or file does not exist;
+  * error - unable to check the directory or file.
+If at least one directory or file is missing or cannot be checked, the command exits with a non-zero exit code.
+
+To change the output format, use the --output option. The following formats are supported: table (default), json, yaml.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doBackupVerifyFlagValidation(cmd.Flags())
+		doBackupVerify()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupVerifyCmd)
+	backupVerifyCmd.PersistentFlags().StringArrayVar(
+		&backupVerifyTimestamp,
+		timestampFlagName,
+		[]string{},
+		"the backup timestamp for verifying, could be specified multiple times",
+	)
+	backupVerifyCmd.PersistentFlags().StringVar(
+		&backupVerifyBackupDir,
+		backupDirFlagName,
+		"",
+		"the full path to backup directory for local backups",
+	)
+	backupVerifyCmd.PersistentFlags().IntVar(
+		&backupVerifyParallelProcesses,
+		parallelProcessesFlagName,
+		1,
+		"the number of parallel processes to verify backups on segments",
+	)
+	backupVerifyCmd.PersistentFlags().StringVar(
+		&backupVerifyOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+	backupVerifyCmd.PersistentFlags().StringArrayVar(
+		&backupVerifyDatabase,
+		databaseFlagName,
+		[]string{},
+		"verify only backups of the specified database, could be specified multiple times",
+	)
+	backupVerifyCmd.PersistentFlags().StringArrayVar(
+		&backupVerifyExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not verify backups of the specified database, could be specified multiple times",
+	)
+	backupVerifyCmd.MarkFlagsMutuallyExclusive(databaseFlagName, excludeDatabaseFlagName)
+}
+
+// These flag checks are applied only for backup-verify command.
+func doBackupVerifyFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If timestamps are specified and have correct values.
+	if flags.Changed(timestampFlagName) {
+		for _, timestamp := range backupVerifyTimestamp {
+			err = gpbckpconfig.CheckTimestamp(timestamp)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(timestamp, timestampFlagName, err))
+				execOSExit(exitErrorCode)
+			}
+		}
+		// --timestamp is not compatible with --database, --exclude-database
+		err = checkCompatibleFlags(flags, timestampFlagName, databaseFlagName, excludeDatabaseFlagName)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, timestampFlagName, databaseFlagName, excludeDatabaseFlagName))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If parallel-processes flag is specified and have correct values.
+	if flags.Changed(parallelProcessesFlagName) && !gpbckpconfig.IsPositiveValue(backupVerifyParallelProcesses) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(backupVerifyParallelProcesses), parallelProcessesFlagName, err))
+		execOSExit(exitErrorCode)
+	}
+	// If backup-dir flag is specified and it exists and the full path is specified.
+	if flags.Changed(backupDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(backupVerifyBackupDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupVerifyBackupDir, backupDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(backupVerifyOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(backupVerifyOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doBackupVerify() {
+	logHeadersDebug()
+	err := backupVerify()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func backupVerify() error {
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	defer closeSSHConnections()
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: backupVerifyDatabase,
+		Exclude: backupVerifyExcludeDatabase,
+	}
+	backupList, err := fetchBackupNamesForVerify(backupVerifyTimestamp, dbFilter, hDB)
+	if err != nil {
+		return err
+	}
+	if len(backupList) == 0 {
+		gplog.Info("%s", textmsg.InfoTextNothingToDo())
+		return nil
+	}
+	results := make([]backupVerifyResult, 0)
+	var verifyErr error
+	for _, backupName := range backupList {
+		backupResults, err := backupVerifyDB(backupName, backupVerifyBackupDir, backupVerifyParallelProcesses, hDB)
+		if err != nil {
+			return err
+		}
+		results = append(results, backupResults...)
+		if !isBackupVerified(backupResults) {
+			verifyErr = textmsg.ErrorBackupFilesMissingError()
+			gplog.Error("%s", textmsg.ErrorTextUnableVerifyBackup(backupName, verifyErr))
+		} else {
+			gplog.Info("%s", textmsg.InfoTextBackupVerifySuccess(backupName))
+		}
+	}
+	err = printBackupVerify(backupVerifyOutputFormat, results, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(backupVerifyOutputFormat, err))
+		return err
+	}
+	return verifyErr
+}
+
+// Get the list of backup names for verifying.
+// If timestamps are not specified, all active local backups are returned.
+func fetchBackupNamesForVerify(timestamps []string, dbFilter gpbckpconfig.DatabaseFilter, hDB *sql.DB) ([]string, error) {
+	// Include local backups.
+	skipLocalBackup := false
+	var backupList []string
+	if len(timestamps) > 0 {
+		for _, backupName := range timestamps {
+			backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+				return nil, err
+			}
+			canBeUsed, err := checkBackupCanBeUsed(false, skipLocalBackup, backupData)
+			if err != nil {
+				return nil, err
+			}
+			if canBeUsed {
+				backupList = append(backupList, backupName)
+			}
+		}
+		return backupList, nil
+	}
+	backupNames, err := gpbckpconfig.GetBackupNamesDB(false, false, dbFilter, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+		return nil, err
+	}
+	for _, backupName := range backupNames {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return nil, err
+		}
+		if !backupData.IsLocal() || backupData.IsInProgress() || !gpbckpconfig.IsBackupActive(backupData.DateDeleted) {
+			continue
+		}
+		backupList = append(backupList, backupName)
+	}
+	return backupList, nil
+}
+
+// backupVerifyDB checks that the backup directories and files exist on master and segment hosts.
+func backupVerifyDB(backupName, backupDir string, maxParallelProcesses int, hDB *sql.DB) ([]backupVerifyResult, error) {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return nil, err
+	}
+	bckpDir, segPrefix, isSingleBackupDir, err := getBackupMasterDir(backupDir, backupData.BackupDir, backupData.DatabaseName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("backup directory", backupName, err))
+		return nil, err
+	}
+	gplog.Debug("%s", textmsg.InfoTextBackupDirPath(bckpDir))
+	gplog.Debug("%s", textmsg.InfoTextSegmentPrefix(segPrefix))
+	backupType, err := backupData.GetBackupType()
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("type", backupName, err))
+		return nil, err
+	}
+	masterHost, err := os.Hostname()
+	if err != nil {
+		masterHost = "localhost"
+	}
+	results := &backupVerifyResults{}
+	masterPaths := getMasterVerifyPaths(gpbckpconfig.BackupDirPath(bckpDir, backupName), backupName, backupType)
+	for _, verifyPath := range masterPaths {
+		results.add(backupVerifyResult{
+			Timestamp: backupName,
+			Host:      masterHost,
+			ContentID: "-1",
+			Path:      verifyPath.path,
+			Status:    checkPathLocal(verifyPath),
+		})
+	}
+	// For "metadata-only" backups files exist only on master.
+	if backupType != gpbckpconfig.BackupTypeMetadataOnly {
+		segConfig, err := getSegmentConfigurationClusterInfo(backupData.DatabaseName)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("segment configuration", backupName, err))
+			return nil, err
+		}
+		sshClientDialer, err := getSSHDialer()
+		if err != nil {
+			return nil, err
+		}
+		errList, err := executeOnSegments(backupDir, backupData.BackupDir, backupName, segPrefix, isSingleBackupDir, segConfig, maxParallelProcesses, sshClientDialer,
+			func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+				var hostPaths []backupVerifyPath
+				var contentIDs []string
+				for i, config := range configs {
+					segmentPaths := getSegmentVerifyPaths(paths[i], backupName, config.ContentID, backupData.SingleDataFile)
+					hostPaths = append(hostPaths, segmentPaths...)
+					for range segmentPaths {
+						contentIDs = append(contentIDs, config.ContentID)
+					}
+				}
+				statuses, err := checkPathsOnSegment(hostPaths, host, sshDial)
+				for i, verifyPath := range hostPaths {
+					results.add(backupVerifyResult{
+						Timestamp: backupName,
+						Host:      host,
+						ContentID: contentIDs[i],
+						Path:      verifyPath.path,
+						Status:    statuses[i],
+					})
+				}
+				if err != nil {
+					errCh <- err
+				}
+			})
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("segment backup directory", backupName, err))
+			return nil, err
+		}
+		for _, err := range errList {
+			gplog.Error("%s", textmsg.ErrorTextUnableVerifyBackup(backupName, err))
+		}
+	}
+	return results.list, nil
+}
+
+// Get the list of directories and files, which are expected on master.
+// The metadata file is not created for data-only backups.
+func getMasterVerifyPaths(backupPath, backupName, backupType string) []backupVerifyPath {
+	paths := []backupVerifyPath{
+		{path: backupPath, isDir: true},
+		{path: filepath.Join(backupPath, gpbckpconfig.ConfigFileName(backupName))},
+		{path: filepath.Join(backupPath, gpbckpconfig.ReportFileName(backupName))},
+		{path: filepath.Join(backupPath, gpbckpconfig.TocFileName(backupName))},
+	}
+	if backupType != gpbckpconfig.BackupTypeDataOnly {
+		paths = append(paths, backupVerifyPath{path: filepath.Join(backupPath, gpbckpconfig.MetadataFileName(backupName))})
+	}
+	return paths
+}
+
+// Get the list of directories and files, which are expected on segment.
+// The segment toc file is created only for backups with single data file.
+func getSegmentVerifyPaths(backupPath, backupName, contentID string, singleDataFile bool) []backupVerifyPath {
+	paths := []backupVerifyPath{
+		{path: backupPath, isDir: true},
+	}
+	if singleDataFile {
+		paths = append(paths, backupVerifyPath{path: filepath.Join(backupPath, gpbckpconfig.SegmentTocFileName(contentID, backupName))})
+	}
+	return paths
+}
+
+// checkPathLocal Returns the status of the directory or file on the local host.
+func checkPathLocal(verifyPath backupVerifyPath) string {
+	info, err := os.Stat(verifyPath.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return verifyStatusMissing
+	case err != nil:
+		gplog.Error("%s", textmsg.ErrorTextUnableCheckPath(verifyPath.path, err))
+		return verifyStatusError
+	case info.IsDir() != verifyPath.isDir:
+		return verifyStatusMissing
+	default:
+		return verifyStatusOK
+	}
+}
+
+// checkPathsOnSegment Returns the statuses of the directories and files on the segment host.
+// All paths are checked by one command, that prints the status of each path on a separate line.
+// If the command fails, all paths get the error status.
+func checkPathsOnSegment(verifyPaths []backupVerifyPath, host string, sshDial sshDialer) ([]string, error) {
+	statuses := make([]string, len(verifyPaths))
+	for i := range statuses {
+		statuses[i] = verifyStatusError
+	}
+	command := getCheckPathsCommand(verifyPaths)
+	output, err := runCommandOnHost(command, host, sshDial)
+	if err != nil {
+		return statuses, err
+	}
+	lines := strings.Fields(output)
+	if len(lines) != len(verifyPaths) {
+		err = textmsg.ErrorUnexpectedCommandOutputError()
+		gplog.Error("%s", textmsg.ErrorTextCommandExecutionFailed(err, command, "on host", host))
+		return statuses, err
+	}
+	for i, line := range lines {
+		if line == verifyStatusOK || line == verifyStatusMissing {
+			statuses[i] = line
+		}
+	}
+	return statuses, nil
+}
+
+// getCheckPathsCommand Returns the command, that prints the status of each path on a separate line.
+// Paths are quoted, so they are not interpreted by the remote shell.
+func getCheckPathsCommand(verifyPaths []backupVerifyPath) string {
+	checks := make([]string, 0, len(verifyPaths))
+	for _, verifyPath := range verifyPaths {
+		testFlag := "-f"
+		if verifyPath.isDir {
+			testFlag = "-d"
+		}
+		checks = append(checks, fmt.Sprintf("if test %s %s; then echo %s; else echo %s; fi", testFlag, shellQuote(verifyPath.path), verifyStatusOK, verifyStatusMissing))
+	}
+	return strings.Join(checks, "; ")
+}
+
+// isBackupVerified Returns true, if all directories and files exist.
+func isBackupVerified(results []backupVerifyResult) bool {
+	for _, result := range results {
+		if result.Status != verifyStatusOK {
+			return false
+		}
+	}
+	return true
+}
+
+// printBackupVerify writes the verification results to w in the specified format.
+// Results are sorted by timestamp in descending order, then by content id and path.
+func printBackupVerify(outputFormat string, results []backupVerifyResult, w io.Writer) error {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Timestamp != results[j].Timestamp {
+			return results[i].Timestamp > results[j].Timestamp
+		}
+		contentI, _ := strconv.Atoi(results[i].ContentID)
+		contentJ, _ := strconv.Atoi(results[j].ContentID)
+		if contentI != contentJ {
+			return contentI < contentJ
+		}
+		return results[i].Path < results[j].Path
+	})
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, results)
+	default:
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"timestamp", "host", "content", "path", "status"})
+		for _, result := range results {
+			t.AppendRow(table.Row{result.Timestamp, result.Host, result.ContentID, result.Path, result.Status})
+		}
+		t.Render()
+		return nil
+	}
+}
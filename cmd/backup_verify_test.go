@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestGetMasterVerifyPaths(t *testing.T) {
+	backupPath := "/data/backups/20240101/20240101120000"
+	tests := []struct {
+		name       string
+		backupType string
+		want       []backupVerifyPath
+	}{
+		{
+			name:       "Test full backup",
+			backupType: gpbckpconfig.BackupTypeFull,
+			want: []backupVerifyPath{
+				{path: backupPath, isDir: true},
+				{path: backupPath + "/gpbackup_20240101120000_config.yaml"},
+				{path: backupPath + "/gpbackup_20240101120000_report"},
+				{path: backupPath + "/gpbackup_20240101120000_toc.yaml"},
+				{path: backupPath + "/gpbackup_20240101120000_metadata.sql"},
+			},
+		},
+		{
+			name:       "Test data-only backup",
+			backupType: gpbckpconfig.BackupTypeDataOnly,
+			want: []backupVerifyPath{
+				{path: backupPath, isDir: true},
+				{path: backupPath + "/gpbackup_20240101120000_config.yaml"},
+				{path: backupPath + "/gpbackup_20240101120000_report"},
+				{path: backupPath + "/gpbackup_20240101120000_toc.yaml"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMasterVerifyPaths(backupPath, "20240101120000", tt.backupType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSegmentVerifyPaths(t *testing.T) {
+	backupPath := "/data/backups/20240101/20240101120000"
+	tests := []struct {
+		name           string
+		singleDataFile bool
+		want           []backupVerifyPath
+	}{
+		{
+			name:           "Test multiple data files",
+			singleDataFile: false,
+			want: []backupVerifyPath{
+				{path: backupPath, isDir: true},
+			},
+		},
+		{
+			name:           "Test single data file",
+			singleDataFile: true,
+			want: []backupVerifyPath{
+				{path: backupPath, isDir: true},
+				{path: backupPath + "/gpbackup_0_20240101120000_toc.yaml"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getSegmentVerifyPaths(backupPath, "20240101120000", "0", tt.singleDataFile); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPathLocal(t *testing.T) {
+	testhelper.SetupTestLogger()
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "gpbackup_20240101120000_report")
+	if err := os.WriteFile(filePath, []byte("report"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		verifyPath backupVerifyPath
+		want       string
+	}{
+		{
+			name:       "Test existing directory",
+			verifyPath: backupVerifyPath{path: tempDir, isDir: true},
+			want:       verifyStatusOK,
+		},
+		{
+			name:       "Test existing file",
+			verifyPath: backupVerifyPath{path: filePath},
+			want:       verifyStatusOK,
+		},
+		{
+			name:       "Test missing file",
+			verifyPath: backupVerifyPath{path: filepath.Join(tempDir, "missing")},
+			want:       verifyStatusMissing,
+		},
+		{
+			name:       "Test file instead of directory",
+			verifyPath: backupVerifyPath{path: filePath, isDir: true},
+			want:       verifyStatusMissing,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkPathLocal(tt.verifyPath); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBackupVerified(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []backupVerifyResult
+		want    bool
+	}{
+		{
+			name:    "Test all ok",
+			results: []backupVerifyResult{{Status: verifyStatusOK}, {Status: verifyStatusOK}},
+			want:    true,
+		},
+		{
+			name:    "Test missing file",
+			results: []backupVerifyResult{{Status: verifyStatusOK}, {Status: verifyStatusMissing}},
+			want:    false,
+		},
+		{
+			name:    "Test check error",
+			results: []backupVerifyResult{{Status: verifyStatusError}},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isBackupVerified(tt.results); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintBackupVerify(t *testing.T) {
+	results := []backupVerifyResult{
+		{Timestamp: "20240101120000", Host: "sdw1", ContentID: "1", Path: "/data/seg1", Status: verifyStatusOK},
+		{Timestamp: "20240101120000", Host: "mdw", ContentID: "-1", Path: "/data/master", Status: verifyStatusOK},
+		{Timestamp: "20240102120000", Host: "sdw1", ContentID: "0", Path: "/data/seg0", Status: verifyStatusMissing},
+	}
+	var buf bytes.Buffer
+	if err := printBackupVerify(outputFormatJSON, results, &buf); err != nil {
+		t.Fatalf("printBackupVerify() error: %v", err)
+	}
+	var got []backupVerifyResult
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printBackupVerify() invalid json: %v", err)
+	}
+	wantOrder := []string{"/data/seg0", "/data/master", "/data/seg1"}
+	for i, path := range wantOrder {
+		if got[i].Path != path {
+			t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got[i].Path, path)
+		}
+	}
+	buf.Reset()
+	if err := printBackupVerify(outputFormatTable, results, &buf); err != nil {
+		t.Fatalf("printBackupVerify() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "STATUS") || !strings.Contains(buf.String(), verifyStatusMissing) {
+		t.Errorf("printBackupVerify() table output:\n%v", buf.String())
+	}
+}
+
+func TestCheckPathsOnSegment(t *testing.T) {
+	testhelper.SetupTestLogger()
+	server := newTestSSHServer(t)
+	host, port := server.hostPort(t)
+	transport, err := newSSHTransport(sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("newSSHTransport() error: %v", err)
+	}
+	tempDir := filepath.Join(t.TempDir(), "backup dir")
+	if err := os.Mkdir(tempDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(tempDir, "gpbackup_0_20240101120000_toc.yaml")
+	if err := os.WriteFile(filePath, []byte("toc"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	verifyPaths := []backupVerifyPath{
+		{path: tempDir, isDir: true},
+		{path: filePath},
+		{path: filepath.Join(tempDir, "missing"), isDir: true},
+		{path: filePath, isDir: true},
+	}
+	want := []string{verifyStatusOK, verifyStatusOK, verifyStatusMissing, verifyStatusMissing}
+	got, err := checkPathsOnSegment(verifyPaths, host, transport)
+	if err != nil {
+		t.Fatalf("checkPathsOnSegment() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	if got := server.commands.Load(); got != 1 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, 1)
+	}
+}
@@ -1,5 +1,7 @@
 package cmd
 
+import "time"
+
 const (
 	commandName = "gpbackman"
 
@@ -19,6 +21,10 @@ const (
 
 	// Flags.
 	historyDBFlagName            = "history-db"
+	historyDBBusyTimeoutFlagName = "history-db-busy-timeout"
+	historyDBRetriesFlagName     = "history-db-retries"
+	historyDBSnapshotsFlagName   = "history-db-snapshots"
+	historyDBSnapshotDirFlagName = "history-db-snapshot-dir"
 	historyFilesFlagName         = "history-file"
 	logFileFlagName              = "log-file"
 	logLevelConsoleFlagName      = "log-level-console"
@@ -41,15 +47,99 @@ const (
 	parallelProcessesFlagName    = "parallel-processes"
 	ignoreErrorsFlagName         = "ignore-errors"
 	detailFlagName               = "detail"
+	outputFlagName               = "output"
+	dryRunFlagName               = "dry-run"
+	keepFullFlagName             = "keep-full"
+	keepDailyFlagName            = "keep-daily"
+	keepWeeklyFlagName           = "keep-weekly"
+	keepMonthlyFlagName          = "keep-monthly"
+	databaseFlagName             = "database"
+	excludeDatabaseFlagName      = "exclude-database"
+	deleteFlagName               = "delete"
+	yesFlagName                  = "yes"
+	breakFlagName                = "break"
+	fixFlagName                  = "fix"
+	snapshotFlagName             = "snapshot"
+	conflictFlagName             = "conflict"
+	chainFlagName                = "chain"
+	summaryFlagName              = "summary"
+	artifactFlagName             = "artifact"
+	outputFileFlagName           = "output-file"
+	regexFlagName                = "regex"
+	asOfFlagName                 = "as-of"
+	tocFlagName                  = "toc"
+	minAgeHoursFlagName          = "min-age-hours"
+	sshUserFlagName              = "ssh-user"
+	sshKeyFlagName               = "ssh-key"
+	sshAgentFlagName             = "ssh-agent"
+	sshPortFlagName              = "ssh-port"
+	sshTimeoutFlagName           = "ssh-timeout"
+	sshKnownHostsFlagName        = "ssh-known-hosts"
+	sshJumpHostFlagName          = "ssh-jump-host"
+	sshForwardAgentFlagName      = "ssh-forward-agent"
+
+	maxPortValue = 65535
 
 	exitErrorCode = 1
 
+	// Output formats.
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+	outputFormatYAML  = "yaml"
+	outputFormatCSV   = "csv"
+	outputFormatText  = "text"
+
+	// Statuses of the backup files verification.
+	verifyStatusOK      = "ok"
+	verifyStatusMissing = "missing"
+	verifyStatusError   = "error"
+
+	// Statuses of the backup reports from the incremental chain.
+	reportStatusOK          = "ok"
+	reportStatusUnavailable = "unavailable"
+	reportStatusError       = "error"
+
+	// Backup files on master, that could be fetched.
+	fetchArtifactReport   = "report"
+	fetchArtifactConfig   = "config"
+	fetchArtifactToc      = "toc"
+	fetchArtifactMetadata = "metadata"
+
+	// States of the backup files in the storage.
+	storageStatePresent = "present"
+	storageStatePartial = "partially present"
+	storageStateMissing = "missing"
+	storageStateUnknown = "unknown"
+
+	// Actions for repairing backups with unfinished deletion.
+	repairActionRestore     = "restore"
+	repairActionDelete      = "finish deletion"
+	repairActionMarkDeleted = "mark deleted"
+
+	// Problems found by the history database check.
+	checkProblemOrphanRows             = "orphan rows"
+	checkProblemUnreadableBackup       = "unreadable backup"
+	checkProblemInvalidTimestamp       = "invalid timestamp"
+	checkProblemInvalidStatus          = "invalid status"
+	checkProblemInvalidBackupType      = "invalid backup type"
+	checkProblemInvalidDateDeleted     = "invalid date deleted"
+	checkProblemInvalidObjectFiltering = "invalid object filtering"
+	checkProblemMissingBaseBackup      = "missing base backup"
+	checkProblemDeletedBaseBackup      = "deleted base backup"
+
+	// Database for connection to the local cluster, if PGDATABASE is not set.
+	defaultClusterDatabase = "postgres"
+
 	// Default for checking the existence of the file.
 	checkFileExistsConst = true
 
 	// Batch size for deleting from sqlite3.
 	// This is to prevent problem with sqlite3.
 	sqliteDeleteBatchSize = 1000
+
+	// The delay before the first retry, if the history database is locked.
+	// The delay is doubled for each next retry.
+	historyDBRetryDelay = time.Second
 )
 
 var (
@@ -0,0 +1,333 @@
+package cmd
+
+import (
+	"database/sql"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman history-check command (historyCheckCmd)
+var (
+	historyCheckFix          bool
+	historyCheckOutputFormat string
+)
+
+// historyCheckProblem is a problem found in the history database.
+type historyCheckProblem struct {
+	Timestamp string `json:"timestamp" yaml:"timestamp"`
+	Problem   string `json:"problem" yaml:"problem"`
+	Details   string `json:"details" yaml:"details"`
+	Fixed     bool   `json:"fixed" yaml:"fixed"`
+}
+
+var historyCheckCmd = &cobra.Command{
+	Use:   "history-check",
+	Short: "Check the integrity of the history database",
+	Long: `Check the integrity of the history database.
+
+The following problems are found and displayed:
+  * orphan rows - rows in the restore_plans, restore_plan_tables, include_* and exclude_* tables without the backup in the backups table;
+  * unreadable backup - the backup information can't be read from the history database;
+  * invalid timestamp - the backup timestamp is not in the format YYYYMMDDHHMMSS;
+  * invalid status - the backup status is not Success, Failure or In Progress;
+  * invalid backup type - the incompatible backup type flags are set for the backup;
+  * invalid date deleted - the backup deletion date is neither the date nor the deletion status;
+  * invalid object filtering - several object filtering types are set for the backup;
+  * missing base backup - the backup from the restore plan of the active incremental backup doesn't exist;
+  * deleted base backup - the backup from the restore plan of the active incremental backup is deleted.
+If at least one problem is found, the command exits with an error.
+
+To repair the problems, that can be repaired safely, use the --fix option.
+Only orphan rows are repaired, they are deleted from the history database in a single transaction.
+Other problems are displayed and must be repaired manually.
+
+To change the output format, use the --output option. The following formats are supported: table (default), json, yaml.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doHistoryCheckFlagValidation(cmd.Flags())
+		doHistoryCheck()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCheckCmd)
+	historyCheckCmd.PersistentFlags().BoolVar(
+		&historyCheckFix,
+		fixFlagName,
+		false,
+		"repair the problems, that can be repaired safely",
+	)
+	historyCheckCmd.PersistentFlags().StringVar(
+		&historyCheckOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+}
+
+// These flag checks are applied only for history-check command.
+func doHistoryCheckFlagValidation(flags *pflag.FlagSet) {
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err := checkOutputFormat(historyCheckOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyCheckOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doHistoryCheck() {
+	logHeadersDebug()
+	err := historyCheck()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func historyCheck() error {
+	if historyCheckFix {
+		unlock, err := lockHistoryDB(getHistoryDBPath(rootHistoryDB))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	problems, err := checkHistoryDB(hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("check", err))
+		return err
+	}
+	if historyCheckFix {
+		err = fixHistoryDB(problems, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("fix", err))
+			return err
+		}
+	}
+	err = printHistoryCheck(historyCheckOutputFormat, problems, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(historyCheckOutputFormat, err))
+		return err
+	}
+	unfixed := countUnfixedProblems(problems)
+	if unfixed > 0 {
+		err = textmsg.ErrorHistoryDBProblemsFoundError(strconv.Itoa(unfixed))
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("check", err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryDBCheckSuccess())
+	return nil
+}
+
+// checkHistoryDB Returns all problems found in the history database.
+func checkHistoryDB(hDB *sql.DB) ([]historyCheckProblem, error) {
+	problems := make([]historyCheckProblem, 0)
+	orphanRows, err := gpbckpconfig.GetOrphanRowsDB(hDB)
+	if err != nil {
+		return nil, err
+	}
+	for _, rows := range orphanRows {
+		for _, backupName := range rows.BackupNames {
+			problems = append(problems, historyCheckProblem{
+				Timestamp: backupName,
+				Problem:   checkProblemOrphanRows,
+				Details:   "table " + rows.Table,
+			})
+		}
+	}
+	backupNames, err := gpbckpconfig.GetBackupNamesDB(true, true, gpbckpconfig.DatabaseFilter{}, hDB)
+	if err != nil {
+		return nil, err
+	}
+	backups := make(map[string]gpbckpconfig.BackupConfig, len(backupNames))
+	for _, backupName := range backupNames {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			problems = append(problems, historyCheckProblem{
+				Timestamp: backupName,
+				Problem:   checkProblemUnreadableBackup,
+				Details:   err.Error(),
+			})
+			continue
+		}
+		backups[backupName] = backupData
+	}
+	for _, backupName := range backupNames {
+		backupData, ok := backups[backupName]
+		if !ok {
+			continue
+		}
+		problems = append(problems, checkBackupData(backupData)...)
+		problems = append(problems, checkBackupRestorePlan(backupData, backups)...)
+	}
+	return problems, nil
+}
+
+// checkBackupData Returns the problems with the values, that are rejected by the backup data functions.
+// It is also used to validate the backups from the history files before the migration.
+func checkBackupData(backupData gpbckpconfig.BackupConfig) []historyCheckProblem {
+	var problems []historyCheckProblem
+	if err := gpbckpconfig.CheckTimestamp(backupData.Timestamp); err != nil {
+		problems = append(problems, historyCheckProblem{
+			Timestamp: backupData.Timestamp,
+			Problem:   checkProblemInvalidTimestamp,
+			Details:   err.Error(),
+		})
+	}
+	if _, err := backupData.IsSuccess(); err != nil {
+		problems = append(problems, historyCheckProblem{
+			Timestamp: backupData.Timestamp,
+			Problem:   checkProblemInvalidStatus,
+			Details:   backupData.Status,
+		})
+	}
+	if _, err := backupData.GetBackupType(); err != nil {
+		problems = append(problems, historyCheckProblem{
+			Timestamp: backupData.Timestamp,
+			Problem:   checkProblemInvalidBackupType,
+			Details:   err.Error(),
+		})
+	}
+	if _, err := backupData.GetBackupDateDeleted(); err != nil {
+		problems = append(problems, historyCheckProblem{
+			Timestamp: backupData.Timestamp,
+			Problem:   checkProblemInvalidDateDeleted,
+			Details:   backupData.DateDeleted,
+		})
+	}
+	if _, err := backupData.GetObjectFilteringInfo(); err != nil {
+		problems = append(problems, historyCheckProblem{
+			Timestamp: backupData.Timestamp,
+			Problem:   checkProblemInvalidObjectFiltering,
+			Details:   err.Error(),
+		})
+	}
+	return problems
+}
+
+// checkBackupRestorePlan Returns the problems with the base backups of the incremental backup.
+// Only active incremental backups, that are not failed, are checked.
+func checkBackupRestorePlan(backupData gpbckpconfig.BackupConfig, backups map[string]gpbckpconfig.BackupConfig) []historyCheckProblem {
+	var problems []historyCheckProblem
+	if !backupData.Incremental || backupData.Status == gpbckpconfig.BackupStatusFailure || !gpbckpconfig.IsBackupActive(backupData.DateDeleted) {
+		return problems
+	}
+	for _, entry := range backupData.RestorePlan {
+		if entry.Timestamp == backupData.Timestamp {
+			continue
+		}
+		baseData, ok := backups[entry.Timestamp]
+		switch {
+		case !ok:
+			problems = append(problems, historyCheckProblem{
+				Timestamp: backupData.Timestamp,
+				Problem:   checkProblemMissingBaseBackup,
+				Details:   entry.Timestamp,
+			})
+		case !gpbckpconfig.IsBackupActive(baseData.DateDeleted):
+			problems = append(problems, historyCheckProblem{
+				Timestamp: backupData.Timestamp,
+				Problem:   checkProblemDeletedBaseBackup,
+				Details:   entry.Timestamp,
+			})
+		}
+	}
+	return problems
+}
+
+// fixHistoryDB Repairs the problems, that can be repaired safely, and marks them as fixed.
+// Only orphan rows are deleted, the snapshot of the history database is created before the deletion.
+func fixHistoryDB(problems []historyCheckProblem, hDB *sql.DB) error {
+	var fixed int
+	for _, problem := range problems {
+		if problem.Problem == checkProblemOrphanRows {
+			fixed++
+		}
+	}
+	if fixed == 0 {
+		return nil
+	}
+	err := snapshotHistoryDB(getHistoryDBPath(rootHistoryDB))
+	if err != nil {
+		return err
+	}
+	err = gpbckpconfig.DeleteOrphanRowsDB(hDB)
+	if err != nil {
+		return err
+	}
+	for i := range problems {
+		if problems[i].Problem == checkProblemOrphanRows {
+			problems[i].Fixed = true
+		}
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryDBProblemsFixed(strconv.Itoa(fixed)))
+	return nil
+}
+
+func countUnfixedProblems(problems []historyCheckProblem) int {
+	var count int
+	for _, problem := range problems {
+		if !problem.Fixed {
+			count++
+		}
+	}
+	return count
+}
+
+// printHistoryCheck writes the found problems to w in the specified format.
+// Problems are sorted by timestamp in descending order, then by problem and details.
+func printHistoryCheck(outputFormat string, problems []historyCheckProblem, w io.Writer) error {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Timestamp != problems[j].Timestamp {
+			return problems[i].Timestamp > problems[j].Timestamp
+		}
+		if problems[i].Problem != problems[j].Problem {
+			return problems[i].Problem < problems[j].Problem
+		}
+		return problems[i].Details < problems[j].Details
+	})
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, problems)
+	default:
+		if len(problems) == 0 {
+			return nil
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"timestamp", "problem", "details", "fixed"})
+		for _, problem := range problems {
+			t.AppendRow(table.Row{problem.Timestamp, problem.Problem, problem.Details, strconv.FormatBool(problem.Fixed)})
+		}
+		t.Render()
+		return nil
+	}
+}
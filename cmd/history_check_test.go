@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestCheckBackupData(t *testing.T) {
+	tests := []struct {
+		name       string
+		backupData gpbckpconfig.BackupConfig
+		want       []historyCheckProblem
+	}{
+		{
+			name: "Test valid backup",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:   "20240101100000",
+				Status:      gpbckpconfig.BackupStatusSuccess,
+				DateDeleted: "20240102100000",
+			},
+			want: nil,
+		},
+		{
+			name: "Test invalid timestamp and backup type",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:    "2024010110",
+				Status:       gpbckpconfig.BackupStatusSuccess,
+				Incremental:  true,
+				DataOnly:     true,
+				MetadataOnly: false,
+			},
+			want: []historyCheckProblem{
+				{Timestamp: "2024010110", Problem: checkProblemInvalidTimestamp, Details: "not a timestamp"},
+				{Timestamp: "2024010110", Problem: checkProblemInvalidBackupType, Details: "backup type does not match any of the available values"},
+			},
+		},
+		{
+			name: "Test invalid values",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:             "20240101100000",
+				Status:                "Unknown",
+				DateDeleted:           "Unknown",
+				IncludeSchemaFiltered: true,
+				ExcludeTableFiltered:  true,
+			},
+			want: []historyCheckProblem{
+				{Timestamp: "20240101100000", Problem: checkProblemInvalidStatus, Details: "Unknown"},
+				{Timestamp: "20240101100000", Problem: checkProblemInvalidDateDeleted, Details: "Unknown"},
+				{Timestamp: "20240101100000", Problem: checkProblemInvalidObjectFiltering, Details: "backup filtering type does not match any of the available values"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkBackupData(tt.backupData); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckBackupRestorePlan(t *testing.T) {
+	backups := map[string]gpbckpconfig.BackupConfig{
+		"20240101100000": {Timestamp: "20240101100000", Status: gpbckpconfig.BackupStatusSuccess},
+		"20240102100000": {Timestamp: "20240102100000", Status: gpbckpconfig.BackupStatusSuccess, DateDeleted: "20240105100000"},
+	}
+	restorePlan := []gpbckpconfig.RestorePlanEntry{
+		{Timestamp: "20240101100000"},
+		{Timestamp: "20240102100000"},
+		{Timestamp: "20240103100000"},
+		{Timestamp: "20240104100000"},
+	}
+	tests := []struct {
+		name       string
+		backupData gpbckpconfig.BackupConfig
+		want       []historyCheckProblem
+	}{
+		{
+			name: "Test active incremental backup",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:   "20240104100000",
+				Status:      gpbckpconfig.BackupStatusSuccess,
+				Incremental: true,
+				RestorePlan: restorePlan,
+			},
+			want: []historyCheckProblem{
+				{Timestamp: "20240104100000", Problem: checkProblemDeletedBaseBackup, Details: "20240102100000"},
+				{Timestamp: "20240104100000", Problem: checkProblemMissingBaseBackup, Details: "20240103100000"},
+			},
+		},
+		{
+			name: "Test deleted incremental backup",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:   "20240104100000",
+				Status:      gpbckpconfig.BackupStatusSuccess,
+				DateDeleted: "20240105100000",
+				Incremental: true,
+				RestorePlan: restorePlan,
+			},
+			want: nil,
+		},
+		{
+			name: "Test failed incremental backup",
+			backupData: gpbckpconfig.BackupConfig{
+				Timestamp:   "20240104100000",
+				Status:      gpbckpconfig.BackupStatusFailure,
+				Incremental: true,
+				RestorePlan: restorePlan,
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := checkBackupRestorePlan(tt.backupData, backups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintHistoryCheck(t *testing.T) {
+	problems := []historyCheckProblem{
+		{Timestamp: "20240101100000", Problem: checkProblemOrphanRows, Details: "table restore_plans", Fixed: true},
+		{Timestamp: "20240102100000", Problem: checkProblemInvalidStatus, Details: "Unknown"},
+	}
+	tests := []struct {
+		name         string
+		outputFormat string
+		problems     []historyCheckProblem
+		want         string
+	}{
+		{
+			name:         "Test table format",
+			outputFormat: outputFormatTable,
+			problems:     problems,
+			want: " TIMESTAMP      | PROBLEM        | DETAILS             | FIXED \n" +
+				"----------------+----------------+---------------------+-------\n" +
+				" 20240102100000 | invalid status | Unknown             | false \n" +
+				" 20240101100000 | orphan rows    | table restore_plans | true  \n",
+		},
+		{
+			name:         "Test empty table format",
+			outputFormat: outputFormatTable,
+			problems:     []historyCheckProblem{},
+			want:         "",
+		},
+		{
+			name:         "Test empty json format",
+			outputFormat: outputFormatJSON,
+			problems:     []historyCheckProblem{},
+			want:         "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printHistoryCheck(tt.outputFormat, tt.problems, &buf); err != nil {
+				t.Fatalf("printHistoryCheck() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -14,6 +14,8 @@ import (
 var (
 	historyCleanBeforeTimestamp string
 	historyCleanOlderThenDays   uint
+	historyCleanDatabase        []string
+	historyCleanExcludeDatabase []string
 )
 
 var historyCleanCmd = &cobra.Command{
@@ -24,10 +26,17 @@ Only the database is being cleaned up.
 
 Information is deleted only about deleted backups from gpbackup_history.db. Each backup must be deleted first.
 
+The backups are deleted from all tables of the history database in batches. Each batch is deleted in a single transaction.
+If the deletion of the batch fails, the batch is rolled back and the history database keeps the complete information about its backups.
+
 To delete information about backups older than the given timestamp, use the --before-timestamp option. 
 To delete information about backups older than the given number of days, use the --older-than-day option. 
 Only --older-than-days or --before-timestamp option must be specified, not both.
 
+To delete information only about backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together.
+
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
 If the --history-db option is not specified, the history database will be searched in the current directory.`,
@@ -53,7 +62,20 @@ func init() {
 		"",
 		"delete information about backups older than the given timestamp",
 	)
+	historyCleanCmd.PersistentFlags().StringArrayVar(
+		&historyCleanDatabase,
+		databaseFlagName,
+		[]string{},
+		"delete information only about backups of the specified database, could be specified multiple times",
+	)
+	historyCleanCmd.PersistentFlags().StringArrayVar(
+		&historyCleanExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not delete information about backups of the specified database, could be specified multiple times",
+	)
 	historyCleanCmd.MarkFlagsMutuallyExclusive(beforeTimestampFlagName, olderThenDaysFlagName)
+	historyCleanCmd.MarkFlagsMutuallyExclusive(databaseFlagName, excludeDatabaseFlagName)
 }
 
 // These flag checks are applied only for backup-clean command.
@@ -86,7 +108,12 @@ func doCleanHistory() {
 }
 
 func cleanHistory() error {
-	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB))
+	unlock, err := lockHistoryDB(getHistoryDBPath(rootHistoryDB))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
 		return err
@@ -97,23 +124,32 @@ func cleanHistory() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
 		}
 	}()
-	err = historyCleanDB(beforeTimestamp, hDB)
+	dbFilter := gpbckpconfig.DatabaseFilter{
+		Include: historyCleanDatabase,
+		Exclude: historyCleanExcludeDatabase,
+	}
+	err = historyCleanDB(beforeTimestamp, dbFilter, hDB)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func historyCleanDB(cutOffTimestamp string, hDB *sql.DB) error {
-	backupList, err := gpbckpconfig.GetBackupNamesForCleanBeforeTimestamp(cutOffTimestamp, hDB)
+func historyCleanDB(cutOffTimestamp string, dbFilter gpbckpconfig.DatabaseFilter, hDB *sql.DB) error {
+	backupList, err := gpbckpconfig.GetBackupNamesForCleanBeforeTimestamp(cutOffTimestamp, dbFilter, hDB)
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
 		return err
 	}
 	if len(backupList) > 0 {
 		gplog.Debug("%s", textmsg.InfoTextBackupDeleteListFromHistory(backupList))
-		err := gpbckpconfig.CleanBackupsDB(backupList, sqliteDeleteBatchSize, hDB)
+		err = snapshotHistoryDB(getHistoryDBPath(rootHistoryDB))
+		if err != nil {
+			return err
+		}
+		err = gpbckpconfig.CleanBackupsDB(backupList, sqliteDeleteBatchSize, hDB)
 		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("clean", err))
 			return err
 		}
 	} else {
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"database/sql"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman history-export command (historyExportCmd)
+var (
+	historyExportShowDeleted      bool
+	historyExportShowFailed       bool
+	historyExportBackupTypeFilter string
+	historyExportTimestamp        []string
+	historyExportDatabase         []string
+	historyExportExcludeDatabase  []string
+	historyExportOutputFormat     string
+	historyExportOutputFile       string
+)
+
+// Options for the history-export command.
+type HistoryExportOptions struct {
+	ShowDeleted      bool
+	ShowFailed       bool
+	BackupTypeFilter string
+	Timestamp        []string
+	DatabaseFilter   gpbckpconfig.DatabaseFilter
+}
+
+var historyExportCmd = &cobra.Command{
+	Use:   "history-export",
+	Short: "Export backups from the history database",
+	Long: `Export backups from gpbackup_history.db SQLite history database to the gpbackup_history.yaml file format.
+
+The exported data contains all backup fields from the history database, including the restore plans
+and the object filtering lists. The yaml format is the same as the format of the gpbackup_history.yaml file,
+so the exported file could be loaded back using the history-migrate command.
+
+By default, only active backups or backups with deletion status "In progress" are exported.
+To export deleted backups, use the --deleted option.
+To export failed backups, use the --failed option.
+To export all backups, use --deleted and --failed options together.
+
+To export backups of a specific type, use the --type option.
+
+To export only backups of the specific databases, use the --database option. It could be specified multiple times.
+To skip backups of the specific databases, use the --exclude-database option. It could be specified multiple times.
+The --database and --exclude-database options cannot be used together.
+
+To export specific backups, use the --timestamp option. It could be specified multiple times.
+The backups are exported regardless of their status.
+When --timestamp is set, the following options cannot be used: --type, --failed, --deleted, --database, --exclude-database.
+
+To change the output format, use the --output option. The following formats are supported:
+  * yaml - gpbackup_history.yaml file format (default);
+  * json - JSON object with the same fields as in the yaml format.
+
+By default, the exported data is written to stdout.
+To write the exported data to a file, use the --output-file option. The full path to the file is required.
+The file must not exist.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doHistoryExportFlagValidation(cmd.Flags())
+		doHistoryExport()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyExportCmd)
+	historyExportCmd.PersistentFlags().BoolVar(
+		&historyExportShowDeleted,
+		deletedFlagName,
+		false,
+		"export deleted backups",
+	)
+	historyExportCmd.PersistentFlags().BoolVar(
+		&historyExportShowFailed,
+		failedFlagName,
+		false,
+		"export failed backups",
+	)
+	historyExportCmd.PersistentFlags().StringVar(
+		&historyExportBackupTypeFilter,
+		typeFlagName,
+		"",
+		"backup type filter (full, incremental, data-only, metadata-only)",
+	)
+	historyExportCmd.PersistentFlags().StringArrayVar(
+		&historyExportTimestamp,
+		timestampFlagName,
+		[]string{},
+		"export the backup with the specified timestamp, could be specified multiple times",
+	)
+	historyExportCmd.PersistentFlags().StringArrayVar(
+		&historyExportDatabase,
+		databaseFlagName,
+		[]string{},
+		"export backups only for the specified database, could be specified multiple times",
+	)
+	historyExportCmd.PersistentFlags().StringArrayVar(
+		&historyExportExcludeDatabase,
+		excludeDatabaseFlagName,
+		[]string{},
+		"do not export backups for the specified database, could be specified multiple times",
+	)
+	historyExportCmd.PersistentFlags().StringVar(
+		&historyExportOutputFormat,
+		outputFlagName,
+		outputFormatYAML,
+		"output format (yaml, json)",
+	)
+	historyExportCmd.PersistentFlags().StringVar(
+		&historyExportOutputFile,
+		outputFileFlagName,
+		"",
+		"the full path to the file to write the exported data",
+	)
+}
+
+// These flag checks are applied only for history-export command.
+func doHistoryExportFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	if flags.Changed(timestampFlagName) {
+		for _, timestamp := range historyExportTimestamp {
+			err = gpbckpconfig.CheckTimestamp(timestamp)
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(timestamp, timestampFlagName, err))
+				execOSExit(exitErrorCode)
+			}
+		}
+		// --timestamp is not compatible with --type, --failed, --deleted, --database, --exclude-database
+		err = checkCompatibleFlags(flags, timestampFlagName,
+			typeFlagName, failedFlagName, deletedFlagName, databaseFlagName, excludeDatabaseFlagName)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, timestampFlagName, typeFlagName, failedFlagName, deletedFlagName, databaseFlagName, excludeDatabaseFlagName))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// database and exclude-database flags cannot be used together.
+	err = checkCompatibleFlags(flags, databaseFlagName, excludeDatabaseFlagName)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(err, databaseFlagName, excludeDatabaseFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If type is specified and have correct values.
+	if flags.Changed(typeFlagName) {
+		err = checkBackupType(historyExportBackupTypeFilter)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyExportBackupTypeFilter, typeFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(historyExportOutputFormat, outputFormatYAML, outputFormatJSON)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyExportOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If output-file flag is specified, the full path is specified and the file doesn't exist.
+	if flags.Changed(outputFileFlagName) {
+		err = gpbckpconfig.CheckFullPath(historyExportOutputFile, false)
+		if err == nil {
+			if _, statErr := os.Stat(historyExportOutputFile); statErr == nil {
+				err = textmsg.ErrorFileAlreadyExist()
+			}
+		}
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyExportOutputFile, outputFileFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+}
+
+func doHistoryExport() {
+	logHeadersDebug()
+	err := historyExport()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func historyExport() error {
+	opts := HistoryExportOptions{
+		ShowDeleted:      historyExportShowDeleted,
+		ShowFailed:       historyExportShowFailed,
+		BackupTypeFilter: historyExportBackupTypeFilter,
+		Timestamp:        historyExportTimestamp,
+		DatabaseFilter: gpbckpconfig.DatabaseFilter{
+			Include: historyExportDatabase,
+			Exclude: historyExportExcludeDatabase,
+		},
+	}
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	historyData, err := historyExportDB(opts, hDB)
+	if err != nil {
+		return err
+	}
+	if historyExportOutputFile == "" {
+		err = writeStructuredOutput(os.Stdout, historyExportOutputFormat, historyData)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(historyExportOutputFormat, err))
+			return err
+		}
+		return nil
+	}
+	err = writeHistoryExportFile(historyExportOutputFile, historyExportOutputFormat, historyData)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("write", err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryExported(strconv.Itoa(len(historyData.BackupConfigs)), historyExportOutputFile))
+	return nil
+}
+
+// historyExportDB Returns the backups from the history database, that match the options.
+// The backups are sorted by timestamp in descending order, as in the gpbackup_history.yaml file.
+func historyExportDB(opts HistoryExportOptions, hDB *sql.DB) (gpbckpconfig.History, error) {
+	historyData := gpbckpconfig.History{BackupConfigs: make([]gpbckpconfig.BackupConfig, 0)}
+	backupNames := opts.Timestamp
+	if len(backupNames) == 0 {
+		var err error
+		backupNames, err = gpbckpconfig.GetBackupNamesDB(opts.ShowDeleted, opts.ShowFailed, opts.DatabaseFilter, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+			return historyData, err
+		}
+	}
+	for _, backupName := range backupNames {
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			return historyData, err
+		}
+		if opts.BackupTypeFilter != "" {
+			backupType, err := backupData.GetBackupType()
+			if err != nil {
+				gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("type", backupName, err))
+				return historyData, err
+			}
+			if backupType != opts.BackupTypeFilter {
+				continue
+			}
+		}
+		historyData.BackupConfigs = append(historyData.BackupConfigs, backupData)
+	}
+	sort.SliceStable(historyData.BackupConfigs, func(i, j int) bool {
+		return historyData.BackupConfigs[i].Timestamp > historyData.BackupConfigs[j].Timestamp
+	})
+	return historyData, nil
+}
+
+// writeHistoryExportFile writes the exported backups to the new file.
+// If the file already exists, an error is returned.
+func writeHistoryExportFile(filename, outputFormat string, historyData gpbckpconfig.History) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	err = writeStructuredOutput(file, outputFormat, historyData)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
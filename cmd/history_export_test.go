@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+// createTestHistoryDB Creates the history database with the backups.
+func createTestHistoryDB(t *testing.T, historyDBPath string, backupConfigs []gpbckpconfig.BackupConfig) *sql.DB {
+	hDB, err := history.InitializeHistoryDatabase(historyDBPath)
+	if err != nil {
+		t.Fatalf("InitializeHistoryDatabase() error = %v", err)
+	}
+	t.Cleanup(func() { hDB.Close() })
+	for _, backupConfig := range backupConfigs {
+		hBackupConfig := gpbckpconfig.ConvertToHistoryBackupConfig(backupConfig)
+		err = history.StoreBackupHistory(hDB, &hBackupConfig)
+		if err != nil {
+			t.Fatalf("StoreBackupHistory() error = %v", err)
+		}
+	}
+	return hDB
+}
+
+func TestHistoryExportDB(t *testing.T) {
+	testhelper.SetupTestLogger()
+	fullBackup := gpbckpconfig.BackupConfig{
+		DatabaseName:     "test1",
+		Timestamp:        "20240101100000",
+		EndTime:          "20240101100010",
+		Status:           gpbckpconfig.BackupStatusSuccess,
+		IncludeRelations: []string{"public.t1", "public.t2"},
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{
+			{Timestamp: "20240101100000", TableFQNs: []string{"public.t1", "public.t2"}},
+		},
+	}
+	incrBackup := gpbckpconfig.BackupConfig{
+		DatabaseName: "test1",
+		Timestamp:    "20240102100000",
+		EndTime:      "20240102100010",
+		Status:       gpbckpconfig.BackupStatusSuccess,
+		Incremental:  true,
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{
+			{Timestamp: "20240101100000", TableFQNs: []string{"public.t1"}},
+			{Timestamp: "20240102100000", TableFQNs: []string{"public.t2"}},
+		},
+	}
+	failedBackup := gpbckpconfig.BackupConfig{
+		DatabaseName: "test2",
+		Timestamp:    "20240103100000",
+		EndTime:      "20240103100010",
+		Status:       gpbckpconfig.BackupStatusFailure,
+		MetadataOnly: true,
+	}
+	hDB := createTestHistoryDB(t, filepath.Join(t.TempDir(), "gpbackup_history.db"), []gpbckpconfig.BackupConfig{fullBackup, incrBackup, failedBackup})
+	tests := []struct {
+		name    string
+		opts    HistoryExportOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Test active backups",
+			opts: HistoryExportOptions{},
+			want: []string{"20240102100000", "20240101100000"},
+		},
+		{
+			name: "Test all backups",
+			opts: HistoryExportOptions{ShowDeleted: true, ShowFailed: true},
+			want: []string{"20240103100000", "20240102100000", "20240101100000"},
+		},
+		{
+			name: "Test type filter",
+			opts: HistoryExportOptions{BackupTypeFilter: gpbckpconfig.BackupTypeIncremental},
+			want: []string{"20240102100000"},
+		},
+		{
+			name: "Test database filter",
+			opts: HistoryExportOptions{ShowFailed: true, DatabaseFilter: gpbckpconfig.DatabaseFilter{Include: []string{"test2"}}},
+			want: []string{"20240103100000"},
+		},
+		{
+			name: "Test timestamps",
+			opts: HistoryExportOptions{Timestamp: []string{"20240101100000", "20240103100000"}},
+			want: []string{"20240103100000", "20240101100000"},
+		},
+		{
+			name:    "Test missing timestamp",
+			opts:    HistoryExportOptions{Timestamp: []string{"20240104100000"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyData, err := historyExportDB(tt.opts, hDB)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("historyExportDB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := make([]string, 0, len(historyData.BackupConfigs))
+			for _, backupConfig := range historyData.BackupConfigs {
+				got = append(got, backupConfig.Timestamp)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+	// The restore plan and the filter lists are exported.
+	historyData, err := historyExportDB(HistoryExportOptions{Timestamp: []string{"20240102100000", "20240101100000"}}, hDB)
+	if err != nil {
+		t.Fatalf("historyExportDB() error = %v", err)
+	}
+	if !reflect.DeepEqual(historyData.BackupConfigs[0].RestorePlan, incrBackup.RestorePlan) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", historyData.BackupConfigs[0].RestorePlan, incrBackup.RestorePlan)
+	}
+	if !reflect.DeepEqual(historyData.BackupConfigs[1].IncludeRelations, fullBackup.IncludeRelations) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", historyData.BackupConfigs[1].IncludeRelations, fullBackup.IncludeRelations)
+	}
+}
+
+func TestWriteHistoryExportFile(t *testing.T) {
+	historyData := gpbckpconfig.History{
+		BackupConfigs: []gpbckpconfig.BackupConfig{
+			{
+				DatabaseName:     "test",
+				Timestamp:        "20240101100000",
+				Status:           gpbckpconfig.BackupStatusSuccess,
+				ExcludeRelations: []string{},
+				ExcludeSchemas:   []string{},
+				IncludeRelations: []string{},
+				IncludeSchemas:   []string{"public"},
+				RestorePlan: []gpbckpconfig.RestorePlanEntry{
+					{Timestamp: "20240101100000", TableFQNs: []string{"public.t1"}},
+				},
+			},
+		},
+	}
+	filename := filepath.Join(t.TempDir(), "gpbackup_history.yaml")
+	err := writeHistoryExportFile(filename, outputFormatYAML, historyData)
+	if err != nil {
+		t.Fatalf("writeHistoryExportFile() error = %v", err)
+	}
+	// The exported file could be read as the gpbackup history file.
+	data, err := gpbckpconfig.ReadHistoryFile(filename)
+	if err != nil {
+		t.Fatalf("ReadHistoryFile() error = %v", err)
+	}
+	got, err := gpbckpconfig.ParseResult(data)
+	if err != nil {
+		t.Fatalf("ParseResult() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, historyData) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, historyData)
+	}
+	// The existing file is not overwritten.
+	err = writeHistoryExportFile(filename, outputFormatJSON, historyData)
+	if !os.IsExist(err) {
+		t.Errorf("writeHistoryExportFile() error = %v, want file exists error", err)
+	}
+}
+
+func TestWriteHistoryExportJSON(t *testing.T) {
+	historyData := gpbckpconfig.History{
+		BackupConfigs: []gpbckpconfig.BackupConfig{
+			{Timestamp: "20240101100000", RestorePlan: []gpbckpconfig.RestorePlanEntry{{Timestamp: "20240101100000"}}},
+		},
+	}
+	buffer := bytes.NewBuffer(nil)
+	err := writeStructuredOutput(buffer, outputFormatJSON, historyData)
+	if err != nil {
+		t.Fatalf("writeStructuredOutput() error = %v", err)
+	}
+	for _, want := range []string{`"backupconfigs": [`, `"timestamp": "20240101100000"`, `"restoreplan": [`, `"tablefqdn": null`} {
+		if !bytes.Contains(buffer.Bytes(), []byte(want)) {
+			t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buffer.String(), want)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman history-lock command (historyLockCmd)
+var (
+	historyLockBreak        bool
+	historyLockForce        bool
+	historyLockOutputFormat string
+)
+
+// historyLockInfo is the lock information with the result of the stale check.
+type historyLockInfo struct {
+	gpbckpconfig.HistoryDBLock `yaml:",inline"`
+	Stale                      bool `json:"stale" yaml:"stale"`
+}
+
+var historyLockCmd = &cobra.Command{
+	Use:   "history-lock",
+	Short: "Display or break the history database lock",
+	Long: `Display or break the history database lock.
+
+The commands that change the history database or the backup storage (backup-clean, backup-delete, backup-orphans with --delete option,
+backup-repair, history-check with --fix option, history-clean, history-migrate and history-restore with --snapshot option)
+take the lock of the history database.
+The lock file is created next to the history database with the .lock suffix. It contains the pid, the host name
+and the command of the process that holds the lock and the time when the lock was taken.
+If the lock is held by another process, the command is not performed.
+
+The lock is stale if it was taken on the current host and the process that holds the lock no longer exists.
+The stale lock is replaced automatically by the next command.
+The lock taken on another host is never considered stale.
+
+By default, the lock information is displayed.
+The output format can be set using the --output option. Supported formats are table, json and yaml.
+
+To break the lock, use the --break option. If the lock is not stale or can not be read, the --force option is required.
+The --force option can be used only with --break option.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), false)
+		doHistoryLockFlagValidation(cmd.Flags())
+		doHistoryLock()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyLockCmd)
+	historyLockCmd.PersistentFlags().BoolVar(
+		&historyLockBreak,
+		breakFlagName,
+		false,
+		"break the history database lock",
+	)
+	historyLockCmd.PersistentFlags().BoolVar(
+		&historyLockForce,
+		forceFlagName,
+		false,
+		"break the lock, even if it is held by an active process",
+	)
+	historyLockCmd.PersistentFlags().StringVar(
+		&historyLockOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+}
+
+// These flag checks are applied only for history-lock command.
+func doHistoryLockFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(historyLockOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyLockOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// force flag could be used only with break flag.
+	if flags.Changed(forceFlagName) && !flags.Changed(breakFlagName) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), forceFlagName, breakFlagName))
+		execOSExit(exitErrorCode)
+	}
+}
+
+func doHistoryLock() {
+	logHeadersDebug()
+	err := historyLock()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func historyLock() error {
+	lockPath := gpbckpconfig.HistoryDBLockPath(getHistoryDBPath(rootHistoryDB))
+	if historyLockBreak {
+		return breakHistoryDBLock(lockPath, historyLockForce)
+	}
+	return displayHistoryDBLock(lockPath, historyLockOutputFormat, os.Stdout)
+}
+
+// getHistoryDBLockInfo Returns the lock information and the result of the stale check.
+// If the lock file doesn't exist, nil is returned.
+func getHistoryDBLockInfo(lockPath string) (*historyLockInfo, error) {
+	lock, err := gpbckpconfig.ReadHistoryDBLock(lockPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	stale, err := gpbckpconfig.IsHistoryDBLockStale(lock)
+	if err != nil {
+		return nil, err
+	}
+	return &historyLockInfo{HistoryDBLock: lock, Stale: stale}, nil
+}
+
+func displayHistoryDBLock(lockPath, outputFormat string, w io.Writer) error {
+	lockInfo, err := getHistoryDBLockInfo(lockPath)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("read", err))
+		return err
+	}
+	if lockInfo == nil {
+		gplog.Info("%s", textmsg.InfoTextHistoryDBNotLocked())
+		return nil
+	}
+	err = printHistoryDBLock(outputFormat, *lockInfo, w)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(outputFormat, err))
+		return err
+	}
+	return nil
+}
+
+// breakHistoryDBLock Removes the lock file.
+// The lock, that is held by an active process or can't be read, is removed only with force.
+func breakHistoryDBLock(lockPath string, force bool) error {
+	lockInfo, err := getHistoryDBLockInfo(lockPath)
+	if err != nil && !force {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("read", err))
+		return err
+	}
+	if err == nil {
+		if lockInfo == nil {
+			gplog.Info("%s", textmsg.InfoTextHistoryDBNotLocked())
+			return nil
+		}
+		if !lockInfo.Stale && !force {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("break", textmsg.ErrorHistoryDBLockActiveError()))
+			return textmsg.ErrorHistoryDBLockActiveError()
+		}
+	}
+	err = os.Remove(lockPath)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("break", err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryDBLockBroken(lockPath))
+	return nil
+}
+
+func printHistoryDBLock(outputFormat string, lockInfo historyLockInfo, w io.Writer) error {
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, lockInfo)
+	default:
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"pid", "hostname", "command", "started", "stale"})
+		t.AppendRow(table.Row{lockInfo.PID, lockInfo.Hostname, lockInfo.Command, lockInfo.Started, strconv.FormatBool(lockInfo.Stale)})
+		t.Render()
+		return nil
+	}
+}
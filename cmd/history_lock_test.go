@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestBreakHistoryDBLock(t *testing.T) {
+	testhelper.SetupTestLogger()
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("unable to get hostname: %v", err)
+	}
+	finished := exec.Command("true")
+	if err := finished.Run(); err != nil {
+		t.Fatalf("unable to run process: %v", err)
+	}
+	activeLock := `{"pid":` + strconv.Itoa(os.Getpid()) + `,"hostname":"` + hostname + `","command":"backup-clean","started":"20240101100000"}`
+	staleLock := `{"pid":` + strconv.Itoa(finished.Process.Pid) + `,"hostname":"` + hostname + `","command":"backup-clean","started":"20240101100000"}`
+	tests := []struct {
+		name        string
+		lockContent string
+		force       bool
+		wantErr     bool
+		wantExists  bool
+	}{
+		{
+			name: "Test no lock",
+		},
+		{
+			name:        "Test stale lock",
+			lockContent: staleLock,
+		},
+		{
+			name:        "Test active lock",
+			lockContent: activeLock,
+			wantErr:     true,
+			wantExists:  true,
+		},
+		{
+			name:        "Test active lock with force",
+			lockContent: activeLock,
+			force:       true,
+		},
+		{
+			name:        "Test invalid lock",
+			lockContent: "invalid",
+			wantErr:     true,
+			wantExists:  true,
+		},
+		{
+			name:        "Test invalid lock with force",
+			lockContent: "invalid",
+			force:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := gpbckpconfig.HistoryDBLockPath(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+			if tt.lockContent != "" {
+				if err := os.WriteFile(lockPath, []byte(tt.lockContent), 0o600); err != nil {
+					t.Fatalf("unable to write lock file: %v", err)
+				}
+			}
+			err := breakHistoryDBLock(lockPath, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("breakHistoryDBLock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			_, err = os.Stat(lockPath)
+			if exists := err == nil; exists != tt.wantExists {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", exists, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestPrintHistoryDBLock(t *testing.T) {
+	lockInfo := historyLockInfo{
+		HistoryDBLock: gpbckpconfig.HistoryDBLock{
+			PID:      123,
+			Hostname: "TestHost",
+			Command:  "backup-clean",
+			Started:  "20240101100000",
+		},
+		Stale: true,
+	}
+	tests := []struct {
+		name         string
+		outputFormat string
+		want         string
+	}{
+		{
+			name:         "Test table format",
+			outputFormat: outputFormatTable,
+			want: " PID | HOSTNAME | COMMAND      | STARTED        | STALE \n" +
+				"-----+----------+--------------+----------------+-------\n" +
+				" 123 | TestHost | backup-clean | 20240101100000 | true  \n",
+		},
+		{
+			name:         "Test json format",
+			outputFormat: outputFormatJSON,
+			want: `{
+  "pid": 123,
+  "hostname": "TestHost",
+  "command": "backup-clean",
+  "started": "20240101100000",
+  "stale": true
+}
+`,
+		},
+		{
+			name:         "Test yaml format",
+			outputFormat: outputFormatYAML,
+			want: `pid: 123
+hostname: TestHost
+command: backup-clean
+started: "20240101100000"
+stale: true
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printHistoryDBLock(tt.outputFormat, lockInfo, &buf); err != nil {
+				t.Fatalf("printHistoryDBLock() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), tt.want)
+			}
+		})
+	}
+}
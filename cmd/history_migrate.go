@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"errors"
+	"os"
+	"strconv"
+
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/history"
 	"github.com/spf13/cobra"
@@ -9,7 +13,12 @@ import (
 	"github.com/woblerr/gpbackman/textmsg"
 )
 
-var historyMigrateHistoryFiles []string
+// Flags for the gpbackman history-migrate command (historyMigrateCmd)
+var (
+	historyMigrateHistoryFiles []string
+	historyMigrateConflict     string
+	historyMigrateDryRun       bool
+)
 
 var historyMigrateCmd = &cobra.Command{
 	Use:   "history-migrate",
@@ -20,6 +29,25 @@ The data from the gpbackup_history.yaml file will be uploaded to gpbackup_histor
 If the gpbackup_history.db file does not exist, it will be created.
 The gpbackup_history.yaml file will be renamed to gpbackup_history.yaml.migrated.
 
+The backups from each file are stored in a single transaction. If any backup can't be stored,
+no backups from the file are stored and the file is not renamed, so the migration could be safely repeated.
+
+The backup conflicts, if the backup with the same timestamp already exists in the history database
+or occurs earlier in the same file. The --conflict option sets how the conflicting backups are processed:
+  * fail - no backups from the file are stored and the command exits with an error (default);
+  * skip - the conflicting backup is not stored, the existing backup is kept;
+  * overwrite - the existing backup is replaced with the backup from the file.
+For each file, the numbers of inserted, skipped and conflicting backups are displayed.
+
+To check the files before the migration, use the --dry-run option.
+In this mode, the history database is not changed and the files are not renamed.
+For each file, the following information is displayed:
+  * the number of backups in the file and the number of backups of each type;
+  * the backups with invalid values (timestamp, status, backup type, date deleted and object filtering);
+  * the numbers of backups, that would be inserted, skipped and conflicting.
+The history database is not created, if it doesn't exist.
+If at least one backup with invalid values is found or the migration would fail, the command exits with an error.
+
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
 
@@ -44,8 +72,19 @@ func init() {
 		[]string{""},
 		"full path to the gpbackup_history.yaml file, could be specified multiple times",
 	)
+	historyMigrateCmd.PersistentFlags().StringVar(
+		&historyMigrateConflict,
+		conflictFlagName,
+		gpbckpconfig.MigrateStrategyFail,
+		"how to process backups, that already exist in the history database (fail, skip, overwrite)",
+	)
+	historyMigrateCmd.PersistentFlags().BoolVar(
+		&historyMigrateDryRun,
+		dryRunFlagName,
+		false,
+		"validate the files and display the migration result without changing the history database",
+	)
 	_ = historyMigrateCmd.MarkPersistentFlagRequired(historyFilesFlagName)
-
 }
 
 // These flag checks are applied only for history-migrate commands.
@@ -62,6 +101,14 @@ func doHistoryMigrateFlagValidation(flags *pflag.FlagSet) {
 			}
 		}
 	}
+	// If conflict flag is specified and have correct values.
+	if flags.Changed(conflictFlagName) {
+		err = checkMigrateStrategy(historyMigrateConflict)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyMigrateConflict, conflictFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
 }
 
 func doMigrateHistory() {
@@ -73,6 +120,33 @@ func doMigrateHistory() {
 }
 
 func migrateHistory() error {
+	if historyMigrateDryRun {
+		return migrateHistoryDryRun()
+	}
+	unlock, err := lockHistoryDB(getHistoryDBPath(rootHistoryDB))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// All history files are parsed before the history database is changed.
+	historyData := make([]gpbckpconfig.History, 0, len(historyMigrateHistoryFiles))
+	for _, historyFile := range historyMigrateHistoryFiles {
+		parseHData, err := readMigrateHistoryFile(getHistoryFilePath(historyFile))
+		if err != nil {
+			return err
+		}
+		historyData = append(historyData, parseHData)
+	}
+	changeCount, err := countMigrateChanges(getHistoryDBPath(rootHistoryDB), historyData, historyMigrateConflict)
+	if err != nil {
+		return err
+	}
+	if changeCount > 0 {
+		err = snapshotHistoryDB(getHistoryDBPath(rootHistoryDB))
+		if err != nil {
+			return err
+		}
+	}
 	hDB, err := history.InitializeHistoryDatabase(getHistoryDBPath(rootHistoryDB))
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableInitHistoryDB(err))
@@ -84,27 +158,16 @@ func migrateHistory() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
 		}
 	}()
-	for _, historyFile := range historyMigrateHistoryFiles {
+	for i, historyFile := range historyMigrateHistoryFiles {
 		gplog.Info("%s", textmsg.InfoTextMigrateHistoryFile("Start", historyFile))
 		hFile := getHistoryFilePath(historyFile)
-		historyData, err := gpbckpconfig.ReadHistoryFile(hFile)
-		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("read", err))
-			return err
-		}
-		parseHData, err := gpbckpconfig.ParseResult(historyData)
+		result, err := gpbckpconfig.MigrateBackupsDB(historyData[i].BackupConfigs, historyMigrateConflict, hDB)
+		gplog.Info("%s", textmsg.InfoTextMigrateHistoryFileResult(
+			strconv.Itoa(result.Inserted), strconv.Itoa(result.Skipped), strconv.Itoa(result.Conflicting)))
 		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("parse", err))
+			gplog.Error("%s", textmsg.ErrorTextUnableWriteIntoHistoryDB(err))
 			return err
 		}
-		for _, backupConfig := range parseHData.BackupConfigs {
-			hBackupConfig := gpbckpconfig.ConvertToHistoryBackupConfig(backupConfig)
-			err = history.StoreBackupHistory(hDB, &hBackupConfig)
-			if err != nil {
-				gplog.Error("%s", textmsg.ErrorTextUnableWriteIntoHistoryDB(err))
-				return err
-			}
-		}
 		err = renameHistoryFile(hFile)
 		if err != nil {
 			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("rename", err))
@@ -114,3 +177,127 @@ func migrateHistory() error {
 	}
 	return nil
 }
+
+// readMigrateHistoryFile Reads and parses the history file.
+func readMigrateHistoryFile(hFile string) (gpbckpconfig.History, error) {
+	historyData, err := gpbckpconfig.ReadHistoryFile(hFile)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("read", err))
+		return gpbckpconfig.History{}, err
+	}
+	parseHData, err := gpbckpconfig.ParseResult(historyData)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("parse", err))
+		return gpbckpconfig.History{}, err
+	}
+	return parseHData, nil
+}
+
+// countMigrateChanges Returns the number of backups, that would be inserted or overwritten in the history database.
+// The history files are processed in order, as in the migration.
+// If the conflicts of the history file fail the migration, the next files are not counted,
+// because the migration stops on this file.
+func countMigrateChanges(historyDBPath string, historyData []gpbckpconfig.History, strategy string) (int, error) {
+	backupNames, err := getMigrateBackupNames(historyDBPath)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	for _, hData := range historyData {
+		result, err := gpbckpconfig.CheckMigrateBackups(hData.BackupConfigs, strategy, backupNames)
+		if err != nil {
+			break
+		}
+		count += result.Inserted
+	}
+	return count, nil
+}
+
+// migrateHistoryDryRun Validates the history files and displays the result of the migration.
+// The history database and the history files are not changed.
+func migrateHistoryDryRun() error {
+	backupNames, err := getMigrateBackupNames(getHistoryDBPath(rootHistoryDB))
+	if err != nil {
+		return err
+	}
+	var problemsCount int
+	var migrateErr error
+	for _, historyFile := range historyMigrateHistoryFiles {
+		parseHData, err := readMigrateHistoryFile(getHistoryFilePath(historyFile))
+		if err != nil {
+			return err
+		}
+		gplog.Info("%s", textmsg.InfoTextDryRunMigrateHistoryFile(historyFile, strconv.Itoa(len(parseHData.BackupConfigs))))
+		typeCounts, problems := checkMigrateBackups(parseHData.BackupConfigs)
+		for _, backupType := range []string{
+			gpbckpconfig.BackupTypeFull,
+			gpbckpconfig.BackupTypeIncremental,
+			gpbckpconfig.BackupTypeDataOnly,
+			gpbckpconfig.BackupTypeMetadataOnly,
+		} {
+			gplog.Info("%s", textmsg.InfoTextDryRunMigrateBackupType(backupType, strconv.Itoa(typeCounts[backupType])))
+		}
+		for _, problem := range problems {
+			gplog.Warn("%s", textmsg.WarnTextBackupProblem(problem.Timestamp, problem.Problem, problem.Details))
+		}
+		problemsCount += len(problems)
+		result, err := gpbckpconfig.CheckMigrateBackups(parseHData.BackupConfigs, historyMigrateConflict, backupNames)
+		gplog.Info("%s", textmsg.InfoTextDryRunMigrateHistoryFileResult(
+			strconv.Itoa(result.Inserted), strconv.Itoa(result.Skipped), strconv.Itoa(result.Conflicting)))
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("migrate", err))
+			if migrateErr == nil {
+				migrateErr = err
+			}
+		}
+	}
+	if problemsCount > 0 {
+		err = textmsg.ErrorHistoryFileProblemsFoundError(strconv.Itoa(problemsCount))
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryFile("validate", err))
+		return err
+	}
+	return migrateErr
+}
+
+// getMigrateBackupNames Returns the timestamps of all backups from the history database.
+// If the history database doesn't exist, the empty result is returned and the history database is not created.
+func getMigrateBackupNames(historyDBPath string) (map[string]bool, error) {
+	backupNames := make(map[string]bool)
+	if _, err := os.Stat(historyDBPath); errors.Is(err, os.ErrNotExist) {
+		return backupNames, nil
+	}
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyDBPath, getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return nil, err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	backupList, err := gpbckpconfig.GetBackupNamesDB(true, true, gpbckpconfig.DatabaseFilter{}, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableReadHistoryDB(err))
+		return nil, err
+	}
+	for _, backupName := range backupList {
+		backupNames[backupName] = true
+	}
+	return backupNames, nil
+}
+
+// checkMigrateBackups Returns the number of backups of each type and the backups with invalid values.
+// The backups with invalid backup type are not counted.
+func checkMigrateBackups(backupConfigs []gpbckpconfig.BackupConfig) (map[string]int, []historyCheckProblem) {
+	typeCounts := make(map[string]int)
+	var problems []historyCheckProblem
+	for _, backupConfig := range backupConfigs {
+		if backupType, err := backupConfig.GetBackupType(); err == nil {
+			typeCounts[backupType]++
+		}
+		problems = append(problems, checkBackupData(backupConfig)...)
+	}
+	return typeCounts, problems
+}
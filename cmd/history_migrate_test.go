@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestCheckMigrateBackups(t *testing.T) {
+	backupConfigs := []gpbckpconfig.BackupConfig{
+		{Timestamp: "20240101100000", Status: gpbckpconfig.BackupStatusSuccess},
+		{Timestamp: "20240102100000", Status: gpbckpconfig.BackupStatusSuccess, Incremental: true},
+		{Timestamp: "20240103100000", Status: gpbckpconfig.BackupStatusFailure, MetadataOnly: true},
+		{Timestamp: "20240104", Status: "Unknown", Incremental: true, DataOnly: true},
+	}
+	wantTypeCounts := map[string]int{
+		gpbckpconfig.BackupTypeFull:         1,
+		gpbckpconfig.BackupTypeIncremental:  1,
+		gpbckpconfig.BackupTypeMetadataOnly: 1,
+	}
+	wantProblems := []historyCheckProblem{
+		{Timestamp: "20240104", Problem: checkProblemInvalidTimestamp, Details: "not a timestamp"},
+		{Timestamp: "20240104", Problem: checkProblemInvalidStatus, Details: "Unknown"},
+		{Timestamp: "20240104", Problem: checkProblemInvalidBackupType, Details: "backup type does not match any of the available values"},
+	}
+	typeCounts, problems := checkMigrateBackups(backupConfigs)
+	if !reflect.DeepEqual(typeCounts, wantTypeCounts) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", typeCounts, wantTypeCounts)
+	}
+	if !reflect.DeepEqual(problems, wantProblems) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", problems, wantProblems)
+	}
+}
+
+func TestGetMigrateBackupNames(t *testing.T) {
+	testhelper.SetupTestLogger()
+	// The missing history database is not created.
+	historyDBPath := filepath.Join(t.TempDir(), "gpbackup_history.db")
+	backupNames, err := getMigrateBackupNames(historyDBPath)
+	if err != nil {
+		t.Fatalf("getMigrateBackupNames() error = %v", err)
+	}
+	if len(backupNames) != 0 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", backupNames, map[string]bool{})
+	}
+	if _, err := os.Stat(historyDBPath); !os.IsNotExist(err) {
+		t.Errorf("getMigrateBackupNames() created the history db, error = %v", err)
+	}
+	createTestHistoryDB(t, historyDBPath, []gpbckpconfig.BackupConfig{
+		{Timestamp: "20240101100000", EndTime: "20240101100010", Status: gpbckpconfig.BackupStatusSuccess},
+		{Timestamp: "20240102100000", EndTime: "20240102100010", Status: gpbckpconfig.BackupStatusFailure},
+	})
+	backupNames, err = getMigrateBackupNames(historyDBPath)
+	if err != nil {
+		t.Fatalf("getMigrateBackupNames() error = %v", err)
+	}
+	want := map[string]bool{"20240101100000": true, "20240102100000": true}
+	if !reflect.DeepEqual(backupNames, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", backupNames, want)
+	}
+}
+
+func TestMigrateHistorySnapshots(t *testing.T) {
+	testhelper.SetupTestLogger()
+	defer func(historyDB string, historyFiles []string, conflict string, dryRun bool, snapshots int, snapshotDir string) {
+		rootHistoryDB = historyDB
+		historyMigrateHistoryFiles = historyFiles
+		historyMigrateConflict = conflict
+		historyMigrateDryRun = dryRun
+		rootHistoryDBSnapshots = snapshots
+		rootHistoryDBSnapshotDir = snapshotDir
+	}(rootHistoryDB, historyMigrateHistoryFiles, historyMigrateConflict, historyMigrateDryRun, rootHistoryDBSnapshots, rootHistoryDBSnapshotDir)
+	existingBackup := gpbckpconfig.BackupConfig{Timestamp: "20240101100000", EndTime: "20240101100010", Status: gpbckpconfig.BackupStatusSuccess}
+	newBackup := gpbckpconfig.BackupConfig{Timestamp: "20240102100000", EndTime: "20240102100010", Status: gpbckpconfig.BackupStatusSuccess}
+	tests := []struct {
+		name          string
+		backupConfigs []gpbckpconfig.BackupConfig
+		wantNewSnap   bool
+	}{
+		{
+			name:          "Test nothing to migrate",
+			backupConfigs: []gpbckpconfig.BackupConfig{existingBackup},
+			wantNewSnap:   false,
+		},
+		{
+			name:          "Test new backup to migrate",
+			backupConfigs: []gpbckpconfig.BackupConfig{existingBackup, newBackup},
+			wantNewSnap:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			historyDBPath := filepath.Join(tempDir, "gpbackup_history.db")
+			hDB := createTestHistoryDB(t, historyDBPath, []gpbckpconfig.BackupConfig{existingBackup})
+			// The snapshot created before a previous change of the history database.
+			existing, err := gpbckpconfig.CreateHistoryDBSnapshot(hDB, historyDBPath, tempDir, "20240101000000")
+			if err != nil {
+				t.Fatalf("CreateHistoryDBSnapshot() error = %v", err)
+			}
+			historyFile := filepath.Join(tempDir, "gpbackup_history.yaml")
+			err = writeHistoryExportFile(historyFile, outputFormatYAML, gpbckpconfig.History{BackupConfigs: tt.backupConfigs})
+			if err != nil {
+				t.Fatalf("writeHistoryExportFile() error = %v", err)
+			}
+			rootHistoryDB = historyDBPath
+			historyMigrateHistoryFiles = []string{historyFile}
+			historyMigrateConflict = gpbckpconfig.MigrateStrategySkip
+			historyMigrateDryRun = false
+			// Only one snapshot is kept, so the new snapshot would remove the existing one.
+			rootHistoryDBSnapshots = 1
+			rootHistoryDBSnapshotDir = ""
+			if err := migrateHistory(); err != nil {
+				t.Fatalf("migrateHistory() error = %v", err)
+			}
+			snapshots, err := gpbckpconfig.GetHistoryDBSnapshots(historyDBPath, tempDir)
+			if err != nil {
+				t.Fatalf("GetHistoryDBSnapshots() error = %v", err)
+			}
+			if len(snapshots) != 1 {
+				t.Fatalf("\nVariables do not match:\n%v\nwant:\n%v", len(snapshots), 1)
+			}
+			if gotNewSnap := snapshots[0].ID != existing.ID; gotNewSnap != tt.wantNewSnap {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotNewSnap, tt.wantNewSnap)
+			}
+		})
+	}
+}
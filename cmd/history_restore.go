@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Flags for the gpbackman history-restore command (historyRestoreCmd)
+var (
+	historyRestoreSnapshot     string
+	historyRestoreOutputFormat string
+)
+
+var historyRestoreCmd = &cobra.Command{
+	Use:   "history-restore",
+	Short: "Restore the history database from the snapshot",
+	Long: `Restore the history database from the snapshot.
+
+The snapshot of the history database is created before each command that changes the history database
+(backup-clean, backup-delete, backup-repair, history-check with --fix option, history-clean and history-migrate).
+The snapshot is the consistent copy of the history database, created with VACUUM INTO.
+The snapshots are not created in the dry run mode.
+
+The snapshot file is created in the format <history db file name>.<id>.snapshot, where the id is the time of the snapshot creation
+in the format YYYYMMDDHHMMSS. If several snapshots are created in the same second, the number is added to the id.
+The directory for snapshots can be set using the --history-db-snapshot-dir option.
+If the --history-db-snapshot-dir option is not specified, the snapshots are created in the directory of the history database.
+The number of snapshots to keep can be set using the --history-db-snapshots option, the oldest snapshots are removed.
+To disable snapshots, set the --history-db-snapshots option to 0.
+
+By default, the available snapshots are displayed.
+The output format can be set using the --output option. Supported formats are table, json and yaml.
+
+To restore the history database, use the --snapshot option with the snapshot id.
+Before the restore, the snapshot of the current history database is created, so the restore could be rolled back.
+The history database is replaced using the SQLite backup API.
+
+The gpbackup_history.db file location can be set using the --history-db option.
+Can be specified only once. The full path to the file is required.
+If the --history-db option is not specified, the history database will be searched in the current directory.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		doRootFlagValidation(cmd.Flags(), checkFileExistsConst)
+		doHistoryRestoreFlagValidation(cmd.Flags())
+		doHistoryRestore()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyRestoreCmd)
+	historyRestoreCmd.PersistentFlags().StringVar(
+		&historyRestoreSnapshot,
+		snapshotFlagName,
+		"",
+		"the id of the snapshot to restore the history database from",
+	)
+	historyRestoreCmd.PersistentFlags().StringVar(
+		&historyRestoreOutputFormat,
+		outputFlagName,
+		outputFormatTable,
+		"output format (table, json, yaml)",
+	)
+}
+
+// These flag checks are applied only for history-restore command.
+func doHistoryRestoreFlagValidation(flags *pflag.FlagSet) {
+	var err error
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(historyRestoreOutputFormat, outputFormatTable, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyRestoreOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// output flag is used only for displaying snapshots.
+	if flags.Changed(snapshotFlagName) && flags.Changed(outputFlagName) {
+		gplog.Error("%s", textmsg.ErrorTextUnableCompatibleFlags(textmsg.ErrorIncompatibleFlagsError(), snapshotFlagName, outputFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If snapshot flag is specified, it is not empty.
+	if flags.Changed(snapshotFlagName) && historyRestoreSnapshot == "" {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(historyRestoreSnapshot, snapshotFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+}
+
+func doHistoryRestore() {
+	logHeadersDebug()
+	err := historyRestore()
+	if err != nil {
+		execOSExit(exitErrorCode)
+	}
+}
+
+func historyRestore() error {
+	historyDBPath := getHistoryDBPath(rootHistoryDB)
+	snapshotDir := gpbckpconfig.HistoryDBSnapshotDir(rootHistoryDBSnapshotDir, historyDBPath)
+	if historyRestoreSnapshot == "" {
+		return displayHistoryDBSnapshots(historyDBPath, snapshotDir, historyRestoreOutputFormat, os.Stdout)
+	}
+	unlock, err := lockHistoryDB(historyDBPath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	snapshot, err := gpbckpconfig.GetHistoryDBSnapshot(historyDBPath, snapshotDir, historyRestoreSnapshot)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBSnapshot("read", err))
+		return err
+	}
+	// The old snapshots are removed only after the restore,
+	// otherwise the snapshot to restore from could be removed.
+	if rootHistoryDBSnapshots > 0 {
+		err = createHistoryDBSnapshot(historyDBPath)
+		if err != nil {
+			return err
+		}
+	}
+	err = restoreHistoryDB(snapshot, historyDBPath)
+	if err != nil {
+		return err
+	}
+	if rootHistoryDBSnapshots > 0 {
+		return pruneHistoryDBSnapshots(historyDBPath)
+	}
+	return nil
+}
+
+func restoreHistoryDB(snapshot gpbckpconfig.HistoryDBSnapshot, historyDBPath string) error {
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyDBPath, getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	err = gpbckpconfig.RestoreHistoryDBSnapshot(snapshot.Path, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBSnapshot("restore", err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryDBSnapshotRestored(snapshot.ID))
+	return nil
+}
+
+func displayHistoryDBSnapshots(historyDBPath, snapshotDir, outputFormat string, w io.Writer) error {
+	snapshots, err := gpbckpconfig.GetHistoryDBSnapshots(historyDBPath, snapshotDir)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBSnapshot("read", err))
+		return err
+	}
+	err = printHistoryDBSnapshots(outputFormat, snapshots, w)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(outputFormat, err))
+		return err
+	}
+	return nil
+}
+
+// printHistoryDBSnapshots writes the snapshots to w in the specified format.
+func printHistoryDBSnapshots(outputFormat string, snapshots []gpbckpconfig.HistoryDBSnapshot, w io.Writer) error {
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		return writeStructuredOutput(w, outputFormat, snapshots)
+	default:
+		if len(snapshots) == 0 {
+			gplog.Info("%s", textmsg.InfoTextHistoryDBNoSnapshots())
+			return nil
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"id", "path", "size"})
+		for _, snapshot := range snapshots {
+			t.AppendRow(table.Row{snapshot.ID, snapshot.Path, snapshot.Size})
+		}
+		t.Render()
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+)
+
+func TestPrintHistoryDBSnapshots(t *testing.T) {
+	testhelper.SetupTestLogger()
+	snapshots := []gpbckpconfig.HistoryDBSnapshot{
+		{ID: "20240102100000", Path: "/data/gpbackup_history.db.20240102100000.snapshot", Size: 8192},
+		{ID: "20240101100000", Path: "/data/gpbackup_history.db.20240101100000.snapshot", Size: 4096},
+	}
+	tests := []struct {
+		name         string
+		outputFormat string
+		snapshots    []gpbckpconfig.HistoryDBSnapshot
+		want         string
+	}{
+		{
+			name:         "Test table format",
+			outputFormat: outputFormatTable,
+			snapshots:    snapshots,
+			want: " ID             | PATH                                              | SIZE \n" +
+				"----------------+---------------------------------------------------+------\n" +
+				" 20240102100000 | /data/gpbackup_history.db.20240102100000.snapshot | 8192 \n" +
+				" 20240101100000 | /data/gpbackup_history.db.20240101100000.snapshot | 4096 \n",
+		},
+		{
+			name:         "Test yaml format",
+			outputFormat: outputFormatYAML,
+			snapshots:    snapshots[:1],
+			want: `- id: "20240102100000"
+  path: /data/gpbackup_history.db.20240102100000.snapshot
+  size: 8192
+`,
+		},
+		{
+			name:         "Test empty table format",
+			outputFormat: outputFormatTable,
+			snapshots:    []gpbckpconfig.HistoryDBSnapshot{},
+			want:         "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := printHistoryDBSnapshots(tt.outputFormat, tt.snapshots, &buf); err != nil {
+				t.Fatalf("printHistoryDBSnapshots() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -2,21 +2,43 @@ package cmd
 
 import (
 	"database/sql"
+	"sync"
 
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"golang.org/x/crypto/ssh"
 )
 
+// segmentAction is an action that is performed for the backup directories of all segments on the host.
+// The configs and paths slices have the same length and contain only the segments of the host.
+// Each action sends at most one error to the errCh channel.
+type segmentAction func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error)
+
+// sshDialer establishes ssh connections to the segment hosts.
+type sshDialer interface {
+	dial(host string) (sshConnection, error)
+}
+
+// sshConnection is the ssh connection to the host.
+type sshConnection interface {
+	NewSession() (*ssh.Session, error)
+	Close() error
+}
+
 type backupDeleteInterface interface {
 	backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error
 }
 
+// backupPluginDeleter could be used for parallel deletions,
+// the history db operations are serialized by historyDBMutex.
 type backupPluginDeleter struct {
 	pluginConfigPath string
 	pluginConfig     *utils.PluginConfig
+	historyDBMutex   sync.Mutex
 }
 
 func (bpd *backupPluginDeleter) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
-	return backupDeleteDBPluginFunc(backupName, bpd.pluginConfigPath, bpd.pluginConfig, hDB, ignoreErrors)
+	return backupDeleteDBPluginFunc(backupName, bpd.pluginConfigPath, bpd.pluginConfig, hDB, ignoreErrors, &bpd.historyDBMutex)
 }
 
 type backupLocalDeleter struct {
@@ -27,3 +49,75 @@ type backupLocalDeleter struct {
 func (bld *backupLocalDeleter) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
 	return backupDeleteDBLocalFunc(backupName, bld.backupDir, bld.maxParallelProcesses, hDB, ignoreErrors)
 }
+
+// backupDryRunPlan keeps track of backups added to the deletion plan in dry run mode.
+// In dry run mode the history db is not changed, so the same backup could be processed several times,
+// for example, as a dependent backup and as a backup from the list for deletion.
+type backupDryRunPlan struct {
+	backupList []string
+}
+
+// addToPlan returns true, if the backup has been added to the plan,
+// and false, if the backup is already in the plan.
+func (bdp *backupDryRunPlan) addToPlan(backupName string) bool {
+	for _, backup := range bdp.backupList {
+		if backup == backupName {
+			return false
+		}
+	}
+	bdp.backupList = append(bdp.backupList, backupName)
+	return true
+}
+
+type backupPluginDryRunDeleter struct {
+	backupDryRunPlan
+	pluginConfigPath string
+	pluginConfig     *utils.PluginConfig
+}
+
+func (bpd *backupPluginDryRunDeleter) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
+	if !bpd.addToPlan(backupName) {
+		return nil
+	}
+	return backupDeleteDBPluginDryRunFunc(backupName, bpd.pluginConfigPath, bpd.pluginConfig, hDB, ignoreErrors)
+}
+
+type backupLocalDryRunDeleter struct {
+	backupDryRunPlan
+	backupDir string
+}
+
+func (bld *backupLocalDryRunDeleter) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
+	if !bld.addToPlan(backupName) {
+		return nil
+	}
+	return backupDeleteDBLocalDryRunFunc(backupName, bld.backupDir, hDB, ignoreErrors)
+}
+
+type backupRepairInterface interface {
+	backupStorageState(backupData gpbckpconfig.BackupConfig, hDB *sql.DB) (string, error)
+	backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error
+}
+
+// backupPluginRepairer finishes the deletion in the same way as backupPluginDeleter.
+type backupPluginRepairer struct {
+	backupPluginDeleter
+	reportFilePath string
+}
+
+func (bpr *backupPluginRepairer) backupStorageState(backupData gpbckpconfig.BackupConfig, hDB *sql.DB) (string, error) {
+	return backupStorageStatePlugin(backupData, bpr.reportFilePath, bpr.pluginConfigPath, bpr.pluginConfig)
+}
+
+type backupLocalRepairer struct {
+	backupDir            string
+	maxParallelProcesses int
+}
+
+func (blr *backupLocalRepairer) backupStorageState(backupData gpbckpconfig.BackupConfig, hDB *sql.DB) (string, error) {
+	return backupStorageStateLocal(backupData.Timestamp, blr.backupDir, blr.maxParallelProcesses, hDB)
+}
+
+func (blr *backupLocalRepairer) backupDeleteDB(backupName string, hDB *sql.DB, ignoreErrors bool) error {
+	return backupRepairDeleteLocalFunc(backupName, blr.backupDir, blr.maxParallelProcesses, hDB)
+}
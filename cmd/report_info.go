@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"sort"
+	"strconv"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/woblerr/gpbackman/gpbckpconfig"
@@ -21,8 +24,67 @@ var (
 	reportInfoPluginConfigFile     string
 	reportInfoReportFilePluginPath string
 	reportInfoBackupDir            string
+	reportInfoOutputFormat         string
+	reportInfoChain                bool
+	reportInfoSummary              bool
 )
 
+// reportInfoGetFunc Returns the content of the backup report.
+// If the report can't be displayed for the backup, the empty content and false are returned.
+type reportInfoGetFunc func(backupData gpbckpconfig.BackupConfig) (string, bool, error)
+
+// reportInfoChainRecord is the report of the backup from the incremental chain.
+type reportInfoChainRecord struct {
+	Timestamp    string                     `json:"timestamp" yaml:"timestamp"`
+	BackupType   string                     `json:"backup_type" yaml:"backup_type"`
+	BackupStatus string                     `json:"backup_status" yaml:"backup_status"`
+	ReportStatus string                     `json:"report_status" yaml:"report_status"`
+	Error        string                     `json:"error" yaml:"error"`
+	Report       *gpbckpconfig.BackupReport `json:"report,omitempty" yaml:"report,omitempty"`
+	content      string
+}
+
+// reportInfoChainSummary is the summary of the backup report from the incremental chain.
+type reportInfoChainSummary struct {
+	Timestamp         string  `json:"timestamp" yaml:"timestamp"`
+	BackupType        string  `json:"backup_type" yaml:"backup_type"`
+	BackupStatus      string  `json:"backup_status" yaml:"backup_status"`
+	ReportStatus      string  `json:"report_status" yaml:"report_status"`
+	Error             string  `json:"error" yaml:"error"`
+	StartTime         string  `json:"start_time" yaml:"start_time"`
+	EndTime           string  `json:"end_time" yaml:"end_time"`
+	Duration          float64 `json:"duration" yaml:"duration"`
+	DatabaseSize      string  `json:"database_size" yaml:"database_size"`
+	DatabaseSizeBytes int64   `json:"database_size_bytes" yaml:"database_size_bytes"`
+	SegmentCount      int     `json:"segment_count" yaml:"segment_count"`
+	TableCount        int     `json:"table_count" yaml:"table_count"`
+}
+
+func (record *reportInfoChainRecord) setError(err error) {
+	record.ReportStatus = reportStatusError
+	record.Error = err.Error()
+}
+
+func (record reportInfoChainRecord) summary() reportInfoChainSummary {
+	s := reportInfoChainSummary{
+		Timestamp:    record.Timestamp,
+		BackupType:   record.BackupType,
+		BackupStatus: record.BackupStatus,
+		ReportStatus: record.ReportStatus,
+		Error:        record.Error,
+	}
+	if record.Report != nil {
+		s.StartTime = record.Report.StartTime
+		s.EndTime = record.Report.EndTime
+		s.Duration = record.Report.Duration
+		s.DatabaseSize = record.Report.DatabaseSize
+		s.DatabaseSizeBytes = record.Report.DatabaseSizeBytes
+		s.SegmentCount = record.Report.SegmentCount
+		s.TableCount = record.Report.TableCount
+	}
+	return s
+}
+
 var reportInfoCmd = &cobra.Command{
 	Use:   "report-info",
 	Short: "Display the report for a specific backup",
@@ -53,7 +115,29 @@ Only --backup-dir or --plugin-config option can be specified, not both.
 
 If a custom plugin is used, it is required to specify the path to the directory with the repo file using the --plugin-report-file-path option.
 It is not necessary to use the --plugin-report-file-path flag for the following plugins (the path is generated automatically):
-  * gpbackup_s3_plugin.
+  * gpbackup_s3_plugin, gpbackup_gcs_plugin, gpbackup_azure_plugin - <folder>/backups/<YYYYMMDD>/<YYYYMMDDHHMMSS>;
+  * gpbackup_ddboost_plugin - <directory>/backups/<YYYYMMDD>/<YYYYMMDDHHMMSS>;
+  * example_plugin - /tmp/plugin_dest/<YYYYMMDD>/<YYYYMMDDHHMMSS>.
+For other plugins, the path to the directory with the report file could be set in the plugin config file
+using the gpbackman_report_path_template option, e.g. "{folder}/backups/{date}/{timestamp}".
+The {date} and {timestamp} placeholders are replaced with the backup date and timestamp,
+other placeholders are replaced with the values of the plugin options with the same names.
+The template is used for all plugins, if it is set.
+
+To change the output format, use the --output option. The following formats are supported:
+  * text - the report as it was written by gpbackup (default);
+  * json - the parsed report as JSON object;
+  * yaml - the parsed report as YAML document.
+The parsed report contains the report fields, the gpbackup flags from the command line,
+the start and end times in the timestamp format, the duration in seconds,
+the database size in bytes, the segment count and the counts of database objects.
+
+To display the reports of all backups from the incremental chain, use the --chain option:
+  * for the incremental backup, the chain contains the base full backup and all incremental backups up to the specified backup from the restore plan;
+  * for the full backup, the chain contains the specified backup and all incremental backups that depend on it.
+The reports are displayed sequentially in the restore order. For the json and yaml formats, the list of the parsed reports is displayed.
+To display the merged summary of the reports with the status of each backup, use the --summary option together with the --chain option.
+If the report of any backup from the chain can't be displayed, the other reports are displayed and the error is returned.
 
 The gpbackup_history.db file location can be set using the --history-db option.
 Can be specified only once. The full path to the file is required.
@@ -92,6 +176,24 @@ func init() {
 		"",
 		"the full path to backup directory",
 	)
+	reportInfoCmd.PersistentFlags().StringVar(
+		&reportInfoOutputFormat,
+		outputFlagName,
+		outputFormatText,
+		"output format (text, json, yaml)",
+	)
+	reportInfoCmd.PersistentFlags().BoolVar(
+		&reportInfoChain,
+		chainFlagName,
+		false,
+		"display the reports of all backups from the incremental chain",
+	)
+	reportInfoCmd.PersistentFlags().BoolVar(
+		&reportInfoSummary,
+		summaryFlagName,
+		false,
+		"display the summary of the reports from the incremental chain",
+	)
 	_ = reportInfoCmd.MarkPersistentFlagRequired(timestampFlagName)
 }
 
@@ -142,6 +244,19 @@ func doReportInfoFlagValidation(flags *pflag.FlagSet) {
 			execOSExit(exitErrorCode)
 		}
 	}
+	// If summary flag is specified, but chain flag is not specified.
+	if flags.Changed(summaryFlagName) && !flags.Changed(chainFlagName) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateValue(textmsg.ErrorNotIndependentFlagsError(), summaryFlagName, chainFlagName))
+		execOSExit(exitErrorCode)
+	}
+	// If output flag is specified and have correct values.
+	if flags.Changed(outputFlagName) {
+		err = checkOutputFormat(reportInfoOutputFormat, outputFormatText, outputFormatJSON, outputFormatYAML)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(reportInfoOutputFormat, outputFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
 }
 
 func doReportInfo() {
@@ -153,7 +268,7 @@ func doReportInfo() {
 }
 
 func reportInfo() error {
-	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB))
+	hDB, err := gpbckpconfig.OpenHistoryDB(getHistoryDBPath(rootHistoryDB), getHistoryDBOptions())
 	if err != nil {
 		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
 		return err
@@ -170,11 +285,21 @@ func reportInfo() error {
 			gplog.Error("%s", textmsg.ErrorTextUnableReadPluginConfigFile(err))
 			return err
 		}
+		if reportInfoChain {
+			return reportInfoChainDB(reportInfoTimestamp, func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+				return getReportPlugin(backupData, reportInfoPluginConfigFile, pluginConfig)
+			}, hDB)
+		}
 		err = reportInfoDBPlugin(reportInfoTimestamp, reportInfoPluginConfigFile, pluginConfig, hDB)
 		if err != nil {
 			return err
 		}
 	} else {
+		if reportInfoChain {
+			return reportInfoChainDB(reportInfoTimestamp, func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+				return getReportLocal(backupData, reportInfoBackupDir)
+			}, hDB)
+		}
 		err := reportInfoDBLocal(reportInfoTimestamp, reportInfoBackupDir, hDB)
 		if err != nil {
 			return err
@@ -197,32 +322,26 @@ func reportInfoDBPlugin(backupName, pluginConfigPath string, pluginConfig *utils
 }
 
 func reportInfoPluginFunc(backupData gpbckpconfig.BackupConfig, pluginConfigPath string, pluginConfig *utils.PluginConfig) error {
-	// Skip local backup.
-	canGetReport, err := checkBackupCanBeUsed(false, true, backupData)
+	content, canGetReport, err := getReportPlugin(backupData, pluginConfigPath, pluginConfig)
 	if err != nil {
 		return err
 	}
 	if canGetReport {
-		reportFile, err := backupData.GetReportFilePathPlugin(reportInfoReportFilePluginPath, pluginConfig.Options)
-		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("report", backupData.Timestamp, err))
-			return err
-		}
-			gplog.Debug("%s", textmsg.InfoTextCommandExecution(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, reportFile))
-		stdout, stderr, err := execReportInfo(pluginConfig.ExecutablePath, restoreDataPluginCommand, pluginConfigPath, reportFile)
-		if stderr != "" {
-			gplog.Error("%s", stderr)
-		}
+		// Display the report.
+		err = printReportInfo(reportInfoOutputFormat, backupData.Timestamp, content, os.Stdout)
 		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupReport(backupData.Timestamp, err))
 			return err
 		}
-		// Display the report.
-		fmt.Println(stdout)
 	}
 	return nil
 }
 
+// getReportPlugin Returns the content of the backup report from the plugin storage.
+// If the report can't be displayed for the backup, the empty content and false are returned.
+func getReportPlugin(backupData gpbckpconfig.BackupConfig, pluginConfigPath string, pluginConfig *utils.PluginConfig) (string, bool, error) {
+	return getBackupFilePlugin(backupData, gpbckpconfig.ReportFileName(backupData.Timestamp), reportInfoReportFilePluginPath, pluginConfigPath, pluginConfig)
+}
+
 func reportInfoDBLocal(backupName, backupDir string, hDB *sql.DB) error {
 	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
 	if err != nil {
@@ -237,30 +356,201 @@ func reportInfoDBLocal(backupName, backupDir string, hDB *sql.DB) error {
 }
 
 func reportInfoFileLocalFunc(backupData gpbckpconfig.BackupConfig, backupDir string) error {
-	// Include local backup.
-	canGetReport, err := checkBackupCanBeUsed(false, false, backupData)
+	content, canGetReport, err := getReportLocal(backupData, backupDir)
 	if err != nil {
 		return err
 	}
 	if canGetReport {
-		timestamp := backupData.Timestamp
-		bckpDir, segPrefix, _, err := getBackupMasterDir(backupDir, backupData.BackupDir, backupData.DatabaseName)
+		err = printReportInfo(reportInfoOutputFormat, backupData.Timestamp, content, os.Stdout)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getReportLocal Returns the content of the backup report from the local storage.
+// If the report can't be displayed for the backup, the empty content and false are returned.
+func getReportLocal(backupData gpbckpconfig.BackupConfig, backupDir string) (string, bool, error) {
+	return getBackupFileLocal(backupData, gpbckpconfig.ReportFileName(backupData.Timestamp), backupDir)
+}
+
+// reportInfoChainDB Displays the reports of all backups from the incremental chain of the backup.
+// If the report of any backup from the chain can't be displayed, the error is returned after displaying the other reports.
+func reportInfoChainDB(backupName string, getReport reportInfoGetFunc, hDB *sql.DB) error {
+	backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+		return err
+	}
+	chain, err := getReportInfoChain(backupData, hDB)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("dependencies", backupName, err))
+		return err
+	}
+	gplog.Debug("%s", textmsg.InfoTextBackupChainList(backupName, chain))
+	records := getReportInfoChainRecords(chain, getReport, hDB)
+	if reportInfoSummary || reportInfoOutputFormat != outputFormatText {
+		parseReportInfoChainRecords(records)
+	}
+	err = printReportInfoChain(reportInfoOutputFormat, reportInfoSummary, records, os.Stdout)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(reportInfoOutputFormat, err))
+		return err
+	}
+	var failed int
+	for _, record := range records {
+		if record.ReportStatus != reportStatusOK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return textmsg.ErrorBackupReportsUnavailableError(strconv.Itoa(failed))
+	}
+	return nil
+}
+
+// getReportInfoChain Returns the timestamps of the backups from the incremental chain of the backup
+// in the restore order.
+// For the incremental backup, the chain is the restore plan of the backup:
+// the base full backup and all incremental backups up to the backup.
+// For the full backup, the chain is the backup and all incremental backups that depend on it.
+func getReportInfoChain(backupData gpbckpconfig.BackupConfig, hDB *sql.DB) ([]string, error) {
+	chain := make([]string, 0, len(backupData.RestorePlan))
+	inChain := make(map[string]bool)
+	for _, entry := range backupData.RestorePlan {
+		if !inChain[entry.Timestamp] {
+			chain = append(chain, entry.Timestamp)
+			inChain[entry.Timestamp] = true
+		}
+	}
+	if !inChain[backupData.Timestamp] {
+		chain = append(chain, backupData.Timestamp)
+		inChain[backupData.Timestamp] = true
+	}
+	if !backupData.Incremental {
+		dependencies, err := gpbckpconfig.GetBackupDependencies(backupData.Timestamp, hDB)
+		if err != nil {
+			return nil, err
+		}
+		for _, dependency := range dependencies {
+			if !inChain[dependency] {
+				chain = append(chain, dependency)
+				inChain[dependency] = true
+			}
+		}
+	}
+	sort.Strings(chain)
+	return chain, nil
+}
+
+// getReportInfoChainRecords Returns the reports of the backups from the chain.
+// The errors are not returned, but saved in the records.
+func getReportInfoChainRecords(chain []string, getReport reportInfoGetFunc, hDB *sql.DB) []reportInfoChainRecord {
+	records := make([]reportInfoChainRecord, 0, len(chain))
+	for _, backupName := range chain {
+		record := reportInfoChainRecord{Timestamp: backupName}
+		backupData, err := gpbckpconfig.GetBackupDataDB(backupName, hDB)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupInfo(backupName, err))
+			record.setError(err)
+			records = append(records, record)
+			continue
+		}
+		record.BackupStatus = backupData.Status
+		record.BackupType, err = backupData.GetBackupType()
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupValue("type", backupName, err))
+		}
+		content, canGetReport, err := getReport(backupData)
+		switch {
+		case err != nil:
+			record.setError(err)
+		case !canGetReport:
+			record.ReportStatus = reportStatusUnavailable
+			record.Error = textmsg.ErrorBackupNotActiveError().Error()
+		default:
+			record.ReportStatus = reportStatusOK
+			record.content = content
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// parseReportInfoChainRecords Parses the reports of the backups from the chain.
+// If the report can't be parsed, the error is saved in the record.
+func parseReportInfoChainRecords(records []reportInfoChainRecord) {
+	for idx := range records {
+		if records[idx].ReportStatus != reportStatusOK {
+			continue
+		}
+		report, err := gpbckpconfig.ParseBackupReport(records[idx].content)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupReport(records[idx].Timestamp, err))
+			records[idx].setError(err)
+			continue
+		}
+		records[idx].Report = &report
+	}
+}
+
+// printReportInfoChain Displays the reports of the backups from the chain.
+// The reports are displayed sequentially or as a summary with the status of each backup.
+func printReportInfoChain(outputFormat string, summary bool, records []reportInfoChainRecord, w io.Writer) error {
+	if summary {
+		summaryRecords := make([]reportInfoChainSummary, 0, len(records))
+		for _, record := range records {
+			summaryRecords = append(summaryRecords, record.summary())
+		}
+		if outputFormat == outputFormatJSON || outputFormat == outputFormatYAML {
+			return writeStructuredOutput(w, outputFormat, summaryRecords)
+		}
+		t := table.NewWriter()
+		t.SetOutputMirror(w)
+		t.SetStyle(table.StyleDefault)
+		t.Style().Options.DrawBorder = false
+		t.AppendHeader(table.Row{"timestamp", "type", "backup status", "report status", "start time", "duration", "database size", "tables", "error"})
+		for _, s := range summaryRecords {
+			// The report values are displayed only for the parsed reports.
+			var duration, tableCount string
+			if s.ReportStatus == reportStatusOK {
+				duration = formatBackupDuration(s.Duration)
+				tableCount = strconv.Itoa(s.TableCount)
+			}
+			t.AppendRow(table.Row{s.Timestamp, s.BackupType, s.BackupStatus, s.ReportStatus, s.StartTime, duration, s.DatabaseSize, tableCount, s.Error})
+		}
+		t.Render()
+		return nil
+	}
+	if outputFormat == outputFormatJSON || outputFormat == outputFormatYAML {
+		return writeStructuredOutput(w, outputFormat, records)
+	}
+	for _, record := range records {
+		if record.ReportStatus == reportStatusOK {
+			fmt.Fprintln(w, record.content)
+		}
+	}
+	return nil
+}
+
+// printReportInfo Displays the report in the specified format.
+// For the json and yaml formats, the report is parsed.
+func printReportInfo(outputFormat, backupName, content string, w io.Writer) error {
+	switch outputFormat {
+	case outputFormatJSON, outputFormatYAML:
+		report, err := gpbckpconfig.ParseBackupReport(content)
 		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupPath("backup directory", timestamp, err))
+			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupReport(backupName, err))
 			return err
 		}
-		gplog.Debug("%s", textmsg.InfoTextBackupDirPath(bckpDir))
-		gplog.Debug("%s", textmsg.InfoTextSegmentPrefix(segPrefix))
-		reportFile := gpbckpconfig.ReportFilePath(bckpDir, timestamp)
-		// Sanitize the file path
-		reportFile = filepath.Clean(reportFile)
-		gplog.Debug("%s", textmsg.InfoTextCommandExecution("read file", reportFile))
-		content, err := os.ReadFile(reportFile)
+		err = writeStructuredOutput(w, outputFormat, report)
 		if err != nil {
-			gplog.Error("%s", textmsg.ErrorTextUnableGetBackupReport(backupData.Timestamp, err))
+			gplog.Error("%s", textmsg.ErrorTextUnableDisplayOutput(outputFormat, err))
 			return err
 		}
-		fmt.Println(string(content))
+	default:
+		fmt.Fprintln(w, content)
 	}
 	return nil
 }
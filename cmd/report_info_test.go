@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"github.com/woblerr/gpbackman/textmsg"
+	"gopkg.in/yaml.v2"
+)
+
+func TestPrintReportInfo(t *testing.T) {
+	testhelper.SetupTestLogger()
+	content := `Greenplum Database Backup Report
+
+timestamp key:         20240101100000
+command line:          gpbackup --dbname demo --single-data-file
+backup status:         Success
+segment count:         2
+
+count of database objects in backup:
+tables                 5
+`
+	want := gpbckpconfig.BackupReport{
+		Timestamp:            "20240101100000",
+		CommandLine:          "gpbackup --dbname demo --single-data-file",
+		Flags:                []gpbckpconfig.BackupReportFlag{{Name: "dbname", Value: "demo"}, {Name: "single-data-file"}},
+		IncrementalBackupSet: []string{},
+		Status:               gpbckpconfig.BackupStatusSuccess,
+		SegmentCount:         2,
+		TableCount:           5,
+		ObjectCounts:         map[string]int{"tables": 5},
+	}
+	var buf bytes.Buffer
+	if err := printReportInfo(outputFormatText, "20240101100000", content, &buf); err != nil {
+		t.Fatalf("printReportInfo() error: %v", err)
+	}
+	if buf.String() != content+"\n" {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), content)
+	}
+	buf.Reset()
+	if err := printReportInfo(outputFormatJSON, "20240101100000", content, &buf); err != nil {
+		t.Fatalf("printReportInfo() error: %v", err)
+	}
+	var got gpbckpconfig.BackupReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printReportInfo() invalid json: %v", err)
+	}
+	if got.TableCount != want.TableCount || got.SegmentCount != want.SegmentCount || len(got.Flags) != len(want.Flags) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	buf.Reset()
+	if err := printReportInfo(outputFormatYAML, "20240101100000", content, &buf); err != nil {
+		t.Fatalf("printReportInfo() error: %v", err)
+	}
+	got = gpbckpconfig.BackupReport{}
+	if err := yaml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printReportInfo() invalid yaml: %v", err)
+	}
+	if got.Timestamp != want.Timestamp || got.ObjectCounts["tables"] != 5 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	// The content, that is not a report, can't be parsed.
+	if err := printReportInfo(outputFormatJSON, "20240101100000", "unexpected output", &buf); err == nil {
+		t.Errorf("printReportInfo() error = %v, want error", err)
+	}
+}
+
+func TestGetReportInfoChain(t *testing.T) {
+	testhelper.SetupTestLogger()
+	fullBackup := gpbckpconfig.BackupConfig{
+		Timestamp:   "20240101100000",
+		EndTime:     "20240101100010",
+		Status:      gpbckpconfig.BackupStatusSuccess,
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{{Timestamp: "20240101100000"}},
+	}
+	incrBackup1 := gpbckpconfig.BackupConfig{
+		Timestamp:   "20240102100000",
+		EndTime:     "20240102100010",
+		Status:      gpbckpconfig.BackupStatusSuccess,
+		Incremental: true,
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{{Timestamp: "20240101100000"}, {Timestamp: "20240102100000"}},
+	}
+	incrBackup2 := gpbckpconfig.BackupConfig{
+		Timestamp:   "20240103100000",
+		EndTime:     "20240103100010",
+		Status:      gpbckpconfig.BackupStatusSuccess,
+		Incremental: true,
+		RestorePlan: []gpbckpconfig.RestorePlanEntry{{Timestamp: "20240101100000"}, {Timestamp: "20240102100000"}, {Timestamp: "20240103100000"}},
+	}
+	otherBackup := gpbckpconfig.BackupConfig{
+		Timestamp: "20240104100000",
+		EndTime:   "20240104100010",
+		Status:    gpbckpconfig.BackupStatusSuccess,
+	}
+	hDB := createTestHistoryDB(t, filepath.Join(t.TempDir(), "gpbackup_history.db"), []gpbckpconfig.BackupConfig{fullBackup, incrBackup1, incrBackup2, otherBackup})
+	tests := []struct {
+		name   string
+		backup gpbckpconfig.BackupConfig
+		want   []string
+	}{
+		{
+			name:   "Test incremental backup",
+			backup: incrBackup1,
+			want:   []string{"20240101100000", "20240102100000"},
+		},
+		{
+			name:   "Test full backup",
+			backup: fullBackup,
+			want:   []string{"20240101100000", "20240102100000", "20240103100000"},
+		},
+		{
+			name:   "Test backup without restore plan",
+			backup: otherBackup,
+			want:   []string{"20240104100000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getReportInfoChain(tt.backup, hDB)
+			if err != nil {
+				t.Fatalf("getReportInfoChain() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetReportInfoChainRecords(t *testing.T) {
+	testhelper.SetupTestLogger()
+	hDB := createTestHistoryDB(t, filepath.Join(t.TempDir(), "gpbackup_history.db"), []gpbckpconfig.BackupConfig{
+		{Timestamp: "20240101100000", EndTime: "20240101100010", Status: gpbckpconfig.BackupStatusSuccess},
+		{Timestamp: "20240102100000", EndTime: "20240102100010", Status: gpbckpconfig.BackupStatusSuccess, Incremental: true, DateDeleted: "20240105100000"},
+		{Timestamp: "20240103100000", EndTime: "20240103100010", Status: gpbckpconfig.BackupStatusSuccess, Incremental: true},
+	})
+	getReport := func(backupData gpbckpconfig.BackupConfig) (string, bool, error) {
+		switch backupData.Timestamp {
+		case "20240102100000":
+			return "", false, nil
+		case "20240103100000":
+			return "", true, textmsg.ErrorFileNotExist()
+		}
+		return "Greenplum Database Backup Report\n\ntimestamp key: " + backupData.Timestamp + "\nsegment count: 2\n", true, nil
+	}
+	records := getReportInfoChainRecords([]string{"20240101100000", "20240102100000", "20240103100000", "20240104100000"}, getReport, hDB)
+	parseReportInfoChainRecords(records)
+	got := make([][2]string, 0, len(records))
+	for _, record := range records {
+		got = append(got, [2]string{record.Timestamp, record.ReportStatus})
+	}
+	want := [][2]string{
+		{"20240101100000", reportStatusOK},
+		{"20240102100000", reportStatusUnavailable},
+		{"20240103100000", reportStatusError},
+		{"20240104100000", reportStatusError},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	if records[0].Report == nil || records[0].Report.SegmentCount != 2 || records[0].BackupType != gpbckpconfig.BackupTypeFull {
+		t.Errorf("\nVariables do not match:\n%v\nwant the parsed report", records[0])
+	}
+	if records[2].Error != textmsg.ErrorFileNotExist().Error() {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", records[2].Error, textmsg.ErrorFileNotExist())
+	}
+}
+
+func TestPrintReportInfoChain(t *testing.T) {
+	records := []reportInfoChainRecord{
+		{
+			Timestamp:    "20240101100000",
+			BackupType:   gpbckpconfig.BackupTypeFull,
+			BackupStatus: gpbckpconfig.BackupStatusSuccess,
+			ReportStatus: reportStatusOK,
+			Report:       &gpbckpconfig.BackupReport{Timestamp: "20240101100000", Duration: 3723, TableCount: 12},
+			content:      "report 20240101100000",
+		},
+		{
+			Timestamp:    "20240102100000",
+			BackupType:   gpbckpconfig.BackupTypeIncremental,
+			BackupStatus: gpbckpconfig.BackupStatusSuccess,
+			ReportStatus: reportStatusUnavailable,
+			Error:        textmsg.ErrorBackupNotActiveError().Error(),
+		},
+	}
+	var buf bytes.Buffer
+	if err := printReportInfoChain(outputFormatText, false, records, &buf); err != nil {
+		t.Fatalf("printReportInfoChain() error: %v", err)
+	}
+	if buf.String() != "report 20240101100000\n" {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), "report 20240101100000\n")
+	}
+	buf.Reset()
+	if err := printReportInfoChain(outputFormatText, true, records, &buf); err != nil {
+		t.Fatalf("printReportInfoChain() error: %v", err)
+	}
+	for _, want := range []string{"REPORT STATUS", "01:02:03", reportStatusUnavailable, textmsg.ErrorBackupNotActiveError().Error()} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", buf.String(), want)
+		}
+	}
+	buf.Reset()
+	if err := printReportInfoChain(outputFormatJSON, true, records, &buf); err != nil {
+		t.Fatalf("printReportInfoChain() error: %v", err)
+	}
+	var got []reportInfoChainSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("printReportInfoChain() invalid json: %v", err)
+	}
+	want := []reportInfoChainSummary{records[0].summary(), records[1].summary()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
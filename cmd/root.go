@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/spf13/cobra"
@@ -16,6 +17,20 @@ var (
 	rootLogFile         string
 	rootLogLevelConsole string
 	rootLogLevelFile    string
+	// History db flags are used by all commands, that work with the history database.
+	rootHistoryDBBusyTimeout int
+	rootHistoryDBRetries     int
+	rootHistoryDBSnapshots   int
+	rootHistoryDBSnapshotDir string
+	// SSH flags are used by the commands, that work with local backups on segment hosts.
+	rootSSHUser           string
+	rootSSHKeyFiles       []string
+	rootSSHAgent          bool
+	rootSSHPort           int
+	rootSSHTimeout        int
+	rootSSHKnownHostsFile string
+	rootSSHJumpHost       string
+	rootSSHForwardAgent   bool
 )
 
 var rootCmd = &cobra.Command{
@@ -31,6 +46,30 @@ func init() {
 		"",
 		"full path to the gpbackup_history.db file",
 	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootHistoryDBBusyTimeout,
+		historyDBBusyTimeoutFlagName,
+		10,
+		"the time in seconds to wait for the history database lock held by another process",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootHistoryDBRetries,
+		historyDBRetriesFlagName,
+		3,
+		"the number of retries with exponential backoff, if the history database is still locked after the busy timeout",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootHistoryDBSnapshots,
+		historyDBSnapshotsFlagName,
+		5,
+		"the number of history database snapshots to keep, the snapshot is created before each change of the history database, 0 disables snapshots",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootHistoryDBSnapshotDir,
+		historyDBSnapshotDirFlagName,
+		"",
+		"the full path to the directory for history database snapshots, if not specified, the directory of the history database is used",
+	)
 	rootCmd.PersistentFlags().StringVar(
 		&rootLogFile,
 		logFileFlagName,
@@ -49,6 +88,54 @@ func init() {
 		"info",
 		"level for file logging (error, info, debug, verbose)",
 	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootSSHUser,
+		sshUserFlagName,
+		"",
+		"the user for ssh connections to segment hosts, if not specified, the current OS user is used",
+	)
+	rootCmd.PersistentFlags().StringArrayVar(
+		&rootSSHKeyFiles,
+		sshKeyFlagName,
+		[]string{},
+		"the full path to ssh private key file, could be specified multiple times, if not specified, the default key files from the ~/.ssh directory are used",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&rootSSHAgent,
+		sshAgentFlagName,
+		false,
+		"use ssh agent from the SSH_AUTH_SOCK environment variable for ssh authentication",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootSSHPort,
+		sshPortFlagName,
+		22,
+		"the port for ssh connections to segment hosts",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootSSHTimeout,
+		sshTimeoutFlagName,
+		30,
+		"the timeout in seconds for establishing ssh connections",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootSSHKnownHostsFile,
+		sshKnownHostsFlagName,
+		"",
+		"the full path to known_hosts file for host keys verification, if not specified, host keys are not verified",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&rootSSHForwardAgent,
+		sshForwardAgentFlagName,
+		false,
+		"forward ssh agent from the SSH_AUTH_SOCK environment variable to segment hosts",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootSSHJumpHost,
+		sshJumpHostFlagName,
+		"",
+		"the jump host in [user@]host[:port] format for ssh connections to segment hosts, if the user is not specified, the ssh user is used, if the port is not specified, the port 22 is used",
+	)
 }
 
 func doInit(version string) {
@@ -78,6 +165,28 @@ func doRootFlagValidation(flags *pflag.FlagSet, checkFileExists bool) {
 			execOSExit(exitErrorCode)
 		}
 	}
+	// Check, that the history db busy timeout and retries are correct.
+	if !gpbckpconfig.IsPositiveValue(rootHistoryDBBusyTimeout) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(rootHistoryDBBusyTimeout), historyDBBusyTimeoutFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+	if rootHistoryDBRetries < 0 {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(rootHistoryDBRetries), historyDBRetriesFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+	// Check, that the number of snapshots is correct.
+	if rootHistoryDBSnapshots < 0 {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(rootHistoryDBSnapshots), historyDBSnapshotsFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+	// If history-db-snapshot-dir flag is specified, the directory exists and the full path is specified.
+	if flags.Changed(historyDBSnapshotDirFlagName) {
+		err = gpbckpconfig.CheckFullPath(rootHistoryDBSnapshotDir, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(rootHistoryDBSnapshotDir, historyDBSnapshotDirFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
 	// Check, that the log level is correct.
 	err = setLogLevelConsole(rootLogLevelConsole)
 	if err != nil {
@@ -89,6 +198,40 @@ func doRootFlagValidation(flags *pflag.FlagSet, checkFileExists bool) {
 		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(rootLogLevelFile, logLevelFileFlagName, err))
 		execOSExit(exitErrorCode)
 	}
+	// If ssh-key flags are specified, the files exist and the full paths are specified.
+	for _, keyFile := range rootSSHKeyFiles {
+		err = gpbckpconfig.CheckFullPath(keyFile, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(keyFile, sshKeyFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// If ssh-known-hosts flag is specified, the file exists and the full path is specified.
+	if flags.Changed(sshKnownHostsFlagName) {
+		err = gpbckpconfig.CheckFullPath(rootSSHKnownHostsFile, checkFileExistsConst)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(rootSSHKnownHostsFile, sshKnownHostsFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// Check, that the ssh port is correct.
+	if !gpbckpconfig.IsPositiveValue(rootSSHPort) || rootSSHPort > maxPortValue {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(rootSSHPort), sshPortFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
+	// If ssh-jump-host flag is specified, it's in the [user@]host[:port] format.
+	if flags.Changed(sshJumpHostFlagName) {
+		_, _, err = parseSSHJumpHost(rootSSHJumpHost)
+		if err != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(rootSSHJumpHost, sshJumpHostFlagName, err))
+			execOSExit(exitErrorCode)
+		}
+	}
+	// Check, that the ssh timeout is correct.
+	if !gpbckpconfig.IsPositiveValue(rootSSHTimeout) {
+		gplog.Error("%s", textmsg.ErrorTextUnableValidateFlag(strconv.Itoa(rootSSHTimeout), sshTimeoutFlagName, textmsg.ErrorInvalidValueError()))
+		execOSExit(exitErrorCode)
+	}
 }
 
 func Execute(version string) {
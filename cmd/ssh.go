@@ -0,0 +1,472 @@
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/woblerr/gpbackman/textmsg"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sshConnections is the pool of ssh connections, that is shared by all operations of the command.
+// It's created on the first call of getSSHDialer and closed by closeSSHConnections.
+var sshConnections *sshConnectionPool
+
+// sshAgentConn is the connection to the ssh agent, that is shared by all ssh connections of the command.
+// It's opened on the first call of getSSHAgentClient and closed by closeSSHConnections.
+var (
+	sshAgentConn   net.Conn
+	sshAgentClient agent.ExtendedAgent
+)
+
+// Default private key files, that are used if no key files and no ssh agent are specified.
+var sshDefaultKeyFiles = []string{"id_rsa", "id_ecdsa", "id_ed25519"}
+
+// sshOptions contains the options for ssh connections to the segment hosts.
+type sshOptions struct {
+	user           string
+	keyFiles       []string
+	useAgent       bool
+	port           int
+	timeout        time.Duration
+	knownHostsFile string
+	jumpHost       string
+	forwardAgent   bool
+}
+
+// The default port of the jump host, if the port is not specified in the jump host.
+const sshJumpHostDefaultPort = 22
+
+// sshTransport establishes ssh connections to the hosts,
+// directly or through the jump host.
+// The jump host has its own address and client configuration,
+// they differ from the hosts in the user and the port.
+// If the agent client is set, the ssh agent is forwarded to the hosts.
+type sshTransport struct {
+	config      *ssh.ClientConfig
+	port        int
+	jumpAddress string
+	jumpConfig  *ssh.ClientConfig
+	agentClient agent.ExtendedAgent
+}
+
+// sshJumpClient is the connection to the host through the jump host.
+// Closing the connection also closes the connection to the jump host.
+type sshJumpClient struct {
+	*ssh.Client
+	jumpClient *ssh.Client
+}
+
+func (c *sshJumpClient) Close() error {
+	err := c.Client.Close()
+	jumpErr := c.jumpClient.Close()
+	if err != nil {
+		return err
+	}
+	return jumpErr
+}
+
+func (t *sshTransport) dial(host string) (sshConnection, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(t.port))
+	if t.jumpAddress == "" {
+		client, err := ssh.Dial("tcp", address, t.config)
+		if err != nil {
+			return nil, err
+		}
+		return t.forwardAgent(client, client)
+	}
+	jumpClient, err := ssh.Dial("tcp", t.jumpAddress, t.jumpConfig)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := jumpClient.Dial("tcp", address)
+	if err != nil {
+		jumpClient.Close()
+		return nil, err
+	}
+	clientConn, chans, reqs, err := ssh.NewClientConn(conn, address, t.config)
+	if err != nil {
+		conn.Close()
+		jumpClient.Close()
+		return nil, err
+	}
+	client := &sshJumpClient{
+		Client:     ssh.NewClient(clientConn, chans, reqs),
+		jumpClient: jumpClient,
+	}
+	return t.forwardAgent(client, client.Client)
+}
+
+// forwardAgent Returns the connection, that forwards the ssh agent to the host for each session.
+// If the agent is not forwarded, the connection is returned as is.
+func (t *sshTransport) forwardAgent(conn sshConnection, client *ssh.Client) (sshConnection, error) {
+	if t.agentClient == nil {
+		return conn, nil
+	}
+	err := agent.ForwardToAgent(client, t.agentClient)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sshAgentForwardingClient{sshConnection: conn}, nil
+}
+
+// sshAgentForwardingClient is the connection, that requests the ssh agent forwarding for each new session.
+type sshAgentForwardingClient struct {
+	sshConnection
+}
+
+func (c *sshAgentForwardingClient) NewSession() (*ssh.Session, error) {
+	session, err := c.sshConnection.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	err = agent.RequestAgentForwarding(session)
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	return session, nil
+}
+
+// sshConnectionPool keeps one ssh connection per host.
+// The operations open new sessions on the existing connection.
+// If the session can't be opened, the connection is discarded and established again on the next dial.
+type sshConnectionPool struct {
+	dialer sshDialer
+	mu     sync.Mutex
+	hosts  map[string]*sshPoolHost
+}
+
+type sshPoolHost struct {
+	mu   sync.Mutex
+	conn sshConnection
+}
+
+// sshPooledConnection is the connection from the pool.
+// Closing it doesn't close the connection, the connections are closed with the pool.
+type sshPooledConnection struct {
+	host *sshPoolHost
+	conn sshConnection
+}
+
+func newSSHConnectionPool(dialer sshDialer) *sshConnectionPool {
+	return &sshConnectionPool{
+		dialer: dialer,
+		hosts:  make(map[string]*sshPoolHost),
+	}
+}
+
+func (p *sshConnectionPool) dial(host string) (sshConnection, error) {
+	p.mu.Lock()
+	poolHost, ok := p.hosts[host]
+	if !ok {
+		poolHost = &sshPoolHost{}
+		p.hosts[host] = poolHost
+	}
+	p.mu.Unlock()
+	poolHost.mu.Lock()
+	defer poolHost.mu.Unlock()
+	if poolHost.conn == nil {
+		conn, err := p.dialer.dial(host)
+		if err != nil {
+			return nil, err
+		}
+		poolHost.conn = conn
+	}
+	return &sshPooledConnection{host: poolHost, conn: poolHost.conn}, nil
+}
+
+func (p *sshConnectionPool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var closeErr error
+	for _, poolHost := range p.hosts {
+		poolHost.mu.Lock()
+		if poolHost.conn != nil {
+			if err := poolHost.conn.Close(); err != nil {
+				closeErr = err
+			}
+			poolHost.conn = nil
+		}
+		poolHost.mu.Unlock()
+	}
+	return closeErr
+}
+
+func (h *sshPoolHost) discard(conn sshConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conn == conn {
+		h.conn.Close()
+		h.conn = nil
+	}
+}
+
+func (c *sshPooledConnection) NewSession() (*ssh.Session, error) {
+	session, err := c.conn.NewSession()
+	if err != nil {
+		c.host.discard(c.conn)
+	}
+	return session, err
+}
+
+func (c *sshPooledConnection) Close() error {
+	return nil
+}
+
+// getSSHDialer Returns the pool of ssh connections configured with the ssh flags of the root command.
+// The same pool is returned for all calls until closeSSHConnections is called.
+func getSSHDialer() (sshDialer, error) {
+	if sshConnections != nil {
+		return sshConnections, nil
+	}
+	transport, err := newSSHTransport(sshOptions{
+		user:           rootSSHUser,
+		keyFiles:       rootSSHKeyFiles,
+		useAgent:       rootSSHAgent,
+		port:           rootSSHPort,
+		timeout:        time.Duration(rootSSHTimeout) * time.Second,
+		knownHostsFile: rootSSHKnownHostsFile,
+		jumpHost:       rootSSHJumpHost,
+		forwardAgent:   rootSSHForwardAgent,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sshConnections = newSSHConnectionPool(transport)
+	return sshConnections, nil
+}
+
+// closeSSHConnections Closes all ssh connections of the command and the connection to the ssh agent.
+func closeSSHConnections() {
+	if sshConnections != nil {
+		err := sshConnections.close()
+		if err != nil {
+			gplog.Debug("%s", textmsg.ErrorTextUnableCloseSSHConnections(err))
+		}
+		sshConnections = nil
+	}
+	closeSSHAgent()
+}
+
+// runCommandOnHost Runs the command on the host in a new session and returns the standard output.
+func runCommandOnHost(command, host string, sshDial sshDialer) (string, error) {
+	connection, err := sshDial.dial(host)
+	if err != nil {
+		return "", err
+	}
+	defer connection.Close()
+	session, err := connection.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	var stdout bytes.Buffer
+	session.Stdout = &stdout
+	gplog.Debug("%s", textmsg.InfoTextCommandExecution(command, "on host", host))
+	if err := session.Run(command); err != nil {
+		gplog.Error("%s", textmsg.ErrorTextCommandExecutionFailed(err, command, "on host", host))
+		return "", err
+	}
+	gplog.Debug("%s", textmsg.InfoTextCommandExecutionSucceeded(command, "on host", host))
+	return stdout.String(), nil
+}
+
+// shellQuote Returns the value quoted in single quotes for use in a remote shell command.
+// Single quotes inside the value are escaped.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellQuoteList Returns the values quoted by shellQuote and separated by spaces.
+func shellQuoteList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, value := range values {
+		quoted = append(quoted, shellQuote(value))
+	}
+	return strings.Join(quoted, " ")
+}
+
+func newSSHTransport(opts sshOptions) (*sshTransport, error) {
+	config, err := getSSHConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	transport := &sshTransport{
+		config: config,
+		port:   opts.port,
+	}
+	if opts.forwardAgent {
+		transport.agentClient, err = getSSHAgentClient()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if opts.jumpHost == "" {
+		return transport, nil
+	}
+	jumpUser, jumpAddress, err := parseSSHJumpHost(opts.jumpHost)
+	if err != nil {
+		return nil, err
+	}
+	// The jump host uses the same authentication and host keys verification.
+	jumpConfig := *config
+	if jumpUser != "" {
+		jumpConfig.User = jumpUser
+	}
+	transport.jumpAddress = jumpAddress
+	transport.jumpConfig = &jumpConfig
+	return transport, nil
+}
+
+// parseSSHJumpHost Returns the user and the address in the host:port format from the jump host in [user@]host[:port] format.
+// If the user is not specified, the empty user is returned.
+// If the port is not specified, the default ssh port is used, the port of the hosts is not used for the jump host.
+func parseSSHJumpHost(jumpHost string) (string, string, error) {
+	var user string
+	host := jumpHost
+	if i := strings.LastIndex(jumpHost, "@"); i >= 0 {
+		user, host = jumpHost[:i], jumpHost[i+1:]
+		if user == "" {
+			return "", "", textmsg.ErrorInvalidValueError()
+		}
+	}
+	address := getSSHHostAddress(host, sshJumpHostDefaultPort)
+	hostname, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", err
+	}
+	portValue, err := strconv.Atoi(port)
+	if hostname == "" || err != nil || portValue <= 0 || portValue > maxPortValue {
+		return "", "", textmsg.ErrorInvalidValueError()
+	}
+	return user, address, nil
+}
+
+// getSSHConfig Returns the ssh client configuration for the options.
+// If the user is not specified, the current OS user is used.
+// If no key files are specified and the ssh agent is not used,
+// the default key files from the ~/.ssh directory are used.
+func getSSHConfig(opts sshOptions) (*ssh.ClientConfig, error) {
+	currentUser, err := operating.System.CurrentUser()
+	if err != nil {
+		return nil, err
+	}
+	user := opts.user
+	if user == "" {
+		user = currentUser.Username
+	}
+	keyFiles := opts.keyFiles
+	useDefaultKeys := len(keyFiles) == 0 && !opts.useAgent
+	if useDefaultKeys {
+		for _, keyFile := range sshDefaultKeyFiles {
+			keyFiles = append(keyFiles, filepath.Join(currentUser.HomeDir, ".ssh", keyFile))
+		}
+	}
+	var signers []ssh.Signer
+	for _, keyFile := range keyFiles {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			// Default key files may not exist.
+			if useDefaultKeys && os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	var authMethods []ssh.AuthMethod
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
+	}
+	if opts.useAgent {
+		agentClient, err := getSSHAgentClient()
+		if err != nil {
+			return nil, err
+		}
+		authMethods = append(authMethods, ssh.PublicKeysCallback(agentClient.Signers))
+	}
+	if len(authMethods) == 0 {
+		return nil, textmsg.ErrorSSHAuthMethodNotFoundError()
+	}
+	hostKeyCallback, err := getSSHHostKeyCallback(opts.knownHostsFile)
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            authMethods,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         opts.timeout,
+	}, nil
+}
+
+// getSSHAgentClient Returns the client for the ssh agent from the SSH_AUTH_SOCK environment variable.
+// The agent is used for the authentication on the hosts and on the jump host,
+// and is forwarded to the hosts, if the agent forwarding is enabled.
+// The connection to the agent is opened once and is closed by closeSSHConnections.
+func getSSHAgentClient() (agent.ExtendedAgent, error) {
+	if sshAgentClient != nil {
+		return sshAgentClient, nil
+	}
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, textmsg.ErrorSSHAgentNotAvailableError()
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	sshAgentConn = conn
+	sshAgentClient = agent.NewClient(conn)
+	return sshAgentClient, nil
+}
+
+// closeSSHAgent Closes the connection to the ssh agent, if it's opened.
+func closeSSHAgent() {
+	if sshAgentConn == nil {
+		return
+	}
+	err := sshAgentConn.Close()
+	if err != nil {
+		gplog.Debug("%s", textmsg.ErrorTextUnableCloseSSHConnections(err))
+	}
+	sshAgentConn = nil
+	sshAgentClient = nil
+}
+
+// getSSHHostKeyCallback Returns the callback for the host keys verification.
+// If the known_hosts file is not specified, the host keys are not verified.
+func getSSHHostKeyCallback(knownHostsFile string) (ssh.HostKeyCallback, error) {
+	if knownHostsFile == "" {
+		// Disable known_hosts check.
+		// This check also disables in gpbackup utility.
+		// #nosec G106
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+	return knownhosts.New(knownHostsFile)
+}
+
+// getSSHHostAddress Returns the address in the host:port format.
+// If the port is not specified in the host, the default port is used.
+func getSSHHostAddress(host string, defaultPort int) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(defaultPort))
+}
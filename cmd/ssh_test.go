@@ -0,0 +1,625 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/woblerr/gpbackman/gpbckpconfig"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSSHServer is an in-process ssh server.
+// The commands are executed on the local host using sh.
+// On the agent forwarding request the keys of the forwarded agent are counted.
+type testSSHServer struct {
+	address     string
+	hostKey     ssh.Signer
+	keyFile     string
+	connections atomic.Int32
+	commands    atomic.Int32
+	agentKeys   atomic.Int32
+	mu          sync.Mutex
+	users       []string
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(clientPriv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	authorizedKey, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, io.EOF
+		},
+	}
+	config.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	server := &testSSHServer{
+		address: listener.Addr().String(),
+		hostKey: hostKey,
+		keyFile: keyFile,
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handleConn(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *testSSHServer) handleConn(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	s.connections.Add(1)
+	s.mu.Lock()
+	s.users = append(s.users, serverConn.User())
+	s.mu.Unlock()
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			go s.handleSession(serverConn, newChannel)
+		case "direct-tcpip":
+			go handleDirectTCPIP(newChannel)
+		default:
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+		}
+	}
+}
+
+func (s *testSSHServer) handleSession(serverConn *ssh.ServerConn, newChannel ssh.NewChannel) {
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	for req := range requests {
+		if req.Type == "auth-agent-req@openssh.com" {
+			req.Reply(true, nil)
+			s.countAgentKeys(serverConn)
+			continue
+		}
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		req.Reply(true, nil)
+		s.commands.Add(1)
+		cmd := exec.Command("sh", "-c", payload.Command)
+		cmd.Stdout = channel
+		cmd.Stderr = channel.Stderr()
+		status := uint32(0)
+		if err := cmd.Run(); err != nil {
+			status = 1
+		}
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{status}))
+		return
+	}
+}
+
+func (s *testSSHServer) countAgentKeys(serverConn *ssh.ServerConn) {
+	channel, requests, err := serverConn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+	go ssh.DiscardRequests(requests)
+	keys, err := agent.NewClient(channel).List()
+	if err != nil {
+		return
+	}
+	s.agentKeys.Add(int32(len(keys)))
+}
+
+func handleDirectTCPIP(newChannel ssh.NewChannel) {
+	var payload struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+	go func() {
+		io.Copy(channel, conn)
+		channel.CloseWrite()
+	}()
+	io.Copy(conn, channel)
+	conn.Close()
+	channel.Close()
+}
+
+func (s *testSSHServer) knownHostsFile(t *testing.T, key ssh.PublicKey) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.address)}, key)
+	if err := os.WriteFile(file, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func (s *testSSHServer) hostPort(t *testing.T) (string, int) {
+	t.Helper()
+	host, portValue, err := net.SplitHostPort(s.address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func TestSSHTransport(t *testing.T) {
+	testhelper.SetupTestLogger()
+	server := newTestSSHServer(t)
+	host, port := server.hostPort(t)
+	existingPath := t.TempDir()
+	missingPath := filepath.Join(existingPath, "missing")
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ssh.NewSignerFromKey(otherPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		opts        sshOptions
+		paths       []string
+		wantErr     bool
+		wantDialErr bool
+	}{
+		{
+			name: "Test existing directory",
+			opts: sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second,
+				knownHostsFile: server.knownHostsFile(t, server.hostKey.PublicKey())},
+			paths: []string{existingPath},
+		},
+		{
+			name:    "Test missing directory",
+			opts:    sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second},
+			paths:   []string{existingPath, missingPath},
+			wantErr: true,
+		},
+		{
+			name: "Test existing directory through jump host",
+			opts: sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second,
+				knownHostsFile: server.knownHostsFile(t, server.hostKey.PublicKey()), jumpHost: server.address},
+			paths: []string{existingPath},
+		},
+		{
+			name: "Test unknown host key",
+			opts: sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second,
+				knownHostsFile: server.knownHostsFile(t, otherKey.PublicKey())},
+			paths:       []string{existingPath},
+			wantErr:     true,
+			wantDialErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport, err := newSSHTransport(tt.opts)
+			if err != nil {
+				t.Fatalf("newSSHTransport() error: %v", err)
+			}
+			if tt.wantDialErr {
+				if _, err := transport.dial(host); err == nil {
+					t.Errorf("dial() expected error")
+				}
+			}
+			errCh := make(chan error, 1)
+			checkBackupDirExistsOnSegments(tt.paths, host, transport, errCh)
+			close(errCh)
+			err = <-errCh
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkBackupDirExistsOnSegments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSSHTransportJumpHostUser(t *testing.T) {
+	testhelper.SetupTestLogger()
+	server := newTestSSHServer(t)
+	host, port := server.hostPort(t)
+	transport, err := newSSHTransport(sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second,
+		jumpHost: "bastion@" + server.address})
+	if err != nil {
+		t.Fatalf("newSSHTransport() error: %v", err)
+	}
+	if _, err := runCommandOnHost("true", host, transport); err != nil {
+		t.Fatalf("runCommandOnHost() error: %v", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	// The jump host is connected first, then the host is connected through the jump host.
+	if want := []string{"bastion", "gpadmin"}; !reflect.DeepEqual(server.users, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", server.users, want)
+	}
+}
+
+func TestSSHConnectionPool(t *testing.T) {
+	testhelper.SetupTestLogger()
+	server := newTestSSHServer(t)
+	host, port := server.hostPort(t)
+	transport, err := newSSHTransport(sshOptions{user: "gpadmin", keyFiles: []string{server.keyFile}, port: port, timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("newSSHTransport() error: %v", err)
+	}
+	pool := newSSHConnectionPool(transport)
+	tempDir := t.TempDir()
+	var configs []gpbckpconfig.SegmentConfig
+	var paths []string
+	for _, contentID := range []string{"0", "1", "2"} {
+		path := filepath.Join(tempDir, "gpseg "+contentID)
+		if err := os.MkdirAll(path, 0700); err != nil {
+			t.Fatal(err)
+		}
+		configs = append(configs, gpbckpconfig.SegmentConfig{ContentID: contentID, Hostname: host})
+		paths = append(paths, path)
+	}
+	errList := runOnSegments(configs, paths, 2, pool,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			checkBackupDirExistsOnSegments(paths, host, sshDial, errCh)
+		})
+	if len(errList) != 0 {
+		t.Fatalf("runOnSegments() errors: %v", errList)
+	}
+	errList = runOnSegments(configs, paths, 2, pool,
+		func(host string, configs []gpbckpconfig.SegmentConfig, paths []string, sshDial sshDialer, errCh chan error) {
+			deleteBackupDirOnSegments(paths, host, sshDial, errCh)
+		})
+	if len(errList) != 0 {
+		t.Fatalf("runOnSegments() errors: %v", errList)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("deleteBackupDirOnSegments() directory %s was not deleted", path)
+		}
+	}
+	if err := pool.close(); err != nil {
+		t.Errorf("close() error: %v", err)
+	}
+	// One connection for the host and one command for all segments of the host per operation.
+	if got := server.connections.Load(); got != 1 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, 1)
+	}
+	if got := server.commands.Load(); got != 2 {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, 2)
+	}
+}
+
+func TestGroupSegmentsByHost(t *testing.T) {
+	configs := []gpbckpconfig.SegmentConfig{
+		{ContentID: "0", Hostname: "sdw1"},
+		{ContentID: "1", Hostname: "sdw2"},
+		{ContentID: "2", Hostname: "sdw1"},
+	}
+	paths := []string{"/data/gpseg0", "/data/gpseg1", "/data/gpseg2"}
+	hosts, hostConfigs, hostPaths := groupSegmentsByHost(configs, paths)
+	if want := []string{"sdw1", "sdw2"}; !reflect.DeepEqual(hosts, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", hosts, want)
+	}
+	if want := []gpbckpconfig.SegmentConfig{configs[0], configs[2]}; !reflect.DeepEqual(hostConfigs["sdw1"], want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", hostConfigs["sdw1"], want)
+	}
+	if want := []string{"/data/gpseg0", "/data/gpseg2"}; !reflect.DeepEqual(hostPaths["sdw1"], want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", hostPaths["sdw1"], want)
+	}
+}
+
+// newTestSSHAgent Starts the ssh agent with the client key of the server
+// and sets the SSH_AUTH_SOCK environment variable.
+func newTestSSHAgent(t *testing.T, server *testSSHServer) {
+	t.Helper()
+	key, err := os.ReadFile(server.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey, err := ssh.ParseRawPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(agent.AddedKey{PrivateKey: privateKey}); err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				agent.ServeAgent(keyring, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	t.Setenv("SSH_AUTH_SOCK", socket)
+	t.Cleanup(closeSSHAgent)
+}
+
+func TestSSHTransportAgent(t *testing.T) {
+	testhelper.SetupTestLogger()
+	tests := []struct {
+		name          string
+		forwardAgent  bool
+		jumpHost      bool
+		wantAgentKeys int32
+	}{
+		{
+			name: "Test agent authentication",
+		},
+		{
+			name:          "Test agent forwarding",
+			forwardAgent:  true,
+			wantAgentKeys: 1,
+		},
+		{
+			name:          "Test agent forwarding through jump host",
+			forwardAgent:  true,
+			jumpHost:      true,
+			wantAgentKeys: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestSSHServer(t)
+			host, port := server.hostPort(t)
+			newTestSSHAgent(t, server)
+			opts := sshOptions{user: "gpadmin", useAgent: true, port: port, timeout: 5 * time.Second, forwardAgent: tt.forwardAgent}
+			if tt.jumpHost {
+				opts.jumpHost = server.address
+			}
+			transport, err := newSSHTransport(opts)
+			if err != nil {
+				t.Fatalf("newSSHTransport() error: %v", err)
+			}
+			if _, err := runCommandOnHost("true", host, transport); err != nil {
+				t.Fatalf("runCommandOnHost() error: %v", err)
+			}
+			if got := server.agentKeys.Load(); got != tt.wantAgentKeys {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.wantAgentKeys)
+			}
+		})
+	}
+}
+
+func TestGetSSHAgentClient(t *testing.T) {
+	server := newTestSSHServer(t)
+	newTestSSHAgent(t, server)
+	agentClient, err := getSSHAgentClient()
+	if err != nil {
+		t.Fatalf("getSSHAgentClient() error: %v", err)
+	}
+	conn := sshAgentConn
+	// The connection to the agent is reused by the next calls.
+	if got, err := getSSHAgentClient(); err != nil || got != agentClient {
+		t.Errorf("getSSHAgentClient() returned new client, error: %v", err)
+	}
+	closeSSHConnections()
+	if sshAgentConn != nil || sshAgentClient != nil {
+		t.Errorf("closeSSHConnections() agent connection was not reset")
+	}
+	if _, err := conn.Write([]byte{0}); err == nil {
+		t.Errorf("closeSSHConnections() agent connection was not closed")
+	}
+}
+
+func TestGetSSHConfigAgentNotAvailable(t *testing.T) {
+	t.Setenv("SSH_AUTH_SOCK", "")
+	if _, err := getSSHConfig(sshOptions{useAgent: true}); err == nil {
+		t.Errorf("getSSHConfig() expected error")
+	}
+}
+
+func TestGetSSHHostAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{
+			name: "Test host without port",
+			host: "bastion",
+			want: "bastion:22",
+		},
+		{
+			name: "Test host with port",
+			host: "bastion:2222",
+			want: "bastion:2222",
+		},
+		{
+			name: "Test ipv6 host without port",
+			host: "::1",
+			want: "[::1]:22",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getSSHHostAddress(tt.host, 22); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			name:  "Test simple path",
+			value: "/data/backups",
+			want:  "'/data/backups'",
+		},
+		{
+			name:  "Test path with spaces and shell characters",
+			value: "/data/my backups/$(id);*",
+			want:  "'/data/my backups/$(id);*'",
+		},
+		{
+			name:  "Test path with single quote",
+			value: "/data/it's",
+			want:  `'/data/it'\''s'`,
+		},
+		{
+			name:  "Test empty value",
+			value: "",
+			want:  "''",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shellQuote(tt.value); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			out, err := exec.Command("sh", "-c", "printf %s "+shellQuote(tt.value)).Output()
+			if err != nil {
+				t.Fatalf("shell execution error: %v", err)
+			}
+			if string(out) != tt.value {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", string(out), tt.value)
+			}
+		})
+	}
+}
+
+func TestParseSSHJumpHost(t *testing.T) {
+	tests := []struct {
+		name        string
+		jumpHost    string
+		wantUser    string
+		wantAddress string
+		wantErr     bool
+	}{
+		{
+			name:        "Test host without user and port",
+			jumpHost:    "bastion",
+			wantAddress: "bastion:22",
+		},
+		{
+			name:        "Test host with port",
+			jumpHost:    "bastion:2222",
+			wantAddress: "bastion:2222",
+		},
+		{
+			name:        "Test host with user and port",
+			jumpHost:    "jump@bastion:2222",
+			wantUser:    "jump",
+			wantAddress: "bastion:2222",
+		},
+		{
+			name:        "Test ipv6 host with user",
+			jumpHost:    "jump@::1",
+			wantUser:    "jump",
+			wantAddress: "[::1]:22",
+		},
+		{
+			name:     "Test empty user",
+			jumpHost: "@bastion",
+			wantErr:  true,
+		},
+		{
+			name:     "Test empty host",
+			jumpHost: "jump@:2222",
+			wantErr:  true,
+		},
+		{
+			name:     "Test invalid port",
+			jumpHost: "bastion:70000",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, address, err := parseSSHJumpHost(tt.jumpHost)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSSHJumpHost() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if user != tt.wantUser || address != tt.wantAddress {
+				t.Errorf("\nVariables do not match:\n%v %v\nwant:\n%v %v", user, address, tt.wantUser, tt.wantAddress)
+			}
+		})
+	}
+}
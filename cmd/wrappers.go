@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"bufio"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,10 +15,23 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/woblerr/gpbackman/gpbckpconfig"
 	"github.com/woblerr/gpbackman/textmsg"
+	"gopkg.in/yaml.v2"
 )
 
 var execOSExit = os.Exit
 
+// The input for the confirmation of destructive actions.
+var confirmationInput io.Reader = os.Stdin
+
+// isInteractiveInput Returns true if the standard input is a terminal.
+var isInteractiveInput = func() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
 func logHeadersDebug() {
 	gplog.Debug("Start %s version %s", commandName, getVersion())
 	gplog.Debug("Use console log level: %s", rootLogLevelConsole)
@@ -69,6 +85,15 @@ func getHistoryDBPath(historyDBPath string) string {
 	return historyDBName
 }
 
+// getHistoryDBOptions Returns the history database connection options from the root command flags.
+func getHistoryDBOptions() gpbckpconfig.HistoryDBOptions {
+	return gpbckpconfig.HistoryDBOptions{
+		BusyTimeout: time.Duration(rootHistoryDBBusyTimeout) * time.Second,
+		Retries:     rootHistoryDBRetries,
+		RetryDelay:  historyDBRetryDelay,
+	}
+}
+
 func getHistoryFilePath(historyFilePath string) string {
 	var historyFileName = historyFileNameConst
 	if historyFilePath != "" {
@@ -169,6 +194,62 @@ func checkBackupType(backupType string) error {
 	return nil
 }
 
+func checkMigrateStrategy(strategy string) error {
+	var validStrategy = map[string]bool{
+		gpbckpconfig.MigrateStrategySkip:      true,
+		gpbckpconfig.MigrateStrategyOverwrite: true,
+		gpbckpconfig.MigrateStrategyFail:      true,
+	}
+	if !validStrategy[strategy] {
+		return textmsg.ErrorInvalidValueError()
+	}
+	return nil
+}
+
+// Check that specified backup file is supported for fetching.
+func checkFetchArtifact(artifact string) error {
+	var validArtifact = map[string]bool{
+		fetchArtifactReport:   true,
+		fetchArtifactConfig:   true,
+		fetchArtifactToc:      true,
+		fetchArtifactMetadata: true,
+	}
+	if !validArtifact[artifact] {
+		return textmsg.ErrorInvalidValueError()
+	}
+	return nil
+}
+
+// Check that specified output format is one of the supported formats.
+func checkOutputFormat(format string, validFormats ...string) error {
+	for _, validFormat := range validFormats {
+		if format == validFormat {
+			return nil
+		}
+	}
+	return textmsg.ErrorInvalidValueError()
+}
+
+// writeStructuredOutput writes value to w in json or yaml format.
+// For any other format, an error is returned.
+func writeStructuredOutput(w io.Writer, format string, value interface{}) error {
+	switch format {
+	case outputFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case outputFormatYAML:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return textmsg.ErrorInvalidValueError()
+	}
+}
+
 // Check skip flag and local backup status.
 // SkipLocalBackup - true, local backup - true, returns "is a local backup" error.
 // SkipLocalBackup - false,local backup - false, returns "is not a local backup" error.
@@ -260,3 +341,117 @@ func handleErrorDB(backupName, errorMessage, backupStatus string, hDB *sql.DB) {
 		gplog.Error("%s", textmsg.ErrorTextUnableSetBackupStatus(backupStatus, backupName, err))
 	}
 }
+
+// askConfirmation Writes the question and reads the answer from the input.
+// Only "y" and "yes" answers are treated as the confirmation.
+func askConfirmation(r io.Reader, w io.Writer, question string) (bool, error) {
+	fmt.Fprint(w, question)
+	answer, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// snapshotHistoryDB Creates the snapshot of the history database before the history database is changed
+// and removes the oldest snapshots beyond the number of snapshots to keep.
+// It should be called right before the first write, when the change is not empty and is confirmed.
+// If snapshots are disabled or the history database doesn't exist yet, nothing is done.
+func snapshotHistoryDB(historyDBPath string) error {
+	if rootHistoryDBSnapshots == 0 {
+		return nil
+	}
+	if _, err := os.Stat(historyDBPath); os.IsNotExist(err) {
+		return nil
+	}
+	err := createHistoryDBSnapshot(historyDBPath)
+	if err != nil {
+		return err
+	}
+	return pruneHistoryDBSnapshots(historyDBPath)
+}
+
+// createHistoryDBSnapshot Creates the snapshot of the history database in the snapshot directory.
+func createHistoryDBSnapshot(historyDBPath string) error {
+	snapshotDir := gpbckpconfig.HistoryDBSnapshotDir(rootHistoryDBSnapshotDir, historyDBPath)
+	hDB, err := gpbckpconfig.OpenHistoryDB(historyDBPath, getHistoryDBOptions())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("open", err))
+		return err
+	}
+	defer func() {
+		closeErr := hDB.Close()
+		if closeErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDB("close", closeErr))
+		}
+	}()
+	snapshot, err := gpbckpconfig.CreateHistoryDBSnapshot(hDB, historyDBPath, snapshotDir, getCurrentTimestamp())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBSnapshot("create", err))
+		return err
+	}
+	gplog.Info("%s", textmsg.InfoTextHistoryDBSnapshotCreated(snapshot.Path))
+	return nil
+}
+
+// pruneHistoryDBSnapshots Removes the oldest snapshots beyond the number of snapshots to keep.
+func pruneHistoryDBSnapshots(historyDBPath string) error {
+	snapshotDir := gpbckpconfig.HistoryDBSnapshotDir(rootHistoryDBSnapshotDir, historyDBPath)
+	removed, err := gpbckpconfig.PruneHistoryDBSnapshots(historyDBPath, snapshotDir, rootHistoryDBSnapshots)
+	for _, snapshot := range removed {
+		gplog.Debug("%s", textmsg.InfoTextHistoryDBSnapshotRemoved(snapshot.Path))
+	}
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBSnapshot("remove", err))
+		return err
+	}
+	return nil
+}
+
+// lockHistoryDB Takes the lock of the history database for the current command.
+// The returned function releases the lock.
+func lockHistoryDB(historyDBPath string) (func(), error) {
+	lockPath := gpbckpconfig.HistoryDBLockPath(historyDBPath)
+	lock, err := gpbckpconfig.NewHistoryDBLock(strings.Join(os.Args[1:], " "), getCurrentTimestamp())
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("acquire", err))
+		return nil, err
+	}
+	err = gpbckpconfig.AcquireHistoryDBLock(lockPath, lock)
+	if err != nil {
+		gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("acquire", err))
+		return nil, err
+	}
+	return func() {
+		releaseErr := gpbckpconfig.ReleaseHistoryDBLock(lockPath, lock)
+		if releaseErr != nil {
+			gplog.Error("%s", textmsg.ErrorTextUnableActionHistoryDBLock("release", releaseErr))
+		}
+	}, nil
+}
+
+// lockHistoryDBForChange Takes the lock of the history database after the change is confirmed.
+// The history database could be changed by another command before the lock is taken,
+// so the revalidate function checks under the lock that the confirmed change is still actual.
+// If the change is not empty, the snapshot of the history database is created.
+// The returned function releases the lock.
+func lockHistoryDBForChange(historyDBPath string, emptyChange bool, revalidate func() error) (func(), error) {
+	unlock, err := lockHistoryDB(historyDBPath)
+	if err != nil {
+		return nil, err
+	}
+	err = revalidate()
+	if err == nil && !emptyChange {
+		err = snapshotHistoryDB(historyDBPath)
+	}
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return unlock, nil
+}
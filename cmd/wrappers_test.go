@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -387,6 +389,58 @@ func TestCheckBackupType(t *testing.T) {
 	}
 }
 
+func TestCheckMigrateStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		wantErr  bool
+	}{
+		{
+			name:     "Valid strategy",
+			strategy: gpbckpconfig.MigrateStrategySkip,
+			wantErr:  false,
+		},
+		{
+			name:     "Invalid strategy",
+			strategy: "merge",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkMigrateStrategy(tt.strategy); (err != nil) != tt.wantErr {
+				t.Errorf("checkMigrateStrategy() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckFetchArtifact(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact string
+		wantErr  bool
+	}{
+		{
+			name:     "Valid artifact",
+			artifact: fetchArtifactToc,
+			wantErr:  false,
+		},
+		{
+			name:     "Invalid artifact",
+			artifact: "plan",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkFetchArtifact(tt.artifact); (err != nil) != tt.wantErr {
+				t.Errorf("checkFetchArtifact() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestGetBackupMasterDir(t *testing.T) {
 	// Create a unique temp directory for this test to avoid conflicts with other tests
 	tempDir, err := os.MkdirTemp("", "gpbackman-test-")
@@ -588,3 +642,168 @@ func TestCheckLocalBackupStatus(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckOutputFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		wantErr bool
+	}{
+		{
+			name:    "Valid output format",
+			format:  outputFormatJSON,
+			wantErr: false,
+		},
+		{
+			name:    "Invalid output format",
+			format:  "xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOutputFormat(tt.format, outputFormatTable, outputFormatJSON, outputFormatYAML); (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputFormat() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteStructuredOutput(t *testing.T) {
+	value := struct {
+		Name  string   `json:"name" yaml:"name"`
+		Items []string `json:"items" yaml:"items"`
+	}{
+		Name:  "test",
+		Items: []string{"a", "b"},
+	}
+	tests := []struct {
+		name    string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "JSON format",
+			format:  outputFormatJSON,
+			want:    "{\n  \"name\": \"test\",\n  \"items\": [\n    \"a\",\n    \"b\"\n  ]\n}\n",
+			wantErr: false,
+		},
+		{
+			name:    "YAML format",
+			format:  outputFormatYAML,
+			want:    "name: test\nitems:\n- a\n- b\n",
+			wantErr: false,
+		},
+		{
+			name:    "Unsupported format",
+			format:  outputFormatTable,
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeStructuredOutput(&buf, tt.format, value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("writeStructuredOutput() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeStructuredOutput() got:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAskConfirmation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{"Test yes answer", "yes\n", true},
+		{"Test short answer", "Y\n", true},
+		{"Test answer without new line", "y", true},
+		{"Test no answer", "n\n", false},
+		{"Test empty answer", "\n", false},
+		{"Test no input", "", false},
+		{"Test other answer", "sure\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := askConfirmation(strings.NewReader(tt.input), &out, "Question? ")
+			if err != nil {
+				t.Errorf("askConfirmation() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			if out.String() != "Question? " {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", out.String(), "Question? ")
+			}
+		})
+	}
+}
+
+func TestLockHistoryDBForChange(t *testing.T) {
+	testhelper.SetupTestLogger()
+	defer func(snapshots int, snapshotDir string) {
+		rootHistoryDBSnapshots = snapshots
+		rootHistoryDBSnapshotDir = snapshotDir
+	}(rootHistoryDBSnapshots, rootHistoryDBSnapshotDir)
+	rootHistoryDBSnapshots = 5
+	rootHistoryDBSnapshotDir = ""
+	tests := []struct {
+		name          string
+		emptyChange   bool
+		revalidateErr error
+		wantErr       bool
+		wantSnapshots int
+	}{
+		{
+			name:          "Test confirmed change",
+			wantSnapshots: 1,
+		},
+		{
+			name:          "Test empty change",
+			emptyChange:   true,
+			wantSnapshots: 0,
+		},
+		{
+			name:          "Test changed after confirmation",
+			revalidateErr: fmt.Errorf("test error"),
+			wantErr:       true,
+			wantSnapshots: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			historyDBPath := filepath.Join(t.TempDir(), "gpbackup_history.db")
+			createTestHistoryDB(t, historyDBPath, nil)
+			unlock, err := lockHistoryDBForChange(historyDBPath, tt.emptyChange, func() error {
+				if _, err := os.Stat(gpbckpconfig.HistoryDBLockPath(historyDBPath)); err != nil {
+					t.Errorf("lock is not taken before revalidation: %v", err)
+				}
+				return tt.revalidateErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lockHistoryDBForChange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				unlock()
+			}
+			if _, err := os.Stat(gpbckpconfig.HistoryDBLockPath(historyDBPath)); !os.IsNotExist(err) {
+				t.Errorf("lock is not released")
+			}
+			snapshots, err := gpbckpconfig.GetHistoryDBSnapshots(historyDBPath, filepath.Dir(historyDBPath))
+			if err != nil {
+				t.Fatalf("GetHistoryDBSnapshots() error = %v", err)
+			}
+			if len(snapshots) != tt.wantSnapshots {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", len(snapshots), tt.wantSnapshots)
+			}
+		})
+	}
+}
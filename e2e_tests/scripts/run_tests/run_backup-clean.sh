@@ -22,7 +22,7 @@ COMMAND="backup-clean"
 
 run_command() {
     local label="${1}"; shift
-    run_gpbackman "${COMMAND}" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db "$@"
+    run_gpbackman "${COMMAND}" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db --yes "$@"
 }
 
 # Test 1: Clean local backups older than timestamp (--before-timestamp)
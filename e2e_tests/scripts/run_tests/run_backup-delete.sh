@@ -7,7 +7,7 @@ COMMAND="backup-delete"
 
 run_command(){
     local label="${1}"; shift
-    run_gpbackman "${COMMAND}" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db "$@"
+    run_gpbackman "${COMMAND}" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db --yes "$@"
 }
 
 get_backup_info_for_timestamp(){
@@ -76,7 +76,7 @@ test_delete_s3_full_cascade() {
 # Test 4: Try to delete non-existent backup (should fail)
 test_delete_nonexistent_backup() {
     local fake_timestamp="19990101000000"
-    if ${BIN_DIR}/gpbackman backup-delete --history-db ${DATA_DIR}/gpbackup_history.db --timestamp "${fake_timestamp}" --force; then
+    if ${BIN_DIR}/gpbackman backup-delete --history-db ${DATA_DIR}/gpbackup_history.db --timestamp "${fake_timestamp}" --force --yes; then
         echo "[ERROR] Expected failure, but command succeeded"
         exit 1
     else
@@ -24,7 +24,7 @@ run_command(){
 
 run_backup_clean() {
     local label="${1}"; shift
-    run_gpbackman "backup-clean" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db "$@"
+    run_gpbackman "backup-clean" "${label}" --history-db ${DATA_DIR}/gpbackup_history.db --yes "$@"
 }
 
 # Test 1: Clean from history db local backups older than timestamp (--before-timestamp)
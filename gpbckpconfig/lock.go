@@ -0,0 +1,165 @@
+package gpbckpconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// HistoryDBLock contains the information about the process that holds the history database lock.
+type HistoryDBLock struct {
+	PID      int    `json:"pid" yaml:"pid"`
+	Hostname string `json:"hostname" yaml:"hostname"`
+	Command  string `json:"command" yaml:"command"`
+	Started  string `json:"started" yaml:"started"`
+}
+
+// HistoryDBLockPath Returns the path to the lock file of the history database.
+// The lock file is located next to the history database.
+func HistoryDBLockPath(historyDBPath string) string {
+	return historyDBPath + ".lock"
+}
+
+// NewHistoryDBLock Returns the lock information for the current process.
+func NewHistoryDBLock(command, started string) (HistoryDBLock, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return HistoryDBLock{}, err
+	}
+	return HistoryDBLock{
+		PID:      os.Getpid(),
+		Hostname: hostname,
+		Command:  command,
+		Started:  started,
+	}, nil
+}
+
+// AcquireHistoryDBLock Creates the lock file with the lock information.
+// If the lock file exists and the lock is stale, the lock file is replaced.
+// If the lock is held by another active process, the error is returned.
+//
+// The lock file is written to the temporary file first and then linked to the lock path,
+// so other processes never read the partially written lock file.
+// The stale lock is taken over atomically, see takeHistoryDBLock.
+func AcquireHistoryDBLock(lockPath string, lock HistoryDBLock) error {
+	data, err := json.Marshal(lock)
+	if err != nil {
+		return err
+	}
+	tmpPath := lockPath + "." + strconv.Itoa(lock.PID) + ".tmp"
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+	// The second attempt is made after the stale lock is removed.
+	for attempt := 0; attempt < 2; attempt++ {
+		err = os.Link(tmpPath, lockPath)
+		if err == nil {
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		currentLock, err := ReadHistoryDBLock(lockPath)
+		if err != nil {
+			// The lock has been released between the attempts.
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		stale, err := IsHistoryDBLockStale(currentLock)
+		if err != nil {
+			return err
+		}
+		if !stale {
+			return textmsg.ErrorHistoryDBLockedError(strconv.Itoa(currentLock.PID), currentLock.Hostname, currentLock.Command, currentLock.Started)
+		}
+		// If the stale lock has been replaced by another process after the check,
+		// the new lock is kept and it is checked on the next attempt.
+		_, err = takeHistoryDBLock(lockPath, currentLock, lock.PID)
+		if err != nil {
+			return err
+		}
+	}
+	return textmsg.ErrorHistoryDBLockNotAcquiredError()
+}
+
+// ReleaseHistoryDBLock Removes the lock file, if the lock is held by the process from the lock information.
+// The lock of another process is not removed.
+func ReleaseHistoryDBLock(lockPath string, lock HistoryDBLock) error {
+	_, err := takeHistoryDBLock(lockPath, lock, lock.PID)
+	return err
+}
+
+// takeHistoryDBLock Removes the lock file, if it contains the expected lock.
+// Returns true, if the lock file has been removed.
+//
+// Reading the lock file and removing it are not atomic:
+// another process can replace the lock file between these operations, and its lock would be removed.
+// So the lock file is moved to the unique path of the current process first.
+// The rename is atomic, the moved file is no longer visible to other processes and is checked without races.
+// If the moved file contains another lock, it is linked back to the lock path.
+// The lock is not restored only if one more process has created the lock file in the meantime,
+// in this case the error is returned.
+func takeHistoryDBLock(lockPath string, expected HistoryDBLock, pid int) (bool, error) {
+	takePath := lockPath + "." + strconv.Itoa(pid) + ".take"
+	err := os.Rename(lockPath, takePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer os.Remove(takePath)
+	takenLock, err := ReadHistoryDBLock(takePath)
+	if err == nil && takenLock == expected {
+		return true, nil
+	}
+	// The lock file has been replaced by another process or can't be read, restore it.
+	linkErr := os.Link(takePath, lockPath)
+	if linkErr != nil {
+		return false, linkErr
+	}
+	return false, err
+}
+
+// ReadHistoryDBLock Reads the lock information from the lock file.
+func ReadHistoryDBLock(lockPath string) (HistoryDBLock, error) {
+	var lock HistoryDBLock
+	data, err := os.ReadFile(lockPath)
+	if err != nil {
+		return lock, err
+	}
+	err = json.Unmarshal(data, &lock)
+	return lock, err
+}
+
+// IsHistoryDBLockStale Returns true if the process that holds the lock doesn't exist.
+// The process can only be checked on the current host,
+// the lock taken on another host is never considered stale.
+func IsHistoryDBLockStale(lock HistoryDBLock) (bool, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false, err
+	}
+	if lock.Hostname != hostname {
+		return false, nil
+	}
+	return !processExists(lock.PID), nil
+}
+
+// processExists Returns true if the process with the given pid exists.
+// The signal 0 is used, it only checks the existence of the process.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,218 @@
+package gpbckpconfig
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// getFinishedProcessPID Returns the pid of the process that has already finished.
+func getFinishedProcessPID(t *testing.T) int {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("unable to run process: %v", err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestIsHistoryDBLockStale(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("unable to get hostname: %v", err)
+	}
+	finishedPID := getFinishedProcessPID(t)
+	tests := []struct {
+		name string
+		lock HistoryDBLock
+		want bool
+	}{
+		{
+			name: "Test active process",
+			lock: HistoryDBLock{PID: os.Getpid(), Hostname: hostname},
+			want: false,
+		},
+		{
+			name: "Test finished process",
+			lock: HistoryDBLock{PID: finishedPID, Hostname: hostname},
+			want: true,
+		},
+		{
+			name: "Test invalid pid",
+			lock: HistoryDBLock{PID: 0, Hostname: hostname},
+			want: true,
+		},
+		{
+			name: "Test process on another host",
+			lock: HistoryDBLock{PID: finishedPID, Hostname: hostname + "-other"},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsHistoryDBLockStale(tt.lock)
+			if err != nil {
+				t.Errorf("IsHistoryDBLockStale() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcquireHistoryDBLock(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("unable to get hostname: %v", err)
+	}
+	lock := HistoryDBLock{PID: os.Getpid(), Hostname: hostname, Command: "backup-clean", Started: "20240101100000"}
+	activeLock := HistoryDBLock{PID: os.Getpid(), Hostname: hostname, Command: "history-clean", Started: "20230101100000"}
+	otherHostLock := HistoryDBLock{PID: getFinishedProcessPID(t), Hostname: hostname + "-other", Command: "history-clean", Started: "20230101100000"}
+	tests := []struct {
+		name     string
+		existing *HistoryDBLock
+		want     HistoryDBLock
+		wantErr  bool
+	}{
+		{
+			name: "Test no lock",
+			want: lock,
+		},
+		{
+			name:     "Test stale lock",
+			existing: &HistoryDBLock{PID: getFinishedProcessPID(t), Hostname: hostname, Command: "history-clean", Started: "20230101100000"},
+			want:     lock,
+		},
+		{
+			name:     "Test active lock",
+			existing: &activeLock,
+			want:     activeLock,
+			wantErr:  true,
+		},
+		{
+			name:     "Test lock on another host",
+			existing: &otherHostLock,
+			want:     otherHostLock,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := HistoryDBLockPath(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+			if tt.existing != nil {
+				if err := AcquireHistoryDBLock(lockPath, *tt.existing); err != nil {
+					t.Fatalf("unable to create existing lock: %v", err)
+				}
+			}
+			err := AcquireHistoryDBLock(lockPath, lock)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("AcquireHistoryDBLock() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			got, err := ReadHistoryDBLock(lockPath)
+			if err != nil {
+				t.Fatalf("ReadHistoryDBLock() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReleaseHistoryDBLock(t *testing.T) {
+	lock := HistoryDBLock{PID: os.Getpid(), Hostname: "TestHost", Command: "backup-clean", Started: "20240101100000"}
+	otherLock := HistoryDBLock{PID: os.Getpid(), Hostname: "TestHost", Command: "history-clean", Started: "20240101100000"}
+	tests := []struct {
+		name       string
+		release    HistoryDBLock
+		wantExists bool
+	}{
+		{
+			name:       "Test release own lock",
+			release:    lock,
+			wantExists: false,
+		},
+		{
+			name:       "Test release lock of another process",
+			release:    otherLock,
+			wantExists: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := HistoryDBLockPath(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+			if err := AcquireHistoryDBLock(lockPath, lock); err != nil {
+				t.Fatalf("AcquireHistoryDBLock() error = %v", err)
+			}
+			if err := ReleaseHistoryDBLock(lockPath, tt.release); err != nil {
+				t.Errorf("ReleaseHistoryDBLock() error = %v", err)
+			}
+			_, err := os.Stat(lockPath)
+			if exists := err == nil; exists != tt.wantExists {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", exists, tt.wantExists)
+			}
+		})
+	}
+	// Release without lock file.
+	lockPath := HistoryDBLockPath(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+	if err := ReleaseHistoryDBLock(lockPath, lock); err != nil {
+		t.Errorf("ReleaseHistoryDBLock() error = %v", err)
+	}
+}
+
+func TestTakeHistoryDBLock(t *testing.T) {
+	staleLock := HistoryDBLock{PID: 1, Hostname: "TestHost", Command: "history-clean", Started: "20230101100000"}
+	newLock := HistoryDBLock{PID: os.Getpid(), Hostname: "TestHost", Command: "backup-clean", Started: "20240101100000"}
+	tests := []struct {
+		name     string
+		existing *HistoryDBLock
+		want     bool
+		wantLock *HistoryDBLock
+	}{
+		{
+			name:     "Test take expected lock",
+			existing: &staleLock,
+			want:     true,
+		},
+		{
+			name:     "Test lock replaced by another process",
+			existing: &newLock,
+			want:     false,
+			wantLock: &newLock,
+		},
+		{
+			name: "Test no lock",
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockPath := HistoryDBLockPath(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+			if tt.existing != nil {
+				if err := AcquireHistoryDBLock(lockPath, *tt.existing); err != nil {
+					t.Fatalf("unable to create existing lock: %v", err)
+				}
+			}
+			// The process that found the stale lock tries to take it over.
+			got, err := takeHistoryDBLock(lockPath, staleLock, 2)
+			if err != nil {
+				t.Fatalf("takeHistoryDBLock() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			gotLock, err := ReadHistoryDBLock(lockPath)
+			if tt.wantLock == nil {
+				if !os.IsNotExist(err) {
+					t.Errorf("ReadHistoryDBLock() error = %v, want not exist error", err)
+				}
+			} else if err != nil || gotLock != *tt.wantLock {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", gotLock, *tt.wantLock)
+			}
+			if _, err := os.Stat(lockPath + ".2.take"); !os.IsNotExist(err) {
+				t.Errorf("the moved lock file is not removed: %v", err)
+			}
+		})
+	}
+}
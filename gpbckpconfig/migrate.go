@@ -0,0 +1,190 @@
+package gpbckpconfig
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// Strategies for the migrated backups, that already exist in the history database.
+const (
+	MigrateStrategySkip      = "skip"
+	MigrateStrategyOverwrite = "overwrite"
+	MigrateStrategyFail      = "fail"
+)
+
+// MigrateResult contains the numbers of the migrated backups.
+//
+// Conflicting is the number of backups, that already exist in the history database.
+// Skipped is the number of conflicting backups, that are not stored.
+// Inserted is the number of stored backups, including the overwritten ones.
+type MigrateResult struct {
+	Inserted    int
+	Skipped     int
+	Conflicting int
+}
+
+// MigrateBackupsDB Stores the backups in the history database in a single transaction.
+// The backup conflicts, if the backup with the same timestamp already exists in the history database
+// or has already been stored from the same list. The conflicting backups are processed according to the strategy:
+//   - skip - the backup is not stored, the existing backup is kept;
+//   - overwrite - the existing backup is deleted from all tables of the history database and the backup is stored;
+//   - fail - no backups are stored and the error is returned.
+//
+// If any backup can't be stored, the transaction is rolled back, so the history database is never changed partially.
+func MigrateBackupsDB(backupConfigs []BackupConfig, strategy string, historyDB *sql.DB) (MigrateResult, error) {
+	var result MigrateResult
+	err := withTransaction(historyDB, func(tx *sql.Tx) error {
+		var err error
+		result, err = migrateBackups(backupConfigs, strategy, tx)
+		return err
+	})
+	return result, err
+}
+
+func migrateBackups(backupConfigs []BackupConfig, strategy string, tx *sql.Tx) (MigrateResult, error) {
+	var result MigrateResult
+	for _, backupConfig := range backupConfigs {
+		var count int
+		countQuery := countBackupsInTableQuery("backups", []string{backupConfig.Timestamp})
+		err := tx.QueryRow(countQuery.text, countQuery.args...).Scan(&count)
+		if err != nil {
+			return result, err
+		}
+		var queries []historyDBQuery
+		if count > 0 {
+			result.Conflicting++
+			switch strategy {
+			case MigrateStrategySkip:
+				result.Skipped++
+				continue
+			case MigrateStrategyOverwrite:
+				// The rows of the child tables are deleted first, because of the foreign keys.
+				for _, table := range historyDBChildTables {
+					queries = append(queries, deleteBackupsFormTableQuery(table, []string{backupConfig.Timestamp}))
+				}
+				queries = append(queries, deleteBackupsFormTableQuery("backups", []string{backupConfig.Timestamp}))
+			default:
+				// All conflicting backups are counted before the error is returned.
+				continue
+			}
+		}
+		queries = append(queries, storeBackupQueries(backupConfig)...)
+		err = statementsFunc(queries, tx)
+		if err != nil {
+			return result, err
+		}
+		result.Inserted++
+	}
+	if strategy != MigrateStrategySkip && strategy != MigrateStrategyOverwrite && result.Conflicting > 0 {
+		return MigrateResult{Conflicting: result.Conflicting}, textmsg.ErrorHistoryDBBackupsConflictError(strconv.Itoa(result.Conflicting))
+	}
+	return result, nil
+}
+
+// CheckMigrateBackups Returns the result of the migration of the backups without storing them.
+// The conflicts are detected and processed in the same way as in MigrateBackupsDB.
+//
+// The backupNames contains the timestamps of the backups, that already exist in the history database.
+// If the migration succeeds, the timestamps of the migrated backups are added to backupNames,
+// so the next list could be checked as if the previous one had been migrated.
+func CheckMigrateBackups(backupConfigs []BackupConfig, strategy string, backupNames map[string]bool) (MigrateResult, error) {
+	var result MigrateResult
+	migrated := make(map[string]bool)
+	for _, backupConfig := range backupConfigs {
+		if backupNames[backupConfig.Timestamp] || migrated[backupConfig.Timestamp] {
+			result.Conflicting++
+			switch strategy {
+			case MigrateStrategySkip:
+				result.Skipped++
+				continue
+			case MigrateStrategyOverwrite:
+				// The existing backup would be replaced.
+			default:
+				continue
+			}
+		}
+		migrated[backupConfig.Timestamp] = true
+		result.Inserted++
+	}
+	if strategy != MigrateStrategySkip && strategy != MigrateStrategyOverwrite && result.Conflicting > 0 {
+		return MigrateResult{Conflicting: result.Conflicting}, textmsg.ErrorHistoryDBBackupsConflictError(strconv.Itoa(result.Conflicting))
+	}
+	for backupName := range migrated {
+		backupNames[backupName] = true
+	}
+	return result, nil
+}
+
+// storeBackupQueries Returns the queries to store the backup in all tables of the history database.
+// The rows are the same as the rows stored by gpbackup.
+// As in gpbackup, if the end time is not set, the current time is used.
+func storeBackupQueries(backupConfig BackupConfig) []historyDBQuery {
+	endTime := backupConfig.EndTime
+	if endTime == "" {
+		endTime = history.CurrentTimestamp()
+	}
+	queries := []historyDBQuery{
+		{
+			text: `
+INSERT INTO backups (
+	timestamp, backup_dir, backup_version, compressed, compression_type, database_name,
+	database_version, segment_count, data_only, date_deleted, exclude_schema_filtered,
+	exclude_table_filtered, include_schema_filtered, include_table_filtered, incremental,
+	leaf_partition_data, metadata_only, plugin, plugin_version, single_data_file, end_time,
+	without_globals, with_statistics, status
+)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+`,
+			args: []interface{}{
+				backupConfig.Timestamp, backupConfig.BackupDir, backupConfig.BackupVersion,
+				backupConfig.Compressed, backupConfig.CompressionType, backupConfig.DatabaseName,
+				backupConfig.DatabaseVersion, 0, backupConfig.DataOnly, backupConfig.DateDeleted,
+				backupConfig.ExcludeSchemaFiltered, backupConfig.ExcludeTableFiltered,
+				backupConfig.IncludeSchemaFiltered, backupConfig.IncludeTableFiltered,
+				backupConfig.Incremental, backupConfig.LeafPartitionData, backupConfig.MetadataOnly,
+				backupConfig.Plugin, backupConfig.PluginVersion, backupConfig.SingleDataFile, endTime,
+				backupConfig.WithoutGlobals, backupConfig.WithStatistics, backupConfig.Status,
+			},
+		},
+	}
+	nameTables := []struct {
+		table string
+		names []string
+	}{
+		{"exclude_relations", backupConfig.ExcludeRelations},
+		{"exclude_schemas", backupConfig.ExcludeSchemas},
+		{"include_relations", backupConfig.IncludeRelations},
+		{"include_schemas", backupConfig.IncludeSchemas},
+	}
+	for _, nameTable := range nameTables {
+		for _, name := range nameTable.names {
+			queries = append(queries, storeBackupNameQuery(nameTable.table, backupConfig.Timestamp, name))
+		}
+	}
+	for _, entry := range backupConfig.RestorePlan {
+		queries = append(queries, historyDBQuery{
+			text: `INSERT INTO restore_plans (timestamp, restore_plan_timestamp) VALUES (?, ?);`,
+			args: sqlArgs(backupConfig.Timestamp, entry.Timestamp),
+		})
+		for _, tableFQN := range entry.TableFQNs {
+			queries = append(queries, historyDBQuery{
+				text: `INSERT INTO restore_plan_tables (timestamp, restore_plan_timestamp, table_fqn) VALUES (?, ?, ?);`,
+				args: sqlArgs(backupConfig.Timestamp, entry.Timestamp, tableFQN),
+			})
+		}
+	}
+	return queries
+}
+
+// The table name can't be passed as a placeholder value,
+// only the include_* and exclude_* tables are used.
+func storeBackupNameQuery(table, timestamp, name string) historyDBQuery {
+	return historyDBQuery{
+		text: fmt.Sprintf(`INSERT INTO %s (timestamp, name) VALUES (?, ?);`, table),
+		args: sqlArgs(timestamp, name),
+	}
+}
@@ -0,0 +1,214 @@
+package gpbckpconfig
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/greenplum-db/gpbackup/history"
+)
+
+func TestMigrateBackupsDB(t *testing.T) {
+	existingBackup := BackupConfig{
+		DatabaseName: "test",
+		Timestamp:    "20240101100000",
+		EndTime:      "20240101100010",
+		Status:       BackupStatusSuccess,
+		RestorePlan:  []RestorePlanEntry{{Timestamp: "20240101100000", TableFQNs: []string{"public.t1"}}},
+	}
+	changedBackup := existingBackup
+	changedBackup.DateDeleted = "20240103100000"
+	changedBackup.IncludeSchemas = []string{"public"}
+	changedBackup.RestorePlan = []RestorePlanEntry{{Timestamp: "20240101100000", TableFQNs: []string{"public.t2"}}}
+	newBackup := BackupConfig{
+		DatabaseName:     "test",
+		Timestamp:        "20240102100000",
+		EndTime:          "20240102100010",
+		Status:           BackupStatusSuccess,
+		Incremental:      true,
+		ExcludeRelations: []string{"public.t3"},
+		RestorePlan: []RestorePlanEntry{
+			{Timestamp: "20240101100000", TableFQNs: []string{"public.t1"}},
+			{Timestamp: "20240102100000", TableFQNs: []string{"public.t2"}},
+		},
+	}
+	tests := []struct {
+		name       string
+		backups    []BackupConfig
+		strategy   string
+		want       MigrateResult
+		wantErr    bool
+		wantBackup BackupConfig
+		wantCount  int
+	}{
+		{
+			name:       "Test skip",
+			backups:    []BackupConfig{changedBackup, newBackup},
+			strategy:   MigrateStrategySkip,
+			want:       MigrateResult{Inserted: 1, Skipped: 1, Conflicting: 1},
+			wantBackup: existingBackup,
+			wantCount:  2,
+		},
+		{
+			name:       "Test overwrite",
+			backups:    []BackupConfig{changedBackup, newBackup},
+			strategy:   MigrateStrategyOverwrite,
+			want:       MigrateResult{Inserted: 2, Conflicting: 1},
+			wantBackup: changedBackup,
+			wantCount:  2,
+		},
+		{
+			name:       "Test fail",
+			backups:    []BackupConfig{newBackup, changedBackup},
+			strategy:   MigrateStrategyFail,
+			want:       MigrateResult{Conflicting: 1},
+			wantErr:    true,
+			wantBackup: existingBackup,
+			wantCount:  1,
+		},
+		{
+			name:       "Test duplicate in the same list",
+			backups:    []BackupConfig{newBackup, newBackup},
+			strategy:   MigrateStrategySkip,
+			want:       MigrateResult{Inserted: 1, Skipped: 1, Conflicting: 1},
+			wantBackup: existingBackup,
+			wantCount:  2,
+		},
+		{
+			name:       "Test no conflicts",
+			backups:    []BackupConfig{newBackup},
+			strategy:   MigrateStrategyFail,
+			want:       MigrateResult{Inserted: 1},
+			wantBackup: existingBackup,
+			wantCount:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hDB, err := history.InitializeHistoryDatabase(filepath.Join(t.TempDir(), "gpbackup_history.db"))
+			if err != nil {
+				t.Fatalf("InitializeHistoryDatabase() error = %v", err)
+			}
+			defer hDB.Close()
+			_, err = MigrateBackupsDB([]BackupConfig{existingBackup}, MigrateStrategyFail, hDB)
+			if err != nil {
+				t.Fatalf("MigrateBackupsDB() error = %v", err)
+			}
+			got, err := MigrateBackupsDB(tt.backups, tt.strategy, hDB)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MigrateBackupsDB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			backupNames, err := GetBackupNamesDB(true, true, DatabaseFilter{}, hDB)
+			if err != nil {
+				t.Fatalf("GetBackupNamesDB() error = %v", err)
+			}
+			if len(backupNames) != tt.wantCount {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", len(backupNames), tt.wantCount)
+			}
+			backupData, err := GetBackupDataDB(tt.wantBackup.Timestamp, hDB)
+			if err != nil {
+				t.Fatalf("GetBackupDataDB() error = %v", err)
+			}
+			if backupData.DateDeleted != tt.wantBackup.DateDeleted ||
+				!reflect.DeepEqual(backupData.RestorePlan, tt.wantBackup.RestorePlan) ||
+				len(backupData.IncludeSchemas) != len(tt.wantBackup.IncludeSchemas) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", backupData, tt.wantBackup)
+			}
+		})
+	}
+}
+
+func TestStoreBackupQueries(t *testing.T) {
+	backupConfig := BackupConfig{
+		Timestamp:      "20240102100000",
+		EndTime:        "20240102100010",
+		IncludeSchemas: []string{"public", "test"},
+		RestorePlan: []RestorePlanEntry{
+			{Timestamp: "20240101100000", TableFQNs: []string{"public.t1"}},
+			{Timestamp: "20240102100000"},
+		},
+	}
+	want := []historyDBQuery{
+		{
+			text: `INSERT INTO include_schemas (timestamp, name) VALUES (?, ?);`,
+			args: []interface{}{"20240102100000", "public"},
+		},
+		{
+			text: `INSERT INTO include_schemas (timestamp, name) VALUES (?, ?);`,
+			args: []interface{}{"20240102100000", "test"},
+		},
+		{
+			text: `INSERT INTO restore_plans (timestamp, restore_plan_timestamp) VALUES (?, ?);`,
+			args: []interface{}{"20240102100000", "20240101100000"},
+		},
+		{
+			text: `INSERT INTO restore_plan_tables (timestamp, restore_plan_timestamp, table_fqn) VALUES (?, ?, ?);`,
+			args: []interface{}{"20240102100000", "20240101100000", "public.t1"},
+		},
+		{
+			text: `INSERT INTO restore_plans (timestamp, restore_plan_timestamp) VALUES (?, ?);`,
+			args: []interface{}{"20240102100000", "20240102100000"},
+		},
+	}
+	got := storeBackupQueries(backupConfig)
+	// The first query stores the backup in the backups table.
+	if len(got) == 0 || got[0].args[0] != backupConfig.Timestamp || got[0].args[20] != backupConfig.EndTime {
+		t.Fatalf("\nVariables do not match:\n%v\nwant the backups table query", got)
+	}
+	if !reflect.DeepEqual(got[1:], want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got[1:], want)
+	}
+}
+
+func TestCheckMigrateBackups(t *testing.T) {
+	backups := []BackupConfig{
+		{Timestamp: "20240101100000"},
+		{Timestamp: "20240102100000"},
+		{Timestamp: "20240102100000"},
+	}
+	tests := []struct {
+		name            string
+		strategy        string
+		want            MigrateResult
+		wantErr         bool
+		wantBackupNames map[string]bool
+	}{
+		{
+			name:            "Test skip",
+			strategy:        MigrateStrategySkip,
+			want:            MigrateResult{Inserted: 1, Skipped: 2, Conflicting: 2},
+			wantBackupNames: map[string]bool{"20240101100000": true, "20240102100000": true},
+		},
+		{
+			name:            "Test overwrite",
+			strategy:        MigrateStrategyOverwrite,
+			want:            MigrateResult{Inserted: 3, Conflicting: 2},
+			wantBackupNames: map[string]bool{"20240101100000": true, "20240102100000": true},
+		},
+		{
+			name:            "Test fail",
+			strategy:        MigrateStrategyFail,
+			want:            MigrateResult{Conflicting: 2},
+			wantErr:         true,
+			wantBackupNames: map[string]bool{"20240101100000": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backupNames := map[string]bool{"20240101100000": true}
+			got, err := CheckMigrateBackups(backups, tt.strategy, backupNames)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckMigrateBackups() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+			if !reflect.DeepEqual(backupNames, tt.wantBackupNames) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", backupNames, tt.wantBackupNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package gpbckpconfig
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+const (
+	backupReportHeader          = "Greenplum Database Backup Report"
+	backupReportObjectCountsKey = "count of database objects in backup:"
+	backupReportTablesObject    = "tables"
+)
+
+// BackupReport contains the information from the gpbackup report file.
+//
+// The start and end times are in the same format as the backup timestamp.
+// The duration is in seconds, the database size is in bytes.
+type BackupReport struct {
+	Timestamp            string             `json:"timestamp" yaml:"timestamp"`
+	DatabaseVersion      string             `json:"database_version" yaml:"database_version"`
+	BackupVersion        string             `json:"backup_version" yaml:"backup_version"`
+	DatabaseName         string             `json:"database_name" yaml:"database_name"`
+	CommandLine          string             `json:"command_line" yaml:"command_line"`
+	Flags                []BackupReportFlag `json:"flags" yaml:"flags"`
+	Compression          string             `json:"compression" yaml:"compression"`
+	PluginExecutable     string             `json:"plugin_executable" yaml:"plugin_executable"`
+	BackupSection        string             `json:"backup_section" yaml:"backup_section"`
+	ObjectFiltering      string             `json:"object_filtering" yaml:"object_filtering"`
+	IncludesStatistics   bool               `json:"includes_statistics" yaml:"includes_statistics"`
+	DataFileFormat       string             `json:"data_file_format" yaml:"data_file_format"`
+	Incremental          bool               `json:"incremental" yaml:"incremental"`
+	IncrementalBackupSet []string           `json:"incremental_backup_set" yaml:"incremental_backup_set"`
+	StartTime            string             `json:"start_time" yaml:"start_time"`
+	EndTime              string             `json:"end_time" yaml:"end_time"`
+	Duration             float64            `json:"duration" yaml:"duration"`
+	Status               string             `json:"status" yaml:"status"`
+	Error                string             `json:"error" yaml:"error"`
+	DatabaseSize         string             `json:"database_size" yaml:"database_size"`
+	DatabaseSizeBytes    int64              `json:"database_size_bytes" yaml:"database_size_bytes"`
+	SegmentCount         int                `json:"segment_count" yaml:"segment_count"`
+	TableCount           int                `json:"table_count" yaml:"table_count"`
+	ObjectCounts         map[string]int     `json:"object_counts" yaml:"object_counts"`
+}
+
+// BackupReportFlag is the gpbackup flag from the command line in the report file.
+// For boolean flags the value is empty.
+type BackupReportFlag struct {
+	Name  string `json:"name" yaml:"name"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// ParseBackupReport Returns the information from the gpbackup report file content.
+//
+// The report consists of the lines in the format "<key>: <value>",
+// the timestamps of the incremental backup set without a key
+// and the counts of database objects at the end of the file.
+// Unknown keys are ignored, so the reports of the different gpbackup versions could be parsed.
+func ParseBackupReport(content string) (BackupReport, error) {
+	report := BackupReport{
+		Flags:                make([]BackupReportFlag, 0),
+		IncrementalBackupSet: make([]string, 0),
+		ObjectCounts:         make(map[string]int),
+	}
+	var headerFound, objectCountsFound bool
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !headerFound {
+			if line != backupReportHeader {
+				return report, textmsg.ErrorInvalidReportFileError()
+			}
+			headerFound = true
+			continue
+		}
+		if line == backupReportObjectCountsKey {
+			objectCountsFound = true
+			continue
+		}
+		if objectCountsFound {
+			// The object name could contain spaces, the count is the last field.
+			idx := strings.LastIndexAny(line, " \t")
+			if idx == -1 {
+				return report, textmsg.ErrorInvalidReportValueError(line, backupReportObjectCountsKey)
+			}
+			count, err := strconv.Atoi(line[idx+1:])
+			if err != nil {
+				return report, textmsg.ErrorInvalidReportValueError(line, backupReportObjectCountsKey)
+			}
+			object := strings.TrimSpace(line[:idx])
+			report.ObjectCounts[object] = count
+			if object == backupReportTablesObject {
+				report.TableCount = count
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			// The timestamps of the incremental backup set are written without a key.
+			if report.Incremental {
+				report.IncrementalBackupSet = append(report.IncrementalBackupSet, line)
+			}
+			continue
+		}
+		err := report.setValue(key, strings.TrimSpace(value))
+		if err != nil {
+			return report, err
+		}
+	}
+	if !headerFound {
+		return report, textmsg.ErrorInvalidReportFileError()
+	}
+	return report, nil
+}
+
+func (report *BackupReport) setValue(key, value string) error {
+	var err error
+	switch key {
+	case "timestamp key":
+		report.Timestamp = value
+	case "gpdb version":
+		report.DatabaseVersion = value
+	case "gpbackup version":
+		report.BackupVersion = value
+	case "database name":
+		report.DatabaseName = value
+	case "command line":
+		report.CommandLine = value
+		report.Flags = parseReportCommandLine(value)
+	case "compression":
+		report.Compression = value
+	case "plugin executable":
+		report.PluginExecutable = value
+	case "backup section":
+		report.BackupSection = value
+	case "object filtering":
+		report.ObjectFiltering = value
+	case "includes statistics":
+		report.IncludesStatistics = value == "Yes"
+	case "data file format":
+		report.DataFileFormat = value
+	case "incremental":
+		report.Incremental = value == "True"
+	case "start time":
+		report.StartTime, err = parseReportTime(value)
+	case "end time":
+		report.EndTime, err = parseReportTime(value)
+	case "duration":
+		report.Duration, err = parseReportDuration(value)
+	case "backup status":
+		report.Status = value
+	case "backup error":
+		report.Error = value
+	case "database size":
+		report.DatabaseSize = value
+		report.DatabaseSizeBytes, err = parseReportSize(value)
+	case "segment count":
+		report.SegmentCount, err = strconv.Atoi(value)
+	}
+	if err != nil {
+		return textmsg.ErrorInvalidReportValueError(value, key)
+	}
+	return nil
+}
+
+// parseReportCommandLine Returns the flags from the gpbackup command line.
+// The first field is the gpbackup executable and is skipped.
+// The flag value could be set as "--flag value" or "--flag=value".
+func parseReportCommandLine(commandLine string) []BackupReportFlag {
+	flags := make([]BackupReportFlag, 0)
+	fields := strings.Fields(commandLine)
+	for i := 1; i < len(fields); i++ {
+		if !strings.HasPrefix(fields[i], "-") {
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimLeft(fields[i], "-"), "=")
+		if !found && i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			value = fields[i+1]
+			i++
+		}
+		flags = append(flags, BackupReportFlag{Name: name, Value: value})
+	}
+	return flags
+}
+
+// parseReportTime Returns the time from the report in the timestamp format.
+func parseReportTime(value string) (string, error) {
+	t, err := time.Parse(DateFormat, value)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(Layout), nil
+}
+
+// parseReportDuration Returns the duration in seconds.
+// The duration in the report is in the format "H:MM:SS".
+func parseReportDuration(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, textmsg.ErrorValidationValue()
+	}
+	var seconds int
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, err
+		}
+		seconds = seconds*60 + n
+	}
+	return float64(seconds), nil
+}
+
+// parseReportSize Returns the database size in bytes.
+// The size in the report is the upper-cased result of the pg_size_pretty function, e.g. "42 MB".
+func parseReportSize(value string) (int64, error) {
+	units := map[string]int64{
+		"BYTES": 1,
+		"KB":    1 << 10,
+		"MB":    1 << 20,
+		"GB":    1 << 30,
+		"TB":    1 << 40,
+		"PB":    1 << 50,
+	}
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return 0, textmsg.ErrorValidationValue()
+	}
+	multiplier, ok := units[fields[1]]
+	if !ok {
+		return 0, textmsg.ErrorValidationValue()
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return size * multiplier, nil
+}
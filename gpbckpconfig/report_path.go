@@ -0,0 +1,107 @@
+package gpbckpconfig
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// backupExamplePluginDir The directory, where the example plugin from gpbackup stores the backup files.
+// See plugins/example_plugin.bash in https://github.com/greenplum-db/gpbackup.
+const backupExamplePluginDir = "/tmp/plugin_dest"
+
+// ReportPathResolver Returns the full path to the report file of the backup in the plugin storage.
+// The plugin options are the options from the plugin config file.
+type ReportPathResolver func(timestamp string, pluginOptions map[string]string) (string, error)
+
+// reportPathResolvers contains the report path resolvers for the plugins.
+// The key is the plugin name from the history database.
+var reportPathResolvers = map[string]ReportPathResolver{
+	BackupS3Plugin:      backupS3PluginReportPath,
+	BackupDDBoostPlugin: backupDDBoostPluginReportPath,
+	BackupGCSPlugin:     backupGCSPluginReportPath,
+	BackupAzurePlugin:   backupAzurePluginReportPath,
+	BackupExamplePlugin: backupExamplePluginReportPath,
+}
+
+// RegisterReportPathResolver Registers the report path resolver for the plugin.
+// If the resolver for the plugin is already registered, it is replaced.
+// The function is not safe for concurrent use, the resolvers should be registered on initialization.
+func RegisterReportPathResolver(pluginName string, resolver ReportPathResolver) {
+	reportPathResolvers[pluginName] = resolver
+}
+
+// backupDDBoostPluginReportPath Returns path to report file name for gpbackup_ddboost_plugin plugin.
+// Basic path for DD Boost plugin format:
+//
+//	<directory>/backups/<YYYYMMDD>/<YYYYMMDDHHMMSS>/gpbackup_<YYYYMMDDHHMMSS>_report
+//
+// The path is relative to the storage unit.
+// If directory option is not specified or it is empty, the error will be returned.
+func backupDDBoostPluginReportPath(timestamp string, pluginOptions map[string]string) (string, error) {
+	pathOption := "directory"
+	directoryValue, exists := pluginOptions[pathOption]
+	if !exists || directoryValue == "" {
+		return "", textmsg.ErrorValidationPluginOption(pathOption, BackupDDBoostPlugin)
+	}
+	directoryValue = strings.Trim(directoryValue, "/")
+	return filepath.Join("/", directoryValue, "backups", timestamp[0:8], timestamp, ReportFileName(timestamp)), nil
+}
+
+// backupGCSPluginReportPath Returns path to report file name for gpbackup_gcs_plugin plugin.
+// The plugin uses the same path format as gpbackup_s3_plugin.
+func backupGCSPluginReportPath(timestamp string, pluginOptions map[string]string) (string, error) {
+	return backupFolderPluginReportPath(BackupGCSPlugin, timestamp, pluginOptions)
+}
+
+// backupAzurePluginReportPath Returns path to report file name for gpbackup_azure_plugin plugin.
+// The plugin uses the same path format as gpbackup_s3_plugin.
+func backupAzurePluginReportPath(timestamp string, pluginOptions map[string]string) (string, error) {
+	return backupFolderPluginReportPath(BackupAzurePlugin, timestamp, pluginOptions)
+}
+
+// backupExamplePluginReportPath Returns path to report file name for example_plugin plugin.
+// The example plugin stores the backup files in the local filesystem:
+//
+//	/tmp/plugin_dest/<YYYYMMDD>/<YYYYMMDDHHMMSS>/gpbackup_<YYYYMMDDHHMMSS>_report
+func backupExamplePluginReportPath(timestamp string, _ map[string]string) (string, error) {
+	return filepath.Join(backupExamplePluginDir, timestamp[0:8], timestamp, ReportFileName(timestamp)), nil
+}
+
+// backupPluginTemplateReportPath Returns path to report file name from the user-defined template:
+//
+//	<template>/gpbackup_<YYYYMMDDHHMMSS>_report
+//
+// The following placeholders could be used in the template:
+//   - {timestamp} - the backup timestamp in the format YYYYMMDDHHMMSS;
+//   - {date} - the backup date in the format YYYYMMDD;
+//   - {<option>} - the value of the plugin option from the plugin config file, e.g. {folder}.
+//
+// If the template is empty or the plugin option is not specified or it is empty, the error will be returned.
+func backupPluginTemplateReportPath(timestamp, template, pluginName string, pluginOptions map[string]string) (string, error) {
+	if template == "" {
+		return "", textmsg.ErrorValidationPluginOption(ReportPathTemplateOption, pluginName)
+	}
+	var err error
+	placeholder := regexp.MustCompile(`\{([^{}]+)\}`)
+	reportPath := placeholder.ReplaceAllStringFunc(template, func(match string) string {
+		name := match[1 : len(match)-1]
+		switch name {
+		case "timestamp":
+			return timestamp
+		case "date":
+			return timestamp[0:8]
+		}
+		value, exists := pluginOptions[name]
+		if (!exists || value == "") && err == nil {
+			err = textmsg.ErrorValidationPluginOption(name, pluginName)
+		}
+		return strings.Trim(value, "/")
+	})
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join("/", strings.Trim(reportPath, "/"), ReportFileName(timestamp)), nil
+}
@@ -0,0 +1,133 @@
+package gpbckpconfig
+
+import (
+	"testing"
+)
+
+func TestBackupPluginReportPathResolvers(t *testing.T) {
+	tests := []struct {
+		name          string
+		plugin        string
+		pluginOptions map[string]string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "Test ddboost plugin",
+			plugin:        BackupDDBoostPlugin,
+			pluginOptions: map[string]string{"storage_unit": "GPDB", "directory": "/data/gpbackup"},
+			want:          "/data/gpbackup/backups/20230112/20230112131415/gpbackup_20230112131415_report",
+		},
+		{
+			name:          "Test ddboost plugin with relative directory",
+			plugin:        BackupDDBoostPlugin,
+			pluginOptions: map[string]string{"storage_unit": "GPDB", "directory": "gp_backups/"},
+			want:          "/gp_backups/backups/20230112/20230112131415/gpbackup_20230112131415_report",
+		},
+		{
+			name:          "Test ddboost plugin without directory",
+			plugin:        BackupDDBoostPlugin,
+			pluginOptions: map[string]string{"storage_unit": "GPDB"},
+			wantErr:       true,
+		},
+		{
+			name:          "Test gcs plugin",
+			plugin:        BackupGCSPlugin,
+			pluginOptions: map[string]string{"bucket": "bucket", "folder": "path/to/folder"},
+			want:          "/path/to/folder/backups/20230112/20230112131415/gpbackup_20230112131415_report",
+		},
+		{
+			name:          "Test azure plugin without folder",
+			plugin:        BackupAzurePlugin,
+			pluginOptions: map[string]string{"container": "container"},
+			wantErr:       true,
+		},
+		{
+			name:   "Test example plugin",
+			plugin: BackupExamplePlugin,
+			want:   "/tmp/plugin_dest/20230112/20230112131415/gpbackup_20230112131415_report",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver, exists := reportPathResolvers[tt.plugin]
+			if !exists {
+				t.Fatalf("report path resolver for plugin %s is not registered", tt.plugin)
+			}
+			got, err := resolver("20230112131415", tt.pluginOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupPluginTemplateReportPath(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		pluginOptions map[string]string
+		want          string
+		wantErr       bool
+	}{
+		{
+			name:          "Test template with option",
+			template:      "{folder}/backups/{date}/{timestamp}",
+			pluginOptions: map[string]string{"folder": "/path/to/folder/"},
+			want:          "/path/to/folder/backups/20230112/20230112131415/gpbackup_20230112131415_report",
+		},
+		{
+			name:     "Test template without options",
+			template: "/archive/{timestamp}/",
+			want:     "/archive/20230112131415/gpbackup_20230112131415_report",
+		},
+		{
+			name:          "Test template with missing option",
+			template:      "{prefix}/{folder}/{timestamp}",
+			pluginOptions: map[string]string{"folder": "folder"},
+			wantErr:       true,
+		},
+		{
+			name:          "Test template with empty option",
+			template:      "{folder}/{timestamp}",
+			pluginOptions: map[string]string{"folder": ""},
+			wantErr:       true,
+		},
+		{
+			name:     "Test empty template",
+			template: "",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backupPluginTemplateReportPath("20230112131415", tt.template, "some_plugin", tt.pluginOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("backupPluginTemplateReportPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterReportPathResolver(t *testing.T) {
+	pluginName := "test_plugin"
+	defer delete(reportPathResolvers, pluginName)
+	RegisterReportPathResolver(pluginName, func(timestamp string, pluginOptions map[string]string) (string, error) {
+		return backupPluginCustomReportPath(timestamp, pluginOptions["path"]), nil
+	})
+	backupConfig := BackupConfig{Timestamp: "20230112131415", Plugin: pluginName}
+	got, err := backupConfig.GetReportFilePathPlugin("", map[string]string{"path": "/test"})
+	if err != nil {
+		t.Fatalf("GetReportFilePathPlugin() error = %v", err)
+	}
+	want := "/test/gpbackup_20230112131415_report"
+	if got != want {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
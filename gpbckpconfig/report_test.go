@@ -0,0 +1,181 @@
+package gpbckpconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testBackupReport = `Greenplum Database Backup Report
+
+timestamp key:            20240102100000
+gpdb version:             6.25.3 build commit:367edc6b4dfd909fe38fc288ade9e294d74e3f9a
+gpbackup version:         1.30.3
+
+database name:            demo
+command line:             gpbackup --dbname demo --incremental --leaf-partition-data --compression-type=zstd --backup-dir /data/backups
+compression:              zstd
+plugin executable:        None
+backup section:           All Sections
+object filtering:         None
+includes statistics:      No
+data file format:         Multiple Data Files Per Segment
+incremental:              True
+incremental backup set:
+20240101100000
+20240102100000
+
+start time:               Tue Jan 02 2024 10:00:00
+end time:                 Tue Jan 02 2024 11:02:03
+duration:                 1:02:03
+
+backup status:            Success
+
+database size:            42 MB
+segment count:            4
+
+count of database objects in backup:
+aggregates                0
+database GUC's            3
+sequences                 1
+tables                    12
+`
+
+func TestParseBackupReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    BackupReport
+		wantErr bool
+	}{
+		{
+			name:    "Test successful incremental backup",
+			content: testBackupReport,
+			want: BackupReport{
+				Timestamp:       "20240102100000",
+				DatabaseVersion: "6.25.3 build commit:367edc6b4dfd909fe38fc288ade9e294d74e3f9a",
+				BackupVersion:   "1.30.3",
+				DatabaseName:    "demo",
+				CommandLine:     "gpbackup --dbname demo --incremental --leaf-partition-data --compression-type=zstd --backup-dir /data/backups",
+				Flags: []BackupReportFlag{
+					{Name: "dbname", Value: "demo"},
+					{Name: "incremental"},
+					{Name: "leaf-partition-data"},
+					{Name: "compression-type", Value: "zstd"},
+					{Name: "backup-dir", Value: "/data/backups"},
+				},
+				Compression:          "zstd",
+				PluginExecutable:     "None",
+				BackupSection:        "All Sections",
+				ObjectFiltering:      "None",
+				DataFileFormat:       "Multiple Data Files Per Segment",
+				Incremental:          true,
+				IncrementalBackupSet: []string{"20240101100000", "20240102100000"},
+				StartTime:            "20240102100000",
+				EndTime:              "20240102110203",
+				Duration:             3723,
+				Status:               BackupStatusSuccess,
+				DatabaseSize:         "42 MB",
+				DatabaseSizeBytes:    42 * 1024 * 1024,
+				SegmentCount:         4,
+				TableCount:           12,
+				ObjectCounts:         map[string]int{"aggregates": 0, "database GUC's": 3, "sequences": 1, "tables": 12},
+			},
+		},
+		{
+			name: "Test failed backup",
+			content: `Greenplum Database Backup Report
+
+timestamp key:         20240101100000
+gpbackup version:      1.30.3
+
+command line:          gpbackup --dbname demo --metadata-only
+includes statistics:   Yes
+incremental:           False
+
+start time:            Mon Jan 01 2024 10:00:00
+end time:              Mon Jan 01 2024 10:00:05
+duration:              0:00:05
+
+backup status:         Failure
+backup error:          ERROR: relation "public.t1" does not exist
+
+segment count:         2
+`,
+			want: BackupReport{
+				Timestamp:            "20240101100000",
+				BackupVersion:        "1.30.3",
+				CommandLine:          "gpbackup --dbname demo --metadata-only",
+				Flags:                []BackupReportFlag{{Name: "dbname", Value: "demo"}, {Name: "metadata-only"}},
+				IncludesStatistics:   true,
+				IncrementalBackupSet: []string{},
+				StartTime:            "20240101100000",
+				EndTime:              "20240101100005",
+				Duration:             5,
+				Status:               BackupStatusFailure,
+				Error:                `ERROR: relation "public.t1" does not exist`,
+				SegmentCount:         2,
+				ObjectCounts:         map[string]int{},
+			},
+		},
+		{
+			name:    "Test not a report",
+			content: "timestamp key: 20240101100000",
+			wantErr: true,
+		},
+		{
+			name:    "Test empty content",
+			content: "",
+			wantErr: true,
+		},
+		{
+			name:    "Test invalid segment count",
+			content: "Greenplum Database Backup Report\n\nsegment count: four\n",
+			wantErr: true,
+		},
+		{
+			name:    "Test invalid object count",
+			content: "Greenplum Database Backup Report\n\ncount of database objects in backup:\ntables many\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBackupReport(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBackupReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%+v\nwant:\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReportSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"Test bytes", "512 BYTES", 512, false},
+		{"Test kilobytes", "8 KB", 8192, false},
+		{"Test gigabytes", "3 GB", 3 * 1024 * 1024 * 1024, false},
+		{"Test unknown unit", "3 XB", 0, true},
+		{"Test invalid value", "3GB", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReportSize(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReportSize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
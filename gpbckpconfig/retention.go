@@ -0,0 +1,166 @@
+package gpbckpconfig
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetentionPolicy describes how many backups should be kept for each database and storage plugin.
+//
+// KeepFull is the number of the latest full backups to keep.
+// KeepDaily, KeepWeekly and KeepMonthly are grandfather-father-son (GFS) rules:
+// the latest full backup of each of the last N days, weeks or months is kept.
+// All rules are combined, a full backup is kept if at least one rule keeps it.
+// Incremental backups are always kept or deleted together with the full backup they are based on.
+type RetentionPolicy struct {
+	KeepFull    uint
+	KeepDaily   uint
+	KeepWeekly  uint
+	KeepMonthly uint
+}
+
+// IsSet Returns true, if at least one retention rule is set.
+func (policy RetentionPolicy) IsSet() bool {
+	return policy.KeepFull > 0 || policy.KeepDaily > 0 || policy.KeepWeekly > 0 || policy.KeepMonthly > 0
+}
+
+// GetBackupNamesForRetention Returns a list of backup names, which are not covered by the retention policy.
+// Backups in progress and already deleted backups are not taken into account.
+// If skipLocalBackup is true, only backups made with storage plugins are taken into account,
+// otherwise only local backups.
+func GetBackupNamesForRetention(policy RetentionPolicy, skipLocalBackup bool, dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	backupNames, err := execQueryFunc(getBackupNameForRetentionQuery(dbFilter), historyDB)
+	if err != nil {
+		return nil, err
+	}
+	backupList := make([]BackupConfig, 0, len(backupNames))
+	for _, backupName := range backupNames {
+		backupData, err := GetBackupDataDB(backupName, historyDB)
+		if err != nil {
+			return nil, err
+		}
+		if backupData.IsLocal() != skipLocalBackup {
+			backupList = append(backupList, backupData)
+		}
+	}
+	return backupNamesOutOfRetention(policy, backupList)
+}
+
+// backupNamesOutOfRetention Returns a list of backup names for deletion, sorted by timestamp in descending order.
+//
+// The following logic is applied for each database and storage plugin:
+//   - successful full backups are selected by retention rules;
+//   - backups from the chain of the selected full backup are kept,
+//     backups from the chain of another successful full backup are deleted;
+//   - other backups (metadata-only, data-only, failed or backups with deleted full backup)
+//     are deleted only if their chain is older than the oldest kept full backup.
+//
+// If there is no successful full backup for the database and storage plugin, nothing is deleted for it.
+func backupNamesOutOfRetention(policy RetentionPolicy, backupList []BackupConfig) ([]string, error) {
+	databaseBackups := make(map[string][]BackupConfig)
+	for _, backupData := range backupList {
+		key := backupData.DatabaseName + "/" + backupData.Plugin
+		databaseBackups[key] = append(databaseBackups[key], backupData)
+	}
+	var result []string
+	for _, backups := range databaseBackups {
+		sort.Slice(backups, func(i, j int) bool {
+			return backups[i].Timestamp > backups[j].Timestamp
+		})
+		fullBackups := make(map[string]bool)
+		var fullBackupList []string
+		for _, backupData := range backups {
+			backupType, err := backupData.GetBackupType()
+			if err != nil {
+				return nil, err
+			}
+			if backupType == BackupTypeFull && backupData.Status == BackupStatusSuccess {
+				fullBackups[backupData.Timestamp] = true
+				fullBackupList = append(fullBackupList, backupData.Timestamp)
+			}
+		}
+		keptFullBackups, err := selectFullBackupsForRetention(policy, fullBackupList)
+		if err != nil {
+			return nil, err
+		}
+		if len(keptFullBackups) == 0 {
+			continue
+		}
+		oldestKeptBackup := ""
+		for timestamp := range keptFullBackups {
+			if oldestKeptBackup == "" || timestamp < oldestKeptBackup {
+				oldestKeptBackup = timestamp
+			}
+		}
+		for _, backupData := range backups {
+			chainRoot := backupData.getChainRoot()
+			switch {
+			case keptFullBackups[chainRoot]:
+				continue
+			case fullBackups[chainRoot] || chainRoot < oldestKeptBackup:
+				result = append(result, backupData.Timestamp)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(result)))
+	return result, nil
+}
+
+// selectFullBackupsForRetention Returns a set of full backups, which are kept by the retention policy.
+// The list of full backups must be sorted by timestamp in descending order.
+func selectFullBackupsForRetention(policy RetentionPolicy, fullBackupList []string) (map[string]bool, error) {
+	result := make(map[string]bool)
+	for i := 0; i < len(fullBackupList) && i < int(policy.KeepFull); i++ {
+		result[fullBackupList[i]] = true
+	}
+	periods := []struct {
+		keep   uint
+		period func(t time.Time) string
+	}{
+		{policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, p := range periods {
+		if p.keep == 0 {
+			continue
+		}
+		seenPeriods := make(map[string]bool)
+		for _, timestamp := range fullBackupList {
+			if uint(len(seenPeriods)) >= p.keep {
+				break
+			}
+			backupTime, err := time.Parse(Layout, timestamp)
+			if err != nil {
+				return nil, err
+			}
+			period := p.period(backupTime)
+			// The first backup is the latest one in the period.
+			if !seenPeriods[period] {
+				seenPeriods[period] = true
+				result[timestamp] = true
+			}
+		}
+	}
+	return result, nil
+}
+
+// getChainRoot Returns the timestamp of the backup on which the backup chain is based.
+// For incremental backups it's the oldest backup from the restore plan, for other backups - the backup itself.
+func (backupConfig BackupConfig) getChainRoot() string {
+	chainRoot := backupConfig.Timestamp
+	if !backupConfig.Incremental {
+		return chainRoot
+	}
+	for _, entry := range backupConfig.RestorePlan {
+		if entry.Timestamp < chainRoot {
+			chainRoot = entry.Timestamp
+		}
+	}
+	return chainRoot
+}
@@ -0,0 +1,236 @@
+package gpbckpconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRetentionPolicyIsSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   bool
+	}{
+		{
+			name:   "Test empty policy",
+			policy: RetentionPolicy{},
+			want:   false,
+		},
+		{
+			name:   "Test keep full",
+			policy: RetentionPolicy{KeepFull: 1},
+			want:   true,
+		},
+		{
+			name:   "Test keep monthly",
+			policy: RetentionPolicy{KeepMonthly: 3},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.IsSet(); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectFullBackupsForRetention(t *testing.T) {
+	fullBackupList := []string{
+		"20240301120000",
+		"20240301100000",
+		"20240229100000",
+		"20240215100000",
+		"20240214100000",
+		"20240120100000",
+	}
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		list    []string
+		want    map[string]bool
+		wantErr bool
+	}{
+		{
+			name:   "Test keep full",
+			policy: RetentionPolicy{KeepFull: 2},
+			list:   fullBackupList,
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240301100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test keep more full backups than exist",
+			policy: RetentionPolicy{KeepFull: 10},
+			list:   fullBackupList[:2],
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240301100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test keep daily",
+			policy: RetentionPolicy{KeepDaily: 2},
+			list:   fullBackupList,
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240229100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test keep weekly",
+			policy: RetentionPolicy{KeepWeekly: 2},
+			list:   fullBackupList,
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240215100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test keep monthly",
+			policy: RetentionPolicy{KeepMonthly: 3},
+			list:   fullBackupList,
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240229100000": true,
+				"20240120100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test combined rules",
+			policy: RetentionPolicy{KeepFull: 1, KeepWeekly: 1, KeepMonthly: 2},
+			list:   fullBackupList,
+			want: map[string]bool{
+				"20240301120000": true,
+				"20240229100000": true,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "Test invalid timestamp",
+			policy:  RetentionPolicy{KeepDaily: 1},
+			list:    []string{"invalid"},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectFullBackupsForRetention(tt.policy, tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("\nselectFullBackupsForRetention() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupNamesOutOfRetention(t *testing.T) {
+	restorePlan := func(timestamps ...string) []RestorePlanEntry {
+		var result []RestorePlanEntry
+		for _, timestamp := range timestamps {
+			result = append(result, RestorePlanEntry{Timestamp: timestamp})
+		}
+		return result
+	}
+	backupList := []BackupConfig{
+		{Timestamp: "20231231100000", DatabaseName: "demo", MetadataOnly: true, Status: BackupStatusSuccess},
+		{Timestamp: "20240101100000", DatabaseName: "demo", Status: BackupStatusSuccess},
+		{Timestamp: "20240102100000", DatabaseName: "demo", Incremental: true, Status: BackupStatusSuccess,
+			RestorePlan: restorePlan("20240101100000", "20240102100000")},
+		{Timestamp: "20240105100000", DatabaseName: "demo", MetadataOnly: true, Status: BackupStatusSuccess},
+		{Timestamp: "20240108100000", DatabaseName: "demo", Status: BackupStatusSuccess},
+		{Timestamp: "20240109100000", DatabaseName: "demo", Incremental: true, Status: BackupStatusSuccess,
+			RestorePlan: restorePlan("20240108100000", "20240109100000")},
+		{Timestamp: "20240110100000", DatabaseName: "demo", Incremental: true, Status: BackupStatusSuccess,
+			RestorePlan: restorePlan("20240108100000", "20240109100000", "20240110100000")},
+		{Timestamp: "20240115100000", DatabaseName: "demo", Status: BackupStatusSuccess},
+		{Timestamp: "20240116100000", DatabaseName: "demo", Status: BackupStatusFailure},
+		{Timestamp: "20240103100000", DatabaseName: "test", Status: BackupStatusSuccess},
+		{Timestamp: "20240104100000", DatabaseName: "meta", MetadataOnly: true, Status: BackupStatusSuccess},
+	}
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		list    []BackupConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "Test keep two full backups with chains",
+			policy: RetentionPolicy{KeepFull: 2},
+			list:   backupList,
+			want: []string{
+				"20240105100000",
+				"20240102100000",
+				"20240101100000",
+				"20231231100000",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test keep one full backup",
+			policy: RetentionPolicy{KeepFull: 1},
+			list:   backupList,
+			want: []string{
+				"20240110100000",
+				"20240109100000",
+				"20240108100000",
+				"20240105100000",
+				"20240102100000",
+				"20240101100000",
+				"20231231100000",
+			},
+			wantErr: false,
+		},
+		{
+			name:   "Test incremental backup with deleted full backup",
+			policy: RetentionPolicy{KeepFull: 1},
+			list: []BackupConfig{
+				{Timestamp: "20240102100000", DatabaseName: "demo", Incremental: true, Status: BackupStatusSuccess,
+					RestorePlan: restorePlan("20240101100000", "20240102100000")},
+				{Timestamp: "20240108100000", DatabaseName: "demo", Status: BackupStatusSuccess},
+			},
+			want:    []string{"20240102100000"},
+			wantErr: false,
+		},
+		{
+			name:    "Test nothing to delete",
+			policy:  RetentionPolicy{KeepMonthly: 1},
+			list:    backupList[9:],
+			want:    nil,
+			wantErr: false,
+		},
+		{
+			name:   "Test invalid backup type",
+			policy: RetentionPolicy{KeepFull: 1},
+			list: []BackupConfig{
+				{Timestamp: "20240101100000", DatabaseName: "demo", Incremental: true, DataOnly: true},
+			},
+			want:    nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := backupNamesOutOfRetention(tt.policy, tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("\nbackupNamesOutOfRetention() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package gpbckpconfig
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+const historyDBSnapshotSuffix = ".snapshot"
+
+// HistoryDBSnapshot contains the information about the snapshot of the history database.
+// The snapshot id is the time when the snapshot was created in the format YYYYMMDDHHMMSS.
+// If several snapshots are created in the same second, the number is added to the id.
+type HistoryDBSnapshot struct {
+	ID   string `json:"id" yaml:"id"`
+	Path string `json:"path" yaml:"path"`
+	Size int64  `json:"size" yaml:"size"`
+}
+
+// HistoryDBSnapshotDir Returns the directory for the snapshots of the history database.
+// If the directory is not set, the directory of the history database is used.
+func HistoryDBSnapshotDir(snapshotDir, historyDBPath string) string {
+	if snapshotDir != "" {
+		return snapshotDir
+	}
+	return filepath.Dir(historyDBPath)
+}
+
+// historyDBSnapshotPath Returns the path to the snapshot file in the format <history db file name>.<id>.snapshot.
+func historyDBSnapshotPath(snapshotDir, historyDBPath, id string) string {
+	return filepath.Join(snapshotDir, filepath.Base(historyDBPath)+"."+id+historyDBSnapshotSuffix)
+}
+
+// CreateHistoryDBSnapshot Creates the consistent copy of the history database in the snapshot directory.
+// The copy is made with VACUUM INTO, so the snapshot contains only committed transactions.
+// If the snapshot with the id already exists, the number is added to the id.
+func CreateHistoryDBSnapshot(historyDB *sql.DB, historyDBPath, snapshotDir, id string) (HistoryDBSnapshot, error) {
+	snapshots, err := GetHistoryDBSnapshots(historyDBPath, snapshotDir)
+	if err != nil {
+		return HistoryDBSnapshot{}, err
+	}
+	// The number is greater than the numbers of all snapshots created in the same second,
+	// so the order of the snapshots is kept, even if some of them have been removed.
+	snapshotID := id
+	for _, snapshot := range snapshots {
+		snapshotTime, snapshotNum := splitSnapshotID(snapshot.ID)
+		if snapshotTime == id && !compareSnapshotID(snapshot.ID, snapshotID) {
+			snapshotID = id + "-" + strconv.Itoa(snapshotNum+1)
+		}
+	}
+	snapshotPath := historyDBSnapshotPath(snapshotDir, historyDBPath, snapshotID)
+	err = withRetry(historyDB, func() error {
+		_, err := historyDB.Exec("VACUUM INTO ?;", snapshotPath)
+		return err
+	})
+	if err != nil {
+		return HistoryDBSnapshot{}, err
+	}
+	info, err := os.Stat(snapshotPath)
+	if err != nil {
+		return HistoryDBSnapshot{}, err
+	}
+	return HistoryDBSnapshot{ID: snapshotID, Path: snapshotPath, Size: info.Size()}, nil
+}
+
+// GetHistoryDBSnapshots Returns the snapshots of the history database from the snapshot directory.
+// The snapshots are sorted from the newest to the oldest.
+func GetHistoryDBSnapshots(historyDBPath, snapshotDir string) ([]HistoryDBSnapshot, error) {
+	prefix := filepath.Base(historyDBPath) + "."
+	paths, err := filepath.Glob(filepath.Join(snapshotDir, prefix+"*"+historyDBSnapshotSuffix))
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]HistoryDBSnapshot, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), prefix), historyDBSnapshotSuffix)
+		snapshots = append(snapshots, HistoryDBSnapshot{ID: id, Path: path, Size: info.Size()})
+	}
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		return compareSnapshotID(snapshots[j].ID, snapshots[i].ID)
+	})
+	return snapshots, nil
+}
+
+// GetHistoryDBSnapshot Returns the snapshot of the history database with the id.
+func GetHistoryDBSnapshot(historyDBPath, snapshotDir, id string) (HistoryDBSnapshot, error) {
+	snapshots, err := GetHistoryDBSnapshots(historyDBPath, snapshotDir)
+	if err != nil {
+		return HistoryDBSnapshot{}, err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ID == id {
+			return snapshot, nil
+		}
+	}
+	return HistoryDBSnapshot{}, textmsg.ErrorHistoryDBSnapshotNotFoundError(id)
+}
+
+// PruneHistoryDBSnapshots Removes the oldest snapshots, so that only the given number of the newest snapshots is kept.
+// Returns the removed snapshots.
+func PruneHistoryDBSnapshots(historyDBPath, snapshotDir string, keep int) ([]HistoryDBSnapshot, error) {
+	snapshots, err := GetHistoryDBSnapshots(historyDBPath, snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []HistoryDBSnapshot
+	for i := keep; i < len(snapshots); i++ {
+		err = os.Remove(snapshots[i].Path)
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, snapshots[i])
+	}
+	return removed, nil
+}
+
+// RestoreHistoryDBSnapshot Replaces the content of the history database with the content of the snapshot.
+// The SQLite backup API is used, so the history database is replaced in a single transaction
+// and the other connections see either the old or the new content.
+// If the history database is busy, the restore is retried.
+func RestoreHistoryDBSnapshot(snapshotPath string, historyDB *sql.DB) error {
+	snapshotDB, err := sql.Open("sqlite3", snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer snapshotDB.Close()
+	ctx := context.Background()
+	snapshotConn, err := snapshotDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer snapshotConn.Close()
+	historyDBConn, err := historyDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer historyDBConn.Close()
+	return withRetry(historyDB, func() error {
+		return historyDBConn.Raw(func(historyDBDriverConn interface{}) error {
+			return snapshotConn.Raw(func(snapshotDriverConn interface{}) error {
+				dest, ok := historyDBDriverConn.(*sqlite3.SQLiteConn)
+				if !ok {
+					return textmsg.ErrorUnexpectedDriverConnectionError("history db")
+				}
+				src, ok := snapshotDriverConn.(*sqlite3.SQLiteConn)
+				if !ok {
+					return textmsg.ErrorUnexpectedDriverConnectionError("snapshot")
+				}
+				return backupDB(dest, src)
+			})
+		})
+	})
+}
+
+// backupDB Copies all pages of the src database into the dest database in one step.
+func backupDB(dest, src *sqlite3.SQLiteConn) error {
+	backup, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return err
+	}
+	done, err := backup.Step(-1)
+	// Finish returns the error of the step, including the busy error.
+	finishErr := backup.Finish()
+	if err != nil {
+		return err
+	}
+	if finishErr != nil {
+		return finishErr
+	}
+	if !done {
+		return sqlite3.Error{Code: sqlite3.ErrBusy}
+	}
+	return nil
+}
+
+// compareSnapshotID Returns true, if the first snapshot was created before the second one.
+// The ids are compared by the time and then by the number.
+func compareSnapshotID(a, b string) bool {
+	timeA, numA := splitSnapshotID(a)
+	timeB, numB := splitSnapshotID(b)
+	if timeA != timeB {
+		return timeA < timeB
+	}
+	return numA < numB
+}
+
+func splitSnapshotID(id string) (string, int) {
+	idTime, idNum, found := strings.Cut(id, "-")
+	if !found {
+		return id, 0
+	}
+	num, _ := strconv.Atoi(idNum)
+	return idTime, num
+}
@@ -0,0 +1,113 @@
+package gpbckpconfig
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryDBSnapshots(t *testing.T) {
+	historyDBPath := filepath.Join(t.TempDir(), "gpbackup_history.db")
+	snapshotDir := t.TempDir()
+	hDB, err := OpenHistoryDB(historyDBPath, HistoryDBOptions{BusyTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB.Close()
+	_, err = hDB.Exec("CREATE TABLE backups (timestamp TEXT); INSERT INTO backups VALUES ('20240101100000');")
+	if err != nil {
+		t.Fatalf("unable to prepare history db: %v", err)
+	}
+	var ids []string
+	for _, id := range []string{"20240101100000", "20240101100000", "20240102100000"} {
+		snapshot, err := CreateHistoryDBSnapshot(hDB, historyDBPath, snapshotDir, id)
+		if err != nil {
+			t.Fatalf("CreateHistoryDBSnapshot() error = %v", err)
+		}
+		ids = append(ids, snapshot.ID)
+	}
+	wantIDs := []string{"20240101100000", "20240101100000-1", "20240102100000"}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", ids, wantIDs)
+	}
+	removed, err := PruneHistoryDBSnapshots(historyDBPath, snapshotDir, 2)
+	if err != nil {
+		t.Fatalf("PruneHistoryDBSnapshots() error = %v", err)
+	}
+	if len(removed) != 1 || removed[0].ID != "20240101100000" {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", removed, "20240101100000")
+	}
+	snapshots, err := GetHistoryDBSnapshots(historyDBPath, snapshotDir)
+	if err != nil {
+		t.Fatalf("GetHistoryDBSnapshots() error = %v", err)
+	}
+	var got []string
+	for _, snapshot := range snapshots {
+		got = append(got, snapshot.ID)
+	}
+	wantIDs = []string{"20240102100000", "20240101100000-1"}
+	if !reflect.DeepEqual(got, wantIDs) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, wantIDs)
+	}
+	_, err = GetHistoryDBSnapshot(historyDBPath, snapshotDir, "20240101100000")
+	if err == nil {
+		t.Errorf("GetHistoryDBSnapshot() expected error for removed snapshot")
+	}
+	// The history database is changed after the snapshot and restored from the snapshot.
+	_, err = hDB.Exec("DELETE FROM backups; CREATE TABLE restore_plans (timestamp TEXT);")
+	if err != nil {
+		t.Fatalf("unable to change history db: %v", err)
+	}
+	snapshot, err := GetHistoryDBSnapshot(historyDBPath, snapshotDir, "20240102100000")
+	if err != nil {
+		t.Fatalf("GetHistoryDBSnapshot() error = %v", err)
+	}
+	err = RestoreHistoryDBSnapshot(snapshot.Path, hDB)
+	if err != nil {
+		t.Fatalf("RestoreHistoryDBSnapshot() error = %v", err)
+	}
+	backupNames, err := execQueryFunc(historyDBQuery{text: "SELECT timestamp FROM backups;"}, hDB)
+	if err != nil {
+		t.Fatalf("unable to read history db: %v", err)
+	}
+	if !reflect.DeepEqual(backupNames, []string{"20240101100000"}) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", backupNames, []string{"20240101100000"})
+	}
+	tables, err := execQueryFunc(historyDBQuery{text: "SELECT name FROM sqlite_master WHERE type = 'table';"}, hDB)
+	if err != nil {
+		t.Fatalf("unable to read history db: %v", err)
+	}
+	if !reflect.DeepEqual(tables, []string{"backups"}) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", tables, []string{"backups"})
+	}
+	// The number is not reused after the snapshot of the same second has been removed.
+	snapshot, err = CreateHistoryDBSnapshot(hDB, historyDBPath, snapshotDir, "20240101100000")
+	if err != nil {
+		t.Fatalf("CreateHistoryDBSnapshot() error = %v", err)
+	}
+	if snapshot.ID != "20240101100000-2" {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", snapshot.ID, "20240101100000-2")
+	}
+}
+
+func TestCompareSnapshotID(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{"Test different time", "20240101100000", "20240102100000", true},
+		{"Test same time without number", "20240101100000", "20240101100000-1", true},
+		{"Test same time with numbers", "20240101100000-10", "20240101100000-2", false},
+		{"Test equal ids", "20240101100000", "20240101100000", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareSnapshotID(tt.a, tt.b); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
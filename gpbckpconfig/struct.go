@@ -2,48 +2,49 @@ package gpbckpconfig
 
 import (
 	"errors"
+	"path/filepath"
 	"strings"
 	"time"
 )
 
 type History struct {
-	BackupConfigs []BackupConfig `yaml:"backupconfigs"`
+	BackupConfigs []BackupConfig `yaml:"backupconfigs" json:"backupconfigs"`
 }
 
 type BackupConfig struct {
-	BackupDir             string             `yaml:"backupdir"`
-	BackupVersion         string             `yaml:"backupversion"`
-	Compressed            bool               `yaml:"compressed"`
-	CompressionType       string             `yaml:"compressiontype"`
-	DatabaseName          string             `yaml:"databasename"`
-	DatabaseVersion       string             `yaml:"databaseversion"`
-	DataOnly              bool               `yaml:"dataonly"`
-	DateDeleted           string             `yaml:"datedeleted"`
-	ExcludeRelations      []string           `yaml:"excluderelations"`
-	ExcludeSchemaFiltered bool               `yaml:"excludeschemafiltered"`
-	ExcludeSchemas        []string           `yaml:"excludeschemas"`
-	ExcludeTableFiltered  bool               `yaml:"excludetablefiltered"`
-	IncludeRelations      []string           `yaml:"includerelations"`
-	IncludeSchemaFiltered bool               `yaml:"includeschemafiltered"`
-	IncludeSchemas        []string           `yaml:"includeschemas"`
-	IncludeTableFiltered  bool               `yaml:"includetablefiltered"`
-	Incremental           bool               `yaml:"incremental"`
-	LeafPartitionData     bool               `yaml:"leafpartitiondata"`
-	MetadataOnly          bool               `yaml:"metadataonly"`
-	Plugin                string             `yaml:"plugin"`
-	PluginVersion         string             `yaml:"pluginversion"`
-	RestorePlan           []RestorePlanEntry `yaml:"restoreplan"`
-	SingleDataFile        bool               `yaml:"singledatafile"`
-	Timestamp             string             `yaml:"timestamp"`
-	EndTime               string             `yaml:"endtime"`
-	WithoutGlobals        bool               `yaml:"withoutgoals"`
-	WithStatistics        bool               `yaml:"withstatistics"`
-	Status                string             `yaml:"status"`
+	BackupDir             string             `yaml:"backupdir" json:"backupdir"`
+	BackupVersion         string             `yaml:"backupversion" json:"backupversion"`
+	Compressed            bool               `yaml:"compressed" json:"compressed"`
+	CompressionType       string             `yaml:"compressiontype" json:"compressiontype"`
+	DatabaseName          string             `yaml:"databasename" json:"databasename"`
+	DatabaseVersion       string             `yaml:"databaseversion" json:"databaseversion"`
+	DataOnly              bool               `yaml:"dataonly" json:"dataonly"`
+	DateDeleted           string             `yaml:"datedeleted" json:"datedeleted"`
+	ExcludeRelations      []string           `yaml:"excluderelations" json:"excluderelations"`
+	ExcludeSchemaFiltered bool               `yaml:"excludeschemafiltered" json:"excludeschemafiltered"`
+	ExcludeSchemas        []string           `yaml:"excludeschemas" json:"excludeschemas"`
+	ExcludeTableFiltered  bool               `yaml:"excludetablefiltered" json:"excludetablefiltered"`
+	IncludeRelations      []string           `yaml:"includerelations" json:"includerelations"`
+	IncludeSchemaFiltered bool               `yaml:"includeschemafiltered" json:"includeschemafiltered"`
+	IncludeSchemas        []string           `yaml:"includeschemas" json:"includeschemas"`
+	IncludeTableFiltered  bool               `yaml:"includetablefiltered" json:"includetablefiltered"`
+	Incremental           bool               `yaml:"incremental" json:"incremental"`
+	LeafPartitionData     bool               `yaml:"leafpartitiondata" json:"leafpartitiondata"`
+	MetadataOnly          bool               `yaml:"metadataonly" json:"metadataonly"`
+	Plugin                string             `yaml:"plugin" json:"plugin"`
+	PluginVersion         string             `yaml:"pluginversion" json:"pluginversion"`
+	RestorePlan           []RestorePlanEntry `yaml:"restoreplan" json:"restoreplan"`
+	SingleDataFile        bool               `yaml:"singledatafile" json:"singledatafile"`
+	Timestamp             string             `yaml:"timestamp" json:"timestamp"`
+	EndTime               string             `yaml:"endtime" json:"endtime"`
+	WithoutGlobals        bool               `yaml:"withoutgoals" json:"withoutgoals"`
+	WithStatistics        bool               `yaml:"withstatistics" json:"withstatistics"`
+	Status                string             `yaml:"status" json:"status"`
 }
 
 type RestorePlanEntry struct {
-	Timestamp string   `yaml:"timestamp"`
-	TableFQNs []string `yaml:"tablefqdn"`
+	Timestamp string   `yaml:"timestamp" json:"timestamp"`
+	TableFQNs []string `yaml:"tablefqdn" json:"tablefqdn"`
 }
 
 const (
@@ -69,6 +70,13 @@ const (
 	DateDeletedLocalFailed  = "Local Delete Failed"
 	// BackupS3Plugin S3 plugin names.
 	BackupS3Plugin = "gpbackup_s3_plugin"
+	// Other storage plugin names with the known report path.
+	BackupDDBoostPlugin = "gpbackup_ddboost_plugin"
+	BackupGCSPlugin     = "gpbackup_gcs_plugin"
+	BackupAzurePlugin   = "gpbackup_azure_plugin"
+	BackupExamplePlugin = "example_plugin"
+	// ReportPathTemplateOption The plugin config option with the user-defined report path template.
+	ReportPathTemplateOption = "gpbackman_report_path_template"
 )
 
 // GetBackupType Get backup type.
@@ -247,21 +255,46 @@ func (backupConfig BackupConfig) IsInProgress() bool {
 
 // GetReportFilePathPlugin Return path to report file name for specific plugin.
 // If custom report path is set, it is returned.
-// Otherwise, the path from plugin is returned.
+// If the report path template is set in the plugin options, the path from the template is returned.
+// Otherwise, the path from the report path resolver registered for the plugin is returned.
 func (backupConfig BackupConfig) GetReportFilePathPlugin(customReportPath string, pluginOptions map[string]string) (string, error) {
 	if customReportPath != "" {
 		return backupPluginCustomReportPath(backupConfig.Timestamp, customReportPath), nil
 	}
-	// In future another plugins may be added.
-	switch backupConfig.Plugin {
-	case BackupS3Plugin:
-		return backupS3PluginReportPath(backupConfig.Timestamp, pluginOptions)
-	default:
-		// nothing to do
+	if template, exists := pluginOptions[ReportPathTemplateOption]; exists {
+		return backupPluginTemplateReportPath(backupConfig.Timestamp, template, backupConfig.Plugin, pluginOptions)
+	}
+	if resolver, exists := reportPathResolvers[backupConfig.Plugin]; exists {
+		return resolver(backupConfig.Timestamp, pluginOptions)
 	}
 	return "", errors.New("the path to the report is not specified")
 }
 
+// GetBackupFilePathPlugin Return path to the backup file on master for specific plugin.
+// The backup files on master are stored in the same directory as the report file,
+// so the path is resolved in the same way as in GetReportFilePathPlugin.
+func (backupConfig BackupConfig) GetBackupFilePathPlugin(fileName, customReportPath string, pluginOptions map[string]string) (string, error) {
+	reportFile, err := backupConfig.GetReportFilePathPlugin(customReportPath, pluginOptions)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(reportFile), fileName), nil
+}
+
+// GetRestorePlanTables Returns the tables from the restore plan of the backup.
+// The key is the table in the format <schema>.<table>,
+// the value is the timestamp of the backup, from which the table data is restored.
+// For incremental backups, the data of unchanged tables is restored from the previous backups of the chain.
+func (backupConfig BackupConfig) GetRestorePlanTables() map[string]string {
+	tables := make(map[string]string)
+	for _, entry := range backupConfig.RestorePlan {
+		for _, table := range entry.TableFQNs {
+			tables[table] = entry.Timestamp
+		}
+	}
+	return tables
+}
+
 // CheckObjectFilteringExists checks if the object filtering exists in the backup.
 //
 // This function is responsible for determining whether table or schema filtering exists in the backup, and if so, whether the specified filter type is being used.
@@ -651,6 +651,53 @@ func TestBackupConfigGetReportFilePathPlugin(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name: "Test some plugin report path if report path template is set",
+			config: BackupConfig{
+				Timestamp: "20220401102430",
+				Plugin:    "some_plugin",
+			},
+			args: args{
+				customReportPath: "",
+				pluginOptions: map[string]string{
+					"folder":                 "/path/to/report",
+					ReportPathTemplateOption: "{folder}/{date}/{timestamp}",
+				},
+			},
+			want:    "/path/to/report/20220401/20220401102430/gpbackup_20220401102430_report",
+			wantErr: false,
+		},
+		{
+			name: "Test s3 plugin report path if report path template is set",
+			config: BackupConfig{
+				Timestamp: "20220401102430",
+				Plugin:    BackupS3Plugin,
+			},
+			args: args{
+				customReportPath: "",
+				pluginOptions: map[string]string{
+					"folder":                 "/path/to/report",
+					ReportPathTemplateOption: "{folder}/{timestamp}",
+				},
+			},
+			want:    "/path/to/report/20220401102430/gpbackup_20220401102430_report",
+			wantErr: false,
+		},
+		{
+			name: "Test ddboost plugin report path if custom report path is not set",
+			config: BackupConfig{
+				Timestamp: "20220401102430",
+				Plugin:    BackupDDBoostPlugin,
+			},
+			args: args{
+				customReportPath: "",
+				pluginOptions: map[string]string{
+					"directory": "gp/backups",
+				},
+			},
+			want:    "/gp/backups/backups/20220401/20220401102430/gpbackup_20220401102430_report",
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1017,3 +1064,62 @@ func TestIsInProgress(t *testing.T) {
 		})
 	}
 }
+
+func TestBackupConfigGetBackupFilePathPlugin(t *testing.T) {
+	tests := []struct {
+		name             string
+		config           BackupConfig
+		customReportPath string
+		pluginOptions    map[string]string
+		want             string
+		wantErr          bool
+	}{
+		{
+			name:             "Test custom report path",
+			config:           BackupConfig{Timestamp: "20220401102430", Plugin: "some_plugin"},
+			customReportPath: "/path/to/report",
+			want:             "/path/to/report/gpbackup_20220401102430_config.yaml",
+		},
+		{
+			name:          "Test s3 plugin",
+			config:        BackupConfig{Timestamp: "20220401102430", Plugin: BackupS3Plugin},
+			pluginOptions: map[string]string{"folder": "/path/to/folder"},
+			want:          "/path/to/folder/backups/20220401/20220401102430/gpbackup_20220401102430_config.yaml",
+		},
+		{
+			name:    "Test unknown plugin",
+			config:  BackupConfig{Timestamp: "20220401102430", Plugin: "some_plugin"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.GetBackupFilePathPlugin(ConfigFileName(tt.config.Timestamp), tt.customReportPath, tt.pluginOptions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BackupConfig.GetBackupFilePathPlugin() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupConfigGetRestorePlanTables(t *testing.T) {
+	config := BackupConfig{
+		Timestamp: "20240102100000",
+		RestorePlan: []RestorePlanEntry{
+			{Timestamp: "20240101100000", TableFQNs: []string{"public.ao_table"}},
+			{Timestamp: "20240102100000", TableFQNs: []string{"public.heap_table", "sales.orders"}},
+		},
+	}
+	want := map[string]string{
+		"public.ao_table":   "20240101100000",
+		"public.heap_table": "20240102100000",
+		"sales.orders":      "20240102100000",
+	}
+	got := config.GetRestorePlanTables()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+}
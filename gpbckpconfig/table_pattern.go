@@ -0,0 +1,109 @@
+package gpbckpconfig
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/woblerr/gpbackman/textmsg"
+)
+
+// TablePattern describes the patterns for the schema and table names.
+//
+// By default, the patterns are glob patterns with the same syntax as in path.Match:
+// '*' matches any sequence of characters, '?' matches any single character,
+// '[...]' matches the character class.
+// If Regex is set, the patterns are regular expressions in RE2 syntax.
+// The pattern must match the whole name. The empty pattern matches any name.
+type TablePattern struct {
+	Schema   string
+	Table    string
+	Regex    bool
+	schemaRe *regexp.Regexp
+	tableRe  *regexp.Regexp
+}
+
+// NewTablePattern Returns the pattern for the schema and table names.
+// If any pattern is invalid, the error will be returned.
+func NewTablePattern(schemaPattern, tablePattern string, isRegex bool) (TablePattern, error) {
+	pattern := TablePattern{Schema: schemaPattern, Table: tablePattern, Regex: isRegex}
+	var err error
+	if isRegex {
+		if pattern.schemaRe, err = compileNamePattern(schemaPattern); err != nil {
+			return TablePattern{}, err
+		}
+		if pattern.tableRe, err = compileNamePattern(tablePattern); err != nil {
+			return TablePattern{}, err
+		}
+		return pattern, nil
+	}
+	for _, value := range []string{schemaPattern, tablePattern} {
+		if _, err = path.Match(value, ""); err != nil {
+			return TablePattern{}, err
+		}
+	}
+	return pattern, nil
+}
+
+// Match Returns true, if the table FQN in the format <schema>.<table> matches the pattern.
+// The names are compared without quotes.
+func (pattern TablePattern) Match(tableFQN string) bool {
+	schemaName, tableName, err := SplitTableFQN(tableFQN)
+	if err != nil {
+		return false
+	}
+	if pattern.Regex {
+		return matchNameRegex(pattern.schemaRe, schemaName) && matchNameRegex(pattern.tableRe, tableName)
+	}
+	return matchNameGlob(pattern.Schema, schemaName) && matchNameGlob(pattern.Table, tableName)
+}
+
+// SplitTableFQN Returns the schema and table names from the table FQN in the format <schema>.<table>.
+// The quoted identifiers are unquoted, for example, "my.schema"."My""Table" is split to my.schema and My"Table.
+// If the table FQN is not in the format <schema>.<table>, the error will be returned.
+func SplitTableFQN(tableFQN string) (string, string, error) {
+	inQuotes := false
+	for idx, char := range tableFQN {
+		switch {
+		case char == '"':
+			inQuotes = !inQuotes
+		case char == '.' && !inQuotes:
+			schemaName, tableName := unquoteIdent(tableFQN[:idx]), unquoteIdent(tableFQN[idx+1:])
+			if schemaName == "" || tableName == "" {
+				return "", "", textmsg.ErrorValidationTableFQN()
+			}
+			return schemaName, tableName, nil
+		}
+	}
+	return "", "", textmsg.ErrorValidationTableFQN()
+}
+
+// unquoteIdent Returns the identifier without quotes.
+// If the identifier is not quoted, it is returned as is.
+func unquoteIdent(ident string) string {
+	if len(ident) >= 2 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return ident
+}
+
+// compileNamePattern Compiles the regular expression, that must match the whole name.
+// The empty pattern is not compiled, it matches any name.
+func compileNamePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+func matchNameRegex(re *regexp.Regexp, name string) bool {
+	return re == nil || re.MatchString(name)
+}
+
+func matchNameGlob(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
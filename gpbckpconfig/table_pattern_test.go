@@ -0,0 +1,127 @@
+package gpbckpconfig
+
+import (
+	"testing"
+)
+
+func TestSplitTableFQN(t *testing.T) {
+	tests := []struct {
+		name       string
+		tableFQN   string
+		wantSchema string
+		wantTable  string
+		wantErr    bool
+	}{
+		{
+			name:       "Test simple names",
+			tableFQN:   "public.orders",
+			wantSchema: "public",
+			wantTable:  "orders",
+		},
+		{
+			name:       "Test quoted names",
+			tableFQN:   `"my.schema"."My""Table"`,
+			wantSchema: "my.schema",
+			wantTable:  `My"Table`,
+		},
+		{
+			name:       "Test dot in table name",
+			tableFQN:   "public.orders.2024",
+			wantSchema: "public",
+			wantTable:  "orders.2024",
+		},
+		{
+			name:     "Test without schema",
+			tableFQN: "orders",
+			wantErr:  true,
+		},
+		{
+			name:     "Test empty table name",
+			tableFQN: "public.",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSchema, gotTable, err := SplitTableFQN(tt.tableFQN)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitTableFQN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if gotSchema != tt.wantSchema || gotTable != tt.wantTable {
+				t.Errorf("\nVariables do not match:\n%v %v\nwant:\n%v %v", gotSchema, gotTable, tt.wantSchema, tt.wantTable)
+			}
+		})
+	}
+}
+
+func TestTablePatternMatch(t *testing.T) {
+	tests := []struct {
+		name          string
+		schemaPattern string
+		tablePattern  string
+		isRegex       bool
+		tableFQN      string
+		want          bool
+	}{
+		{
+			name:          "Test glob match",
+			schemaPattern: "sales_*",
+			tablePattern:  "orders_202?",
+			tableFQN:      "sales_eu.orders_2024",
+			want:          true,
+		},
+		{
+			name:          "Test glob mismatch",
+			schemaPattern: "sales_*",
+			tableFQN:      "public.orders",
+			want:          false,
+		},
+		{
+			name:         "Test glob with quoted names",
+			tablePattern: "My*",
+			tableFQN:     `public."MyTable"`,
+			want:         true,
+		},
+		{
+			name:          "Test regex match",
+			schemaPattern: "public|sales",
+			tablePattern:  `orders_\d+`,
+			isRegex:       true,
+			tableFQN:      "sales.orders_2024",
+			want:          true,
+		},
+		{
+			name:         "Test regex matches the whole name",
+			tablePattern: "orders",
+			isRegex:      true,
+			tableFQN:     "public.orders_2024",
+			want:         false,
+		},
+		{
+			name:         "Test invalid table FQN",
+			tablePattern: "*",
+			tableFQN:     "orders",
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, err := NewTablePattern(tt.schemaPattern, tt.tablePattern, tt.isRegex)
+			if err != nil {
+				t.Fatalf("NewTablePattern() error = %v", err)
+			}
+			if got := pattern.Match(tt.tableFQN); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewTablePatternInvalid(t *testing.T) {
+	if _, err := NewTablePattern("[public", "", false); err == nil {
+		t.Errorf("NewTablePattern() error = %v, want error", err)
+	}
+	if _, err := NewTablePattern("", "orders(", true); err == nil {
+		t.Errorf("NewTablePattern() error = %v, want error", err)
+	}
+}
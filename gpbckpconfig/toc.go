@@ -0,0 +1,52 @@
+package gpbckpconfig
+
+import (
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const tocObjectTypeTable = "TABLE"
+
+// BackupTOC contains the entries from the gpbackup toc file (gpbackup_<YYYYMMDDHHMMSS>_toc.yaml) on master,
+// that are required to get the tables of the backup.
+// See toc package in https://github.com/greenplum-db/gpbackup.
+type BackupTOC struct {
+	PredataEntries []BackupTOCEntry `yaml:"predataentries"`
+	DataEntries    []BackupTOCEntry `yaml:"dataentries"`
+}
+
+// BackupTOCEntry contains the object from the toc file.
+// The schema and name are quoted in the same way as in the restore plan of the history database.
+type BackupTOCEntry struct {
+	Schema     string `yaml:"schema"`
+	Name       string `yaml:"name"`
+	ObjectType string `yaml:"objecttype"`
+}
+
+// ParseBackupTOC Parses the content of the gpbackup toc file.
+func ParseBackupTOC(content []byte) (BackupTOC, error) {
+	var backupTOC BackupTOC
+	err := yaml.Unmarshal(content, &backupTOC)
+	return backupTOC, err
+}
+
+// GetTables Returns the sorted list of tables in the format <schema>.<table>,
+// which metadata or data is in the backup.
+func (backupTOC BackupTOC) GetTables() []string {
+	tables := make(map[string]bool)
+	for _, entry := range backupTOC.PredataEntries {
+		if entry.ObjectType == tocObjectTypeTable {
+			tables[entry.Schema+"."+entry.Name] = true
+		}
+	}
+	for _, entry := range backupTOC.DataEntries {
+		tables[entry.Schema+"."+entry.Name] = true
+	}
+	result := make([]string, 0, len(tables))
+	for table := range tables {
+		result = append(result, table)
+	}
+	sort.Strings(result)
+	return result
+}
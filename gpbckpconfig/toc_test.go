@@ -0,0 +1,43 @@
+package gpbckpconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBackupTOC(t *testing.T) {
+	content := []byte(`globalentries:
+- schema: ""
+  name: gpadmin
+  objecttype: ROLE
+predataentries:
+- schema: public
+  name: public
+  objecttype: SCHEMA
+- schema: public
+  name: heap_table
+  objecttype: TABLE
+- schema: sales
+  name: '"Orders"'
+  objecttype: TABLE
+- schema: public
+  name: heap_view
+  objecttype: VIEW
+dataentries:
+- schema: public
+  name: heap_table
+  oid: 16384
+  rowscopied: 10
+`)
+	backupTOC, err := ParseBackupTOC(content)
+	if err != nil {
+		t.Fatalf("ParseBackupTOC() error = %v", err)
+	}
+	want := []string{"public.heap_table", `sales."Orders"`}
+	if got := backupTOC.GetTables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	if _, err := ParseBackupTOC([]byte("predataentries: unexpected")); err == nil {
+		t.Errorf("ParseBackupTOC() error = %v, want error", err)
+	}
+}
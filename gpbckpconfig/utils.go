@@ -59,6 +59,13 @@ func IsBackupActive(dateDeleted string) bool {
 		dateDeleted == DateDeletedLocalFailed)
 }
 
+// IsBackupDeletionUnfinished Returns true if backup deletion is in progress or failed.
+func IsBackupDeletionUnfinished(dateDeleted string) bool {
+	return (dateDeleted == DateDeletedInProgress ||
+		dateDeleted == DateDeletedPluginFailed ||
+		dateDeleted == DateDeletedLocalFailed)
+}
+
 // IsPositiveValue Returns true if the value is positive.
 func IsPositiveValue(value int) bool {
 	return value > 0
@@ -79,13 +86,23 @@ func backupPluginCustomReportPath(timestamp, folderValue string) string {
 // See GetS3Path() func in https://github.com/greenplum-db/gpbackup-s3-plugin.
 // If folder option is not specified or it is empty, the error will be returned.
 func backupS3PluginReportPath(timestamp string, pluginOptions map[string]string) (string, error) {
+	return backupFolderPluginReportPath(BackupS3Plugin, timestamp, pluginOptions)
+}
+
+// backupFolderPluginReportPath Returns path to report file name for the object storage plugins,
+// that use the folder option in the same way as gpbackup_s3_plugin:
+//
+//	<folder>/backups/<YYYYMMDD>/<YYYYMMDDHHMMSS>/gpbackup_<YYYYMMDDHHMMSS>_report
+//
+// If folder option is not specified or it is empty, the error will be returned.
+func backupFolderPluginReportPath(pluginName, timestamp string, pluginOptions map[string]string) (string, error) {
 	pathOption := "folder"
 	// Timestamp validation is done on flags validation.
 	// We assume, that is the correct value coming from.
 	reportPathBasic := "backups/" + timestamp[0:8] + "/" + timestamp
 	folderValue, exists := pluginOptions[pathOption]
 	if !exists || folderValue == "" {
-		return "", textmsg.ErrorValidationPluginOption(pathOption, BackupS3Plugin)
+		return "", textmsg.ErrorValidationPluginOption(pathOption, pluginName)
 	}
 	// It's necessary to return full path to report file with leading '/'.
 	// But in config file folder value could be with leading '/' or without.
@@ -101,6 +118,27 @@ func ReportFileName(timestamp string) string {
 	return "gpbackup_" + timestamp + "_report"
 }
 
+// ConfigFileName Returns the name of the backup config file.
+func ConfigFileName(timestamp string) string {
+	return "gpbackup_" + timestamp + "_config.yaml"
+}
+
+// TocFileName Returns the name of the backup toc file on master.
+func TocFileName(timestamp string) string {
+	return "gpbackup_" + timestamp + "_toc.yaml"
+}
+
+// MetadataFileName Returns the name of the backup metadata file.
+func MetadataFileName(timestamp string) string {
+	return "gpbackup_" + timestamp + "_metadata.sql"
+}
+
+// SegmentTocFileName Returns the name of the backup toc file on segment.
+// The file exists only for backups with single data file.
+func SegmentTocFileName(contentID, timestamp string) string {
+	return "gpbackup_" + contentID + "_" + timestamp + "_toc.yaml"
+}
+
 // CheckMasterBackupDir checks the backup directory for the master backup.
 // It first tries to find the backup directory in the single-backup-dir format.
 // If the single-backup-dir format is not used, it returns an error.
@@ -153,6 +191,20 @@ func BackupDirPath(backupDir, timestamp string) string {
 	return filepath.Join(backupDir, "backups", timestamp[0:8], timestamp)
 }
 
+// GetTimestampFromBackupDirPath Returns backup timestamp from the path to full backup directory.
+// The path must be in the format <backup_dir>/backups/YYYYMMDD/YYYYMMDDHHMMSS,
+// otherwise an error is returned.
+func GetTimestampFromBackupDirPath(backupDirPath string) (string, error) {
+	timestamp := filepath.Base(backupDirPath)
+	dateDir := filepath.Dir(backupDirPath)
+	if CheckTimestamp(timestamp) != nil ||
+		filepath.Base(dateDir) != timestamp[0:8] ||
+		filepath.Base(filepath.Dir(dateDir)) != "backups" {
+		return "", textmsg.ErrorValidationBackupDirPath()
+	}
+	return timestamp, nil
+}
+
 // searchFilter returns true if the value is present in the list
 func searchFilter(list []string, value string) bool {
 	for _, item := range list {
@@ -1,121 +1,402 @@
 package gpbckpconfig
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/greenplum-db/gpbackup/history"
+	"github.com/mattn/go-sqlite3"
+	"github.com/woblerr/gpbackman/textmsg"
 )
 
+var execSleep = time.Sleep
+
+// HistoryDBOptions contains the options for the history database connection.
+//
+// BusyTimeout is the time to wait for the lock held by another connection (for example, by gpbackup).
+// If the history database is still busy after the timeout,
+// the operation is retried Retries times with the exponential backoff starting from RetryDelay.
+type HistoryDBOptions struct {
+	BusyTimeout time.Duration
+	Retries     int
+	RetryDelay  time.Duration
+}
+
+// defaultHistoryDBOptions contains the options for the history database handles,
+// that are not opened by OpenHistoryDB.
+var defaultHistoryDBOptions = HistoryDBOptions{
+	Retries:    3,
+	RetryDelay: time.Second,
+}
+
+// historyDBDriver is the sqlite3 driver, that keeps the options of the history database handle.
+// Each handle opened by OpenHistoryDB has its own driver, so the options are taken from the handle.
+type historyDBDriver struct {
+	sqlite3.SQLiteDriver
+	opts HistoryDBOptions
+}
+
+// historyDBConnector opens the connections of the history database handle with the options of the handle.
+type historyDBConnector struct {
+	dsn    string
+	driver *historyDBDriver
+}
+
+func (c *historyDBConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *historyDBConnector) Driver() driver.Driver {
+	return c.driver
+}
+
 // OpenHistoryDB Opens the history backup database.
-func OpenHistoryDB(historyDBPath string) (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", historyDBPath)
-	if err != nil {
-		return nil, err
+//
+// The busy timeout is set for each connection, so the operations wait for the lock
+// instead of failing immediately with "database is locked" error.
+// The write transactions are started in IMMEDIATE mode, so the write lock is taken at the beginning of the transaction.
+// In WAL journal mode, the deferred transaction can't be upgraded from read to write while another connection writes,
+// and the busy timeout is not applied in this case.
+// The journal mode of the history database is not changed.
+// The retry options are kept in the returned handle and are used for all operations with it.
+func OpenHistoryDB(historyDBPath string, opts HistoryDBOptions) (*sql.DB, error) {
+	return sql.OpenDB(&historyDBConnector{
+		dsn:    historyDBDSN(historyDBPath, opts.BusyTimeout),
+		driver: &historyDBDriver{opts: opts},
+	}), nil
+}
+
+func historyDBDSN(historyDBPath string, busyTimeout time.Duration) string {
+	params := url.Values{}
+	params.Set("_busy_timeout", strconv.FormatInt(busyTimeout.Milliseconds(), 10))
+	params.Set("_txlock", "immediate")
+	return historyDBPath + "?" + params.Encode()
+}
+
+// getHistoryDBOptions Returns the options of the history database handle.
+// For the handles, that are not opened by OpenHistoryDB, the default options are returned.
+func getHistoryDBOptions(historyDB *sql.DB) HistoryDBOptions {
+	if d, ok := historyDB.Driver().(*historyDBDriver); ok {
+		return d.opts
+	}
+	return defaultHistoryDBOptions
+}
+
+// isHistoryDBBusy Returns true, if the error occurs because the history database is locked by another connection.
+func isHistoryDBBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
+
+// withRetry Executes the function and retries it, if the history database is busy.
+// The number of retries and the delay are taken from the options of the history database handle.
+// The delay between attempts is doubled after each attempt.
+func withRetry(historyDB *sql.DB, fn func() error) error {
+	opts := getHistoryDBOptions(historyDB)
+	delay := opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isHistoryDBBusy(err) || attempt >= opts.Retries {
+			return err
+		}
+		execSleep(delay)
+		delay *= 2
 	}
-	return db, nil
 }
 
 // GetBackupDataDB Read backup data from history database and return BackupConfig struct.
+// If the history database is busy, the reading is retried.
 func GetBackupDataDB(backupName string, hDB *sql.DB) (BackupConfig, error) {
-	hBackupData, err := history.GetBackupConfig(backupName, hDB)
+	var hBackupData *history.BackupConfig
+	err := withRetry(hDB, func() error {
+		var err error
+		hBackupData, err = history.GetBackupConfig(backupName, hDB)
+		return err
+	})
 	if err != nil {
 		return BackupConfig{}, err
 	}
 	return ConvertFromHistoryBackupConfig(hBackupData), nil
 }
 
+// DatabaseFilter describes the databases, which backups are selected.
+// If Include is set, only backups of the specified databases are selected.
+// If Exclude is set, backups of the specified databases are skipped.
+type DatabaseFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// IsSet Returns true, if at least one database is specified in the filter.
+func (dbFilter DatabaseFilter) IsSet() bool {
+	return len(dbFilter.Include) > 0 || len(dbFilter.Exclude) > 0
+}
+
+// Match Returns true, if backup of the database should be selected.
+func (dbFilter DatabaseFilter) Match(databaseName string) bool {
+	if len(dbFilter.Include) > 0 && !searchFilter(dbFilter.Include, databaseName) {
+		return false
+	}
+	return !searchFilter(dbFilter.Exclude, databaseName)
+}
+
+// Returns the condition for the database_name column and the values for its placeholders
+// or empty string, if the filter is not set.
+func (dbFilter DatabaseFilter) sqlCondition() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if len(dbFilter.Include) > 0 {
+		conditions = append(conditions, fmt.Sprintf("database_name IN (%s)", sqlPlaceholders(len(dbFilter.Include))))
+		args = append(args, sqlArgs(dbFilter.Include...)...)
+	}
+	if len(dbFilter.Exclude) > 0 {
+		conditions = append(conditions, fmt.Sprintf("database_name NOT IN (%s)", sqlPlaceholders(len(dbFilter.Exclude))))
+		args = append(args, sqlArgs(dbFilter.Exclude...)...)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// Returns the condition line for multiline queries and the values for its placeholders.
+func (dbFilter DatabaseFilter) sqlConditionLine() (string, []interface{}) {
+	if !dbFilter.IsSet() {
+		return "", nil
+	}
+	condition, args := dbFilter.sqlCondition()
+	return fmt.Sprintf("\tAND %s \n", condition), args
+}
+
+// historyDBQuery contains the query text with placeholders and the values for the placeholders.
+// The values are never inserted into the query text, they are passed to the driver separately.
+type historyDBQuery struct {
+	text string
+	args []interface{}
+}
+
+// historyDBChildTables contains the tables of the history database,
+// which rows belong to the backup from the backups table.
+var historyDBChildTables = []string{
+	"restore_plans",
+	"restore_plan_tables",
+	"exclude_relations",
+	"exclude_schemas",
+	"include_relations",
+	"include_schemas",
+}
+
+// historyDBTables contains the tables of the history database with the backup information.
+// Each table has the timestamp column with the backup name.
+var historyDBTables = append([]string{"backups"}, historyDBChildTables...)
+
+// HistoryDBOrphanRows contains the backup names from the table of the history database,
+// for which there is no row in the backups table.
+type HistoryDBOrphanRows struct {
+	Table       string
+	BackupNames []string
+}
+
+// Returns a list of n placeholders, separated by commas.
+func sqlPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// Returns the values as arguments for the placeholders.
+func sqlArgs(values ...string) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
+
 // GetBackupNamesDB Returns a list of backup names.
-func GetBackupNamesDB(showD, showF bool, historyDB *sql.DB) ([]string, error) {
-	return execQueryFunc(getBackupNameQuery(showD, showF), historyDB)
+func GetBackupNamesDB(showD, showF bool, dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	return execQueryFunc(getBackupNameQuery(showD, showF, dbFilter), historyDB)
 }
 
 func GetBackupDependencies(backupName string, historyDB *sql.DB) ([]string, error) {
 	return execQueryFunc(getBackupDependenciesQuery(backupName), historyDB)
 }
 
-func GetBackupNamesBeforeTimestamp(timestamp string, historyDB *sql.DB) ([]string, error) {
-	return execQueryFunc(getBackupNameBeforeTimestampQuery(timestamp), historyDB)
+func GetBackupNamesBeforeTimestamp(timestamp string, dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	return execQueryFunc(getBackupNameBeforeTimestampQuery(timestamp, dbFilter), historyDB)
 }
 
-func GetBackupNamesAfterTimestamp(timestamp string, historyDB *sql.DB) ([]string, error) {
-	return execQueryFunc(getBackupNameAfterTimestampQuery(timestamp), historyDB)
+func GetBackupNamesAfterTimestamp(timestamp string, dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	return execQueryFunc(getBackupNameAfterTimestampQuery(timestamp, dbFilter), historyDB)
 }
 
-func GetBackupNamesForCleanBeforeTimestamp(timestamp string, historyDB *sql.DB) ([]string, error) {
-	return execQueryFunc(getBackupNameForCleanBeforeTimestampQuery(timestamp), historyDB)
+func GetBackupNamesForCleanBeforeTimestamp(timestamp string, dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	return execQueryFunc(getBackupNameForCleanBeforeTimestampQuery(timestamp, dbFilter), historyDB)
 }
 
-func getBackupNameQuery(showD, showF bool) string {
+// GetBackupNamesForRepair Returns a list of backup names, which deletion is in progress or failed.
+func GetBackupNamesForRepair(dbFilter DatabaseFilter, historyDB *sql.DB) ([]string, error) {
+	return execQueryFunc(getBackupNameForRepairQuery(dbFilter), historyDB)
+}
+
+// GetOrphanRowsDB Returns the backup names from the tables of the history database,
+// for which there is no row in the backups table. Only tables with such rows are returned.
+func GetOrphanRowsDB(historyDB *sql.DB) ([]HistoryDBOrphanRows, error) {
+	var result []HistoryDBOrphanRows
+	for _, table := range historyDBChildTables {
+		backupNames, err := execQueryFunc(getOrphanBackupNamesQuery(table), historyDB)
+		if err != nil {
+			return nil, err
+		}
+		if len(backupNames) > 0 {
+			result = append(result, HistoryDBOrphanRows{Table: table, BackupNames: backupNames})
+		}
+	}
+	return result, nil
+}
+
+// DeleteOrphanRowsDB Deletes the rows without the row in the backups table from all tables of the history database.
+// The rows are deleted in a single transaction.
+func DeleteOrphanRowsDB(historyDB *sql.DB) error {
+	queries := make([]historyDBQuery, 0, len(historyDBChildTables))
+	for _, table := range historyDBChildTables {
+		queries = append(queries, deleteOrphanRowsQuery(table))
+	}
+	return execStatementsFunc(queries, historyDB)
+}
+
+func getBackupNameQuery(showD, showF bool, dbFilter DatabaseFilter) historyDBQuery {
 	orderBy := "ORDER BY timestamp DESC;"
 	getBackupsQuery := "SELECT timestamp FROM backups"
+	var conditions []string
+	var args []interface{}
 	switch {
 	// Displaying all backups (active, deleted, failed)
 	case showD && showF:
-		getBackupsQuery = fmt.Sprintf("%s %s", getBackupsQuery, orderBy)
 	// Displaying only active and deleted backups; failed - hidden.
 	case showD && !showF:
-		getBackupsQuery = fmt.Sprintf("%s WHERE status != '%s' %s", getBackupsQuery, BackupStatusFailure, orderBy)
+		conditions = append(conditions, "status != ?")
+		args = append(args, BackupStatusFailure)
 	// Displaying only active and failed backups; deleted - hidden.
 	case !showD && showF:
-		getBackupsQuery = fmt.Sprintf("%s WHERE date_deleted IN ('', '%s', '%s', '%s') %s", getBackupsQuery, DateDeletedInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed, orderBy)
+		conditions = append(conditions, "date_deleted IN ('', ?, ?, ?)")
+		args = append(args, DateDeletedInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed)
 	// Displaying only active backups or backups with deletion status "In progress", deleted and failed - hidden.
 	default:
-		getBackupsQuery = fmt.Sprintf("%s WHERE status != '%s' AND date_deleted IN ('', '%s', '%s', '%s') %s", getBackupsQuery, BackupStatusFailure, DateDeletedInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed, orderBy)
+		conditions = append(conditions, "status != ?", "date_deleted IN ('', ?, ?, ?)")
+		args = append(args, BackupStatusFailure, DateDeletedInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed)
+	}
+	if dbFilter.IsSet() {
+		condition, conditionArgs := dbFilter.sqlCondition()
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+	if len(conditions) > 0 {
+		getBackupsQuery = fmt.Sprintf("%s WHERE %s", getBackupsQuery, strings.Join(conditions, " AND "))
+	}
+	return historyDBQuery{
+		text: fmt.Sprintf("%s %s", getBackupsQuery, orderBy),
+		args: args,
 	}
-	return getBackupsQuery
 }
 
-func getBackupDependenciesQuery(backupName string) string {
-	return fmt.Sprintf(`
+func getBackupDependenciesQuery(backupName string) historyDBQuery {
+	return historyDBQuery{
+		text: `
 SELECT timestamp 
 FROM restore_plans
-WHERE timestamp != '%s'
-	AND restore_plan_timestamp = '%s'
+WHERE timestamp != ?
+	AND restore_plan_timestamp = ?
 ORDER BY timestamp DESC;
-`, backupName, backupName)
+`,
+		args: []interface{}{backupName, backupName},
+	}
 }
 
 // Only active backups, "In progress", deleted and failed statuses - hidden.
-func getBackupNameBeforeTimestampQuery(timestamp string) string {
-	return fmt.Sprintf(`
+func getBackupNameBeforeTimestampQuery(timestamp string, dbFilter DatabaseFilter) historyDBQuery {
+	condition, conditionArgs := dbFilter.sqlConditionLine()
+	return historyDBQuery{
+		text: fmt.Sprintf(`
 SELECT timestamp 
 FROM backups 
-WHERE timestamp < '%s' 
-	AND status != '%s' 
-	AND date_deleted IN ('', '%s', '%s') 
-ORDER BY timestamp DESC;
-`, timestamp, BackupStatusInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed)
+WHERE timestamp < ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
+%sORDER BY timestamp DESC;
+`, condition),
+		args: append([]interface{}{timestamp, BackupStatusInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed}, conditionArgs...),
+	}
 }
 
 // Only active backups, "In progress", deleted and failed statuses - hidden.
-func getBackupNameAfterTimestampQuery(timestamp string) string {
-	return fmt.Sprintf(`
+func getBackupNameAfterTimestampQuery(timestamp string, dbFilter DatabaseFilter) historyDBQuery {
+	condition, conditionArgs := dbFilter.sqlConditionLine()
+	return historyDBQuery{
+		text: fmt.Sprintf(`
 SELECT timestamp 
 FROM backups 
-WHERE timestamp > '%s' 
-	AND status != '%s' 
-	AND date_deleted IN ('', '%s', '%s') 
-ORDER BY timestamp DESC;
-`, timestamp, BackupStatusInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed)
+WHERE timestamp > ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
+%sORDER BY timestamp DESC;
+`, condition),
+		args: append([]interface{}{timestamp, BackupStatusInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed}, conditionArgs...),
+	}
 }
 
-// Only deleted backups.
-func getBackupNameForCleanBeforeTimestampQuery(timestamp string) string {
-	return fmt.Sprintf(`
+// Only active backups, "In progress", deleted and failed statuses - hidden.
+func getBackupNameForRetentionQuery(dbFilter DatabaseFilter) historyDBQuery {
+	condition, conditionArgs := dbFilter.sqlConditionLine()
+	return historyDBQuery{
+		text: fmt.Sprintf(`
 SELECT timestamp 
 FROM backups 
-WHERE timestamp < '%s' 
-	AND date_deleted NOT IN ('', '%s', '%s', '%s') 
-ORDER BY timestamp DESC;
-`, timestamp, DateDeletedPluginFailed, DateDeletedLocalFailed, DateDeletedInProgress)
+WHERE status != ? 
+	AND date_deleted IN ('', ?, ?) 
+%sORDER BY timestamp DESC;
+`, condition),
+		args: append([]interface{}{BackupStatusInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed}, conditionArgs...),
+	}
+}
+
+// Only backups with "In progress" and failed deletion statuses.
+func getBackupNameForRepairQuery(dbFilter DatabaseFilter) historyDBQuery {
+	condition, conditionArgs := dbFilter.sqlConditionLine()
+	return historyDBQuery{
+		text: fmt.Sprintf(`
+SELECT timestamp 
+FROM backups 
+WHERE date_deleted IN (?, ?, ?) 
+%sORDER BY timestamp DESC;
+`, condition),
+		args: append([]interface{}{DateDeletedInProgress, DateDeletedPluginFailed, DateDeletedLocalFailed}, conditionArgs...),
+	}
+}
 
+// Only deleted backups.
+func getBackupNameForCleanBeforeTimestampQuery(timestamp string, dbFilter DatabaseFilter) historyDBQuery {
+	condition, conditionArgs := dbFilter.sqlConditionLine()
+	return historyDBQuery{
+		text: fmt.Sprintf(`
+SELECT timestamp 
+FROM backups 
+WHERE timestamp < ? 
+	AND date_deleted NOT IN ('', ?, ?, ?) 
+%sORDER BY timestamp DESC;
+`, condition),
+		args: append([]interface{}{timestamp, DateDeletedPluginFailed, DateDeletedLocalFailed, DateDeletedInProgress}, conditionArgs...),
+	}
 }
 
 // UpdateDeleteStatus Updates the date_deleted column in the history database.
 func UpdateDeleteStatus(backupName, dateDeleted string, historyDB *sql.DB) error {
-	err := execStatementFunc(updateDeleteStatusQuery(backupName, dateDeleted), historyDB)
+	err := execStatementsFunc([]historyDBQuery{updateDeleteStatusQuery(backupName, dateDeleted)}, historyDB)
 	if err != nil {
 		return err
 	}
@@ -123,6 +404,9 @@ func UpdateDeleteStatus(backupName, dateDeleted string, historyDB *sql.DB) error
 }
 
 // CleanBackupsDB cleans the backup history database by deleting backups based on the given list of backup names.
+// Each batch of backups is deleted from all tables of the history database in a single transaction.
+// If the deletion from any table fails or the number of deleted rows is unexpected,
+// the whole batch is rolled back, so the history database never contains partially deleted backups.
 func CleanBackupsDB(list []string, batchSize int, historyDB *sql.DB) error {
 	for i := 0; i < len(list); i += batchSize {
 		end := i + batchSize
@@ -130,50 +414,93 @@ func CleanBackupsDB(list []string, batchSize int, historyDB *sql.DB) error {
 			end = len(list)
 		}
 		batchIDs := list[i:end]
-		idStr := "'" + strings.Join(batchIDs, "','") + "'"
-		err := execStatementFunc(deleteBackupsFormTableQuery("backups", idStr), historyDB)
-		if err != nil {
-			return err
-		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("restore_plans", idStr), historyDB)
-		if err != nil {
-			return err
-		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("restore_plan_tables", idStr), historyDB)
+		err := withTransaction(historyDB, func(tx *sql.Tx) error {
+			return cleanBackupsBatch(batchIDs, tx)
+		})
 		if err != nil {
 			return err
 		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("exclude_relations", idStr), historyDB)
+	}
+	return nil
+}
+
+// cleanBackupsBatch Deletes the batch of backups from all tables of the history database.
+// Each backup from the batch must exist in the backups table, so the number of rows deleted from the backups table
+// is compared with the batch size. For the other tables there is no independent expectation of the number of rows,
+// a backup may have no rows there, so the number of deleted rows is not checked.
+func cleanBackupsBatch(backupNames []string, tx *sql.Tx) error {
+	for _, table := range historyDBTables {
+		deleteQuery := deleteBackupsFormTableQuery(table, backupNames)
+		result, err := tx.Exec(deleteQuery.text, deleteQuery.args...)
 		if err != nil {
 			return err
 		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("exclude_schemas", idStr), historyDB)
-		if err != nil {
-			return err
+		if table != "backups" {
+			continue
 		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("include_relations", idStr), historyDB)
+		gotCount, err := result.RowsAffected()
 		if err != nil {
 			return err
 		}
-		err = execStatementFunc(deleteBackupsFormTableQuery("include_schemas", idStr), historyDB)
-		if err != nil {
-			return err
+		if wantCount := int64(len(backupNames)); gotCount != wantCount {
+			return textmsg.ErrorHistoryDBRowsCountError(table, strconv.FormatInt(gotCount, 10), strconv.FormatInt(wantCount, 10))
 		}
 	}
 	return nil
 }
 
-func deleteBackupsFormTableQuery(db, value string) string {
-	return fmt.Sprintf(`DELETE FROM %s WHERE timestamp IN (%s);`, db, value)
+func countBackupsInTableQuery(table string, backupNames []string) historyDBQuery {
+	return historyDBQuery{
+		text: fmt.Sprintf(`SELECT count(*) FROM %s WHERE timestamp IN (%s);`, table, sqlPlaceholders(len(backupNames))),
+		args: sqlArgs(backupNames...),
+	}
 }
 
-func updateDeleteStatusQuery(timestamp, status string) string {
-	return fmt.Sprintf(`UPDATE backups SET date_deleted = '%s' WHERE timestamp = '%s';`, status, timestamp)
+// The table name can't be passed as a placeholder value,
+// only tables from the historyDBTables list are used.
+func deleteBackupsFormTableQuery(table string, backupNames []string) historyDBQuery {
+	return historyDBQuery{
+		text: fmt.Sprintf(`DELETE FROM %s WHERE timestamp IN (%s);`, table, sqlPlaceholders(len(backupNames))),
+		args: sqlArgs(backupNames...),
+	}
+}
+
+func getOrphanBackupNamesQuery(table string) historyDBQuery {
+	return historyDBQuery{
+		text: fmt.Sprintf(`SELECT DISTINCT timestamp FROM %s WHERE timestamp NOT IN (SELECT timestamp FROM backups) ORDER BY timestamp DESC;`, table),
+	}
+}
+
+func deleteOrphanRowsQuery(table string) historyDBQuery {
+	return historyDBQuery{
+		text: fmt.Sprintf(`DELETE FROM %s WHERE timestamp NOT IN (SELECT timestamp FROM backups);`, table),
+	}
+}
+
+func updateDeleteStatusQuery(timestamp, status string) historyDBQuery {
+	return historyDBQuery{
+		text: `UPDATE backups SET date_deleted = ? WHERE timestamp = ?;`,
+		args: []interface{}{status, timestamp},
+	}
 }
 
 // Execute a query that returns rows.
-func execQueryFunc(query string, historyDB *sql.DB) ([]string, error) {
-	sqlRow, err := historyDB.Query(query)
+// If the history database is busy, the query is retried.
+func execQueryFunc(query historyDBQuery, historyDB *sql.DB) ([]string, error) {
+	var resultList []string
+	err := withRetry(historyDB, func() error {
+		var err error
+		resultList, err = queryFunc(query, historyDB)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultList, nil
+}
+
+func queryFunc(query historyDBQuery, historyDB *sql.DB) ([]string, error) {
+	sqlRow, err := historyDB.Query(query.text, query.args...)
 	if err != nil {
 		return nil, err
 	}
@@ -193,17 +520,57 @@ func execQueryFunc(query string, historyDB *sql.DB) ([]string, error) {
 	return resultList, nil
 }
 
-// Execute a query that doesn't return rows.
-func execStatementFunc(query string, historyDB *sql.DB) error {
-	tx, err := historyDB.Begin()
-	if err != nil {
-		return err
-	}
-	_, err = tx.Exec(query)
-	if err != nil {
-		_ = tx.Rollback()
-		return err
+// Execute queries that don't return rows in a single transaction.
+// If any query fails, the transaction is rolled back, so the history database is never changed partially.
+// If the history database is busy, the whole transaction is retried.
+func execStatementsFunc(queries []historyDBQuery, historyDB *sql.DB) error {
+	return withTransaction(historyDB, func(tx *sql.Tx) error {
+		return statementsFunc(queries, tx)
+	})
+}
+
+// Each query text is prepared once and the prepared statement is reused
+// for all queries with the same text, for example, for the batches of the same size.
+func statementsFunc(queries []historyDBQuery, tx *sql.Tx) error {
+	stmts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+	for _, query := range queries {
+		stmt, ok := stmts[query.text]
+		if !ok {
+			var err error
+			stmt, err = tx.Prepare(query.text)
+			if err != nil {
+				return err
+			}
+			stmts[query.text] = stmt
+		}
+		_, err := stmt.Exec(query.args...)
+		if err != nil {
+			return err
+		}
 	}
-	err = tx.Commit()
-	return err
+	return nil
+}
+
+// withTransaction Executes the function in the transaction and commits it.
+// If the function or the commit fails, the transaction is rolled back.
+// If the history database is busy, the transaction is retried.
+func withTransaction(historyDB *sql.DB, fn func(tx *sql.Tx) error) error {
+	return withRetry(historyDB, func() error {
+		tx, err := historyDB.Begin()
+		if err != nil {
+			return err
+		}
+		err = fn(tx)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		// If the commit fails, the transaction is rolled back by the driver.
+		return tx.Commit()
+	})
 }
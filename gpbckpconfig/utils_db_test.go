@@ -1,42 +1,82 @@
 package gpbckpconfig
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
 
 func TestGetBackupNameQuery(t *testing.T) {
 	tests := []struct {
-		name  string
-		showD bool
-		showF bool
-		want  string
+		name     string
+		showD    bool
+		showF    bool
+		dbFilter DatabaseFilter
+		want     historyDBQuery
 	}{
 		{
 			name:  "Test show all",
 			showD: true,
 			showF: true,
-			want:  `SELECT timestamp FROM backups ORDER BY timestamp DESC;`,
+			want:  historyDBQuery{text: `SELECT timestamp FROM backups ORDER BY timestamp DESC;`},
 		},
 		{
 			name:  "Test show deleted",
 			showD: true,
 			showF: false,
-			want:  `SELECT timestamp FROM backups WHERE status != 'Failure' ORDER BY timestamp DESC;`,
+			want: historyDBQuery{
+				text: `SELECT timestamp FROM backups WHERE status != ? ORDER BY timestamp DESC;`,
+				args: []interface{}{"Failure"},
+			},
 		},
 		{
 			name:  "Test show failed",
 			showD: false,
 			showF: true,
-			want:  `SELECT timestamp FROM backups WHERE date_deleted IN ('', 'In progress', 'Plugin Backup Delete Failed', 'Local Delete Failed') ORDER BY timestamp DESC;`,
+			want: historyDBQuery{
+				text: `SELECT timestamp FROM backups WHERE date_deleted IN ('', ?, ?, ?) ORDER BY timestamp DESC;`,
+				args: []interface{}{"In progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+			},
 		},
 		{
 			name:  "Test show default",
 			showD: false,
 			showF: false,
-			want:  `SELECT timestamp FROM backups WHERE status != 'Failure' AND date_deleted IN ('', 'In progress', 'Plugin Backup Delete Failed', 'Local Delete Failed') ORDER BY timestamp DESC;`,
+			want: historyDBQuery{
+				text: `SELECT timestamp FROM backups WHERE status != ? AND date_deleted IN ('', ?, ?, ?) ORDER BY timestamp DESC;`,
+				args: []interface{}{"Failure", "In progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+			},
+		},
+		{
+			name:     "Test show all with included databases",
+			showD:    true,
+			showF:    true,
+			dbFilter: DatabaseFilter{Include: []string{"demo", "test"}},
+			want: historyDBQuery{
+				text: `SELECT timestamp FROM backups WHERE database_name IN (?, ?) ORDER BY timestamp DESC;`,
+				args: []interface{}{"demo", "test"},
+			},
+		},
+		{
+			name:     "Test show default with excluded databases",
+			showD:    false,
+			showF:    false,
+			dbFilter: DatabaseFilter{Exclude: []string{"demo"}},
+			want: historyDBQuery{
+				text: `SELECT timestamp FROM backups WHERE status != ? AND date_deleted IN ('', ?, ?, ?) AND database_name NOT IN (?) ORDER BY timestamp DESC;`,
+				args: []interface{}{"Failure", "In progress", "Plugin Backup Delete Failed", "Local Delete Failed", "demo"},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getBackupNameQuery(tt.showD, tt.showF); got != tt.want {
+			if got := getBackupNameQuery(tt.showD, tt.showF, tt.dbFilter); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("getBackupNameQuery(%v, %v):\n%v\nwant:\n%v", tt.showD, tt.showF, got, tt.want)
 			}
 		})
@@ -47,48 +87,61 @@ func TestGetBackupDependenciesQuery(t *testing.T) {
 	tests := []struct {
 		name     string
 		value    string
-		function func(string) string
-		want     string
+		function func(string) historyDBQuery
+		want     historyDBQuery
 	}{
 		{
 			name:     "Test getBackupDependenciesQuery",
 			value:    "TestBackup",
 			function: getBackupDependenciesQuery,
-			want: `
+			want: historyDBQuery{
+				text: `
 SELECT timestamp 
 FROM restore_plans
-WHERE timestamp != 'TestBackup'
-	AND restore_plan_timestamp = 'TestBackup'
+WHERE timestamp != ?
+	AND restore_plan_timestamp = ?
 ORDER BY timestamp DESC;
-`},
+`,
+				args: []interface{}{"TestBackup", "TestBackup"},
+			}},
 		{
-			name:     "Test getBackupNameBeforeTimestampQuery",
-			value:    "20240101120000",
-			function: getBackupNameBeforeTimestampQuery,
-			want: `
+			name:  "Test getBackupNameBeforeTimestampQuery",
+			value: "20240101120000",
+			function: func(timestamp string) historyDBQuery {
+				return getBackupNameBeforeTimestampQuery(timestamp, DatabaseFilter{})
+			},
+			want: historyDBQuery{
+				text: `
 SELECT timestamp 
 FROM backups 
-WHERE timestamp < '20240101120000' 
-	AND status != 'In Progress' 
-	AND date_deleted IN ('', 'Plugin Backup Delete Failed', 'Local Delete Failed') 
+WHERE timestamp < ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
 ORDER BY timestamp DESC;
-`},
+`,
+				args: []interface{}{"20240101120000", "In Progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+			}},
 		{
-			name:     "Test getBackupNameAfterTimestampQuery",
-			value:    "20240101120000",
-			function: getBackupNameAfterTimestampQuery,
-			want: `
+			name:  "Test getBackupNameAfterTimestampQuery",
+			value: "20240101120000",
+			function: func(timestamp string) historyDBQuery {
+				return getBackupNameAfterTimestampQuery(timestamp, DatabaseFilter{})
+			},
+			want: historyDBQuery{
+				text: `
 SELECT timestamp 
 FROM backups 
-WHERE timestamp > '20240101120000' 
-	AND status != 'In Progress' 
-	AND date_deleted IN ('', 'Plugin Backup Delete Failed', 'Local Delete Failed') 
+WHERE timestamp > ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
 ORDER BY timestamp DESC;
-`},
+`,
+				args: []interface{}{"20240101120000", "In Progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+			}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.function(tt.value); got != tt.want {
+			if got := tt.function(tt.value); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("getBackupDependenciesQuery(%v):\n%v\nwant:\n%v", tt.value, got, tt.want)
 			}
 		})
@@ -100,58 +153,618 @@ func TestGetBackupNameForCleanBeforeTimestampQuery(t *testing.T) {
 		name  string
 		value string
 		showD bool
-		want  string
+		want  historyDBQuery
 	}{
 		{
 			name:  "Show backups",
 			value: "20240101120000",
 			showD: true,
-			want: `
+			want: historyDBQuery{
+				text: `
 SELECT timestamp 
 FROM backups 
-WHERE timestamp < '20240101120000' 
-	AND date_deleted NOT IN ('', 'Plugin Backup Delete Failed', 'Local Delete Failed', 'In progress') 
+WHERE timestamp < ? 
+	AND date_deleted NOT IN ('', ?, ?, ?) 
 ORDER BY timestamp DESC;
-`},
+`,
+				args: []interface{}{"20240101120000", "Plugin Backup Delete Failed", "Local Delete Failed", "In progress"},
+			}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := getBackupNameForCleanBeforeTimestampQuery(tt.value); got != tt.want {
+			if got := getBackupNameForCleanBeforeTimestampQuery(tt.value, DatabaseFilter{}); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("getBackupNameForCleanBeforeTimestampQuery(%v, %v):\n%v\nwant:\n%v", tt.value, tt.showD, got, tt.want)
 			}
 		})
 	}
 }
 
-func TestTextQueryFunctionsArg(t *testing.T) {
-	testBackupName := "TestBackup"
+func TestGetBackupNameForRetentionQuery(t *testing.T) {
+	want := historyDBQuery{
+		text: `
+SELECT timestamp 
+FROM backups 
+WHERE status != ? 
+	AND date_deleted IN ('', ?, ?) 
+ORDER BY timestamp DESC;
+`,
+		args: []interface{}{"In Progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+	}
+	if got := getBackupNameForRetentionQuery(DatabaseFilter{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("getBackupNameForRetentionQuery():\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestGetBackupNameForRepairQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbFilter DatabaseFilter
+		want     historyDBQuery
+	}{
+		{
+			name:     "Test without database filter",
+			dbFilter: DatabaseFilter{},
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE date_deleted IN (?, ?, ?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"In progress", "Plugin Backup Delete Failed", "Local Delete Failed"},
+			}},
+		{
+			name:     "Test with database filter",
+			dbFilter: DatabaseFilter{Exclude: []string{"demo"}},
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE date_deleted IN (?, ?, ?) 
+	AND database_name NOT IN (?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"In progress", "Plugin Backup Delete Failed", "Local Delete Failed", "demo"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getBackupNameForRepairQuery(tt.dbFilter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getBackupNameForRepairQuery():\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetBackupNameQueryWithDatabaseFilter(t *testing.T) {
+	dbFilter := DatabaseFilter{Include: []string{"demo", "o'test"}}
+	tests := []struct {
+		name     string
+		function func() historyDBQuery
+		want     historyDBQuery
+	}{
+		{
+			name:     "Test getBackupNameBeforeTimestampQuery",
+			function: func() historyDBQuery { return getBackupNameBeforeTimestampQuery("20240101120000", dbFilter) },
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE timestamp < ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
+	AND database_name IN (?, ?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"20240101120000", "In Progress", "Plugin Backup Delete Failed", "Local Delete Failed", "demo", "o'test"},
+			}},
+		{
+			name:     "Test getBackupNameAfterTimestampQuery",
+			function: func() historyDBQuery { return getBackupNameAfterTimestampQuery("20240101120000", dbFilter) },
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE timestamp > ? 
+	AND status != ? 
+	AND date_deleted IN ('', ?, ?) 
+	AND database_name IN (?, ?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"20240101120000", "In Progress", "Plugin Backup Delete Failed", "Local Delete Failed", "demo", "o'test"},
+			}},
+		{
+			name:     "Test getBackupNameForCleanBeforeTimestampQuery",
+			function: func() historyDBQuery { return getBackupNameForCleanBeforeTimestampQuery("20240101120000", dbFilter) },
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE timestamp < ? 
+	AND date_deleted NOT IN ('', ?, ?, ?) 
+	AND database_name IN (?, ?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"20240101120000", "Plugin Backup Delete Failed", "Local Delete Failed", "In progress", "demo", "o'test"},
+			}},
+		{
+			name: "Test getBackupNameForRetentionQuery",
+			function: func() historyDBQuery {
+				return getBackupNameForRetentionQuery(DatabaseFilter{Include: []string{"demo"}, Exclude: []string{"test"}})
+			},
+			want: historyDBQuery{
+				text: `
+SELECT timestamp 
+FROM backups 
+WHERE status != ? 
+	AND date_deleted IN ('', ?, ?) 
+	AND database_name IN (?) AND database_name NOT IN (?) 
+ORDER BY timestamp DESC;
+`,
+				args: []interface{}{"In Progress", "Plugin Backup Delete Failed", "Local Delete Failed", "demo", "test"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.function(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabaseFilterMatch(t *testing.T) {
 	tests := []struct {
 		name     string
-		value1   string
-		value2   string
-		function func(string, string) string
-		want     string
+		dbFilter DatabaseFilter
+		value    string
+		want     bool
+	}{
+		{
+			name:     "Test empty filter",
+			dbFilter: DatabaseFilter{},
+			value:    "demo",
+			want:     true,
+		},
+		{
+			name:     "Test included database",
+			dbFilter: DatabaseFilter{Include: []string{"demo", "test"}},
+			value:    "demo",
+			want:     true,
+		},
+		{
+			name:     "Test not included database",
+			dbFilter: DatabaseFilter{Include: []string{"test"}},
+			value:    "demo",
+			want:     false,
+		},
+		{
+			name:     "Test excluded database",
+			dbFilter: DatabaseFilter{Exclude: []string{"demo"}},
+			value:    "demo",
+			want:     false,
+		},
+		{
+			name:     "Test not excluded database",
+			dbFilter: DatabaseFilter{Exclude: []string{"test"}},
+			value:    "demo",
+			want:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dbFilter.Match(tt.value); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextQueryFunctionsArg(t *testing.T) {
+	tests := []struct {
+		name string
+		got  historyDBQuery
+		want historyDBQuery
+	}{
+		{
+			name: "Test deleteBackupsFormTableQuery",
+			got:  deleteBackupsFormTableQuery("restore_plans", []string{"20220401102430", "20220401102431"}),
+			want: historyDBQuery{
+				text: "DELETE FROM restore_plans WHERE timestamp IN (?, ?);",
+				args: []interface{}{"20220401102430", "20220401102431"},
+			},
+		},
+		{
+			name: "Test countBackupsInTableQuery",
+			got:  countBackupsInTableQuery("restore_plans", []string{"20220401102430", "20220401102431"}),
+			want: historyDBQuery{
+				text: "SELECT count(*) FROM restore_plans WHERE timestamp IN (?, ?);",
+				args: []interface{}{"20220401102430", "20220401102431"},
+			},
+		},
+		{
+			name: "Test updateDeleteStatusQuery",
+			got:  updateDeleteStatusQuery("TestBackup", "20220401102430"),
+			want: historyDBQuery{
+				text: "UPDATE backups SET date_deleted = ? WHERE timestamp = ?;",
+				args: []interface{}{"20220401102430", "TestBackup"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+// createTestHistoryDB Creates the history database with the tables of the backup information.
+// Each table contains the rows for the given backups.
+func createTestHistoryDB(t *testing.T, tables []string, backupNames []string) *sql.DB {
+	hDB, err := OpenHistoryDB(filepath.Join(t.TempDir(), "gpbackup_history.db"), HistoryDBOptions{BusyTimeout: time.Second})
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	for _, table := range tables {
+		_, err = hDB.Exec("CREATE TABLE " + table + " (timestamp TEXT);")
+		if err != nil {
+			t.Fatalf("unable to prepare history db: %v", err)
+		}
+		for _, backupName := range backupNames {
+			_, err = hDB.Exec("INSERT INTO "+table+" VALUES (?);", backupName)
+			if err != nil {
+				t.Fatalf("unable to prepare history db: %v", err)
+			}
+		}
+	}
+	return hDB
+}
+
+// countTestHistoryDBRows Returns the number of rows in each table.
+func countTestHistoryDBRows(t *testing.T, tables []string, hDB *sql.DB) map[string]int {
+	counts := make(map[string]int)
+	for _, table := range tables {
+		var count int
+		err := hDB.QueryRow("SELECT count(*) FROM " + table + ";").Scan(&count)
+		if err != nil {
+			t.Fatalf("unable to read history db: %v", err)
+		}
+		counts[table] = count
+	}
+	return counts
+}
+
+func TestCleanBackupsDB(t *testing.T) {
+	backupNames := []string{"20240101100000", "20240102100000", "20240103100000", "20240104100000", "o'test"}
+	tests := []struct {
+		name       string
+		tables     []string
+		list       []string
+		wantErr    bool
+		wantCounts int
+	}{
+		{
+			name:       "Test delete backups in batches",
+			tables:     historyDBTables,
+			list:       []string{"20240101100000", "20240102100000", "20240103100000", "o'test"},
+			wantCounts: 1,
+		},
+		{
+			name:       "Test missing table",
+			tables:     historyDBTables[:len(historyDBTables)-1],
+			list:       []string{"20240101100000", "20240102100000", "20240103100000", "o'test"},
+			wantErr:    true,
+			wantCounts: len(backupNames),
+		},
+		{
+			name:       "Test missing backup in second batch",
+			tables:     historyDBTables,
+			list:       []string{"20240101100000", "20240102100000", "20240103100000", "o'test", "20240105100000"},
+			wantErr:    true,
+			wantCounts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hDB := createTestHistoryDB(t, tt.tables, backupNames)
+			defer hDB.Close()
+			err := CleanBackupsDB(tt.list, 3, hDB)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CleanBackupsDB() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// If the batch fails, its rows are not deleted from any table.
+			for table, count := range countTestHistoryDBRows(t, tt.tables, hDB) {
+				if count != tt.wantCounts {
+					t.Errorf("table %s:\nVariables do not match:\n%v\nwant:\n%v", table, count, tt.wantCounts)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryDBDSN(t *testing.T) {
+	got := historyDBDSN("/data/gpbackup_history.db", 10*time.Second)
+	want := "/data/gpbackup_history.db?_busy_timeout=10000&_txlock=immediate"
+	if got != want {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestIsHistoryDBBusy(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Test busy error", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"Test locked error", sqlite3.Error{Code: sqlite3.ErrLocked}, true},
+		{"Test other sqlite error", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"Test other error", errors.New("test error"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isHistoryDBBusy(tt.err); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	defer func(sleep func(time.Duration)) {
+		execSleep = sleep
+	}(execSleep)
+	hDB, err := OpenHistoryDB(filepath.Join(t.TempDir(), "gpbackup_history.db"), HistoryDBOptions{Retries: 3, RetryDelay: time.Second})
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB.Close()
+	busyErr := sqlite3.Error{Code: sqlite3.ErrBusy}
+	tests := []struct {
+		name       string
+		errs       []error
+		wantErr    error
+		wantDelays []time.Duration
+	}{
+		{
+			name: "Test success",
+			errs: []error{nil},
+		},
+		{
+			name:       "Test success after retries",
+			errs:       []error{busyErr, busyErr, nil},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:       "Test retries exceeded",
+			errs:       []error{busyErr, busyErr, busyErr, busyErr},
+			wantErr:    busyErr,
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:    "Test not busy error",
+			errs:    []error{errors.New("test error")},
+			wantErr: errors.New("test error"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var delays []time.Duration
+			execSleep = func(d time.Duration) { delays = append(delays, d) }
+			attempt := 0
+			err := withRetry(hDB, func() error {
+				err := tt.errs[attempt]
+				attempt++
+				return err
+			})
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Errorf("withRetry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attempt != len(tt.errs) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", attempt, len(tt.errs))
+			}
+			if !reflect.DeepEqual(delays, tt.wantDelays) {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", delays, tt.wantDelays)
+			}
+		})
+	}
+}
+
+func TestGetHistoryDBOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	opts1 := HistoryDBOptions{BusyTimeout: time.Second, Retries: 1, RetryDelay: time.Millisecond}
+	opts2 := HistoryDBOptions{BusyTimeout: 2 * time.Second, Retries: 5, RetryDelay: time.Second}
+	hDB1, err := OpenHistoryDB(filepath.Join(tempDir, "gpbackup_history1.db"), opts1)
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB1.Close()
+	// The options of the handle opened later don't change the options of the first handle.
+	hDB2, err := OpenHistoryDB(filepath.Join(tempDir, "gpbackup_history2.db"), opts2)
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB2.Close()
+	otherDB, err := sql.Open("sqlite3", filepath.Join(tempDir, "gpbackup_history3.db"))
+	if err != nil {
+		t.Fatalf("unable to open history db: %v", err)
+	}
+	defer otherDB.Close()
+	tests := []struct {
+		name string
+		hDB  *sql.DB
+		want HistoryDBOptions
 	}{
 		{
-			name:     "Test deleteBackupsFormTableQuery",
-			value1:   testBackupName,
-			value2:   "'20220401102430', '20220401102430'",
-			function: deleteBackupsFormTableQuery,
-			want:     "DELETE FROM TestBackup WHERE timestamp IN ('20220401102430', '20220401102430');",
+			name: "Test first handle",
+			hDB:  hDB1,
+			want: opts1,
+		},
+		{
+			name: "Test second handle",
+			hDB:  hDB2,
+			want: opts2,
 		},
 		{
-			name:     "Test updateDeleteStatusQuery",
-			value1:   testBackupName,
-			value2:   "20220401102430",
-			function: updateDeleteStatusQuery,
-			want:     "UPDATE backups SET date_deleted = '20220401102430' WHERE timestamp = 'TestBackup';",
+			name: "Test handle not opened by OpenHistoryDB",
+			hDB:  otherDB,
+			want: defaultHistoryDBOptions,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.function(tt.value1, tt.value2); got != tt.want {
-				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			if got := getHistoryDBOptions(tt.hDB); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestUpdateDeleteStatusLockedHistoryDB(t *testing.T) {
+	defer func(sleep func(time.Duration)) {
+		execSleep = sleep
+	}(execSleep)
+	historyDBPath := filepath.Join(t.TempDir(), "gpbackup_history.db")
+	// The connection of another process, that writes into the history database.
+	writerDB, err := sql.Open("sqlite3", historyDBPath)
+	if err != nil {
+		t.Fatalf("unable to open history db: %v", err)
+	}
+	defer writerDB.Close()
+	_, err = writerDB.Exec("CREATE TABLE backups (timestamp TEXT, date_deleted TEXT); INSERT INTO backups VALUES ('20240101100000', '');")
+	if err != nil {
+		t.Fatalf("unable to prepare history db: %v", err)
+	}
+	ctx := context.Background()
+	writerConn, err := writerDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("unable to get connection: %v", err)
+	}
+	defer writerConn.Close()
+	_, err = writerConn.ExecContext(ctx, "BEGIN EXCLUSIVE;")
+	if err != nil {
+		t.Fatalf("unable to lock history db: %v", err)
+	}
+	hDB, err := OpenHistoryDB(historyDBPath, HistoryDBOptions{BusyTimeout: 50 * time.Millisecond, Retries: 2, RetryDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB.Close()
+	// The history database stays locked for all attempts.
+	execSleep = func(time.Duration) {}
+	err = UpdateDeleteStatus("20240101100000", DateDeletedInProgress, hDB)
+	if !isHistoryDBBusy(err) {
+		t.Errorf("UpdateDeleteStatus() error = %v, want busy error", err)
+	}
+	// The history database is unlocked before the retry.
+	execSleep = func(time.Duration) {
+		_, _ = writerConn.ExecContext(ctx, "COMMIT;")
+	}
+	err = UpdateDeleteStatus("20240101100000", DateDeletedInProgress, hDB)
+	if err != nil {
+		t.Errorf("UpdateDeleteStatus() error = %v", err)
+	}
+	var got string
+	err = hDB.QueryRow("SELECT date_deleted FROM backups WHERE timestamp = '20240101100000';").Scan(&got)
+	if err != nil {
+		t.Fatalf("unable to read history db: %v", err)
+	}
+	if got != DateDeletedInProgress {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, DateDeletedInProgress)
+	}
+}
+
+func TestUpdateDeleteStatusWALHistoryDB(t *testing.T) {
+	defer func(sleep func(time.Duration)) {
+		execSleep = sleep
+	}(execSleep)
+	historyDBPath := filepath.Join(t.TempDir(), "gpbackup_history.db")
+	// The connection of another process, that writes into the history database in WAL journal mode.
+	writerDB, err := sql.Open("sqlite3", historyDBPath)
+	if err != nil {
+		t.Fatalf("unable to open history db: %v", err)
+	}
+	defer writerDB.Close()
+	_, err = writerDB.Exec("PRAGMA journal_mode=WAL; CREATE TABLE backups (timestamp TEXT, date_deleted TEXT); INSERT INTO backups VALUES ('20240101100000', '');")
+	if err != nil {
+		t.Fatalf("unable to prepare history db: %v", err)
+	}
+	ctx := context.Background()
+	writerConn, err := writerDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("unable to get connection: %v", err)
+	}
+	defer writerConn.Close()
+	hDB, err := OpenHistoryDB(historyDBPath, HistoryDBOptions{BusyTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("OpenHistoryDB() error = %v", err)
+	}
+	defer hDB.Close()
+	// The history database is read, so the connection of the handle has seen the current snapshot.
+	_, err = GetBackupNamesDB(true, true, DatabaseFilter{}, hDB)
+	if err != nil {
+		t.Fatalf("GetBackupNamesDB() error = %v", err)
+	}
+	_, err = writerConn.ExecContext(ctx, "BEGIN IMMEDIATE; UPDATE backups SET date_deleted = 'test';")
+	if err != nil {
+		t.Fatalf("unable to lock history db: %v", err)
+	}
+	// The write transaction waits for the busy timeout instead of failing, so no retries are needed.
+	execSleep = func(time.Duration) {
+		t.Errorf("unexpected retry")
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		_, _ = writerConn.ExecContext(ctx, "COMMIT;")
+	}()
+	err = UpdateDeleteStatus("20240101100000", DateDeletedInProgress, hDB)
+	if err != nil {
+		t.Errorf("UpdateDeleteStatus() error = %v", err)
+	}
+	var got string
+	err = hDB.QueryRow("SELECT date_deleted FROM backups WHERE timestamp = '20240101100000';").Scan(&got)
+	if err != nil {
+		t.Fatalf("unable to read history db: %v", err)
+	}
+	if got != DateDeletedInProgress {
+		t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, DateDeletedInProgress)
+	}
+}
+
+func TestOrphanRowsDB(t *testing.T) {
+	hDB := createTestHistoryDB(t, historyDBTables, []string{"20240101100000", "20240102100000", "20240103100000"})
+	defer hDB.Close()
+	_, err := hDB.Exec("DELETE FROM backups WHERE timestamp != '20240102100000';")
+	if err != nil {
+		t.Fatalf("unable to prepare history db: %v", err)
+	}
+	got, err := GetOrphanRowsDB(hDB)
+	if err != nil {
+		t.Fatalf("GetOrphanRowsDB() error = %v", err)
+	}
+	want := make([]HistoryDBOrphanRows, 0, len(historyDBChildTables))
+	for _, table := range historyDBChildTables {
+		want = append(want, HistoryDBOrphanRows{Table: table, BackupNames: []string{"20240103100000", "20240101100000"}})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, want)
+	}
+	err = DeleteOrphanRowsDB(hDB)
+	if err != nil {
+		t.Fatalf("DeleteOrphanRowsDB() error = %v", err)
+	}
+	got, err = GetOrphanRowsDB(hDB)
+	if err != nil {
+		t.Fatalf("GetOrphanRowsDB() error = %v", err)
+	}
+	if got != nil {
+		t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, nil)
+	}
+	for table, count := range countTestHistoryDBRows(t, historyDBTables, hDB) {
+		if count != 1 {
+			t.Errorf("table %s:\nVariables do not match:\n%v\nwant:\n%v", table, count, 1)
+		}
+	}
+}
@@ -132,6 +132,47 @@ func TestIsBackupActive(t *testing.T) {
 	}
 }
 
+func TestIsBackupDeletionUnfinished(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{
+			name:  "Test empty delete date",
+			value: "",
+			want:  false,
+		},
+		{
+			name:  "Test plugin error",
+			value: DateDeletedPluginFailed,
+			want:  true,
+		},
+		{
+			name:  "Test local error",
+			value: DateDeletedLocalFailed,
+			want:  true,
+		},
+		{
+			name:  "Test deletion in progress",
+			value: DateDeletedInProgress,
+			want:  true,
+		},
+		{
+			name:  "Test deleted",
+			value: "20220401102430",
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBackupDeletionUnfinished(tt.value); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsPositiveValue(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -226,6 +267,89 @@ func TestReportFileName(t *testing.T) {
 	}
 }
 
+func TestBackupFileNames(t *testing.T) {
+	timestamp := "20230101123400"
+	tests := []struct {
+		name     string
+		function func() string
+		want     string
+	}{
+		{
+			name:     "Test ConfigFileName",
+			function: func() string { return ConfigFileName(timestamp) },
+			want:     "gpbackup_20230101123400_config.yaml",
+		},
+		{
+			name:     "Test TocFileName",
+			function: func() string { return TocFileName(timestamp) },
+			want:     "gpbackup_20230101123400_toc.yaml",
+		},
+		{
+			name:     "Test MetadataFileName",
+			function: func() string { return MetadataFileName(timestamp) },
+			want:     "gpbackup_20230101123400_metadata.sql",
+		},
+		{
+			name:     "Test SegmentTocFileName",
+			function: func() string { return SegmentTocFileName("0", timestamp) },
+			want:     "gpbackup_0_20230101123400_toc.yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.function(); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTimestampFromBackupDirPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "Test valid backup directory path",
+			value:   "/data/master/gpseg-1/backups/20230101/20230101123400",
+			want:    "20230101123400",
+			wantErr: false,
+		},
+		{
+			name:    "Test date directory does not match timestamp",
+			value:   "/data/master/gpseg-1/backups/20230102/20230101123400",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Test invalid timestamp",
+			value:   "/data/master/gpseg-1/backups/20230101/test",
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Test not in backups directory",
+			value:   "/data/master/gpseg-1/other/20230101/20230101123400",
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetTimestampFromBackupDirPath(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("\nGetTimestampFromBackupDirPath() error:\n%v\nwantErr:\n%v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("\nVariables do not match:\n%v\nwant:\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestBackupPluginCustomReportPath(t *testing.T) {
 	type args struct {
 		timestamp   string
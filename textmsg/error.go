@@ -26,6 +26,14 @@ func ErrorTextUnableInitHistoryDB(err error) string {
 	return fmt.Sprintf("Unable to initialize history db. Error: %v", err)
 }
 
+func ErrorTextUnableActionHistoryDBLock(value string, err error) string {
+	return fmt.Sprintf("Unable to %s history db lock. Error: %v", value, err)
+}
+
+func ErrorTextUnableActionHistoryDBSnapshot(value string, err error) string {
+	return fmt.Sprintf("Unable to %s history db snapshot. Error: %v", value, err)
+}
+
 // Errors that occur when working with a history db.
 
 func ErrorTextUnableActionHistoryFile(value string, err error) string {
@@ -66,6 +74,30 @@ func ErrorTextBackupDeleteInProgress(backupName string, err error) string {
 	return fmt.Sprintf("Backup %s deletion in progress. Error: %v", backupName, err)
 }
 
+func ErrorTextUnableVerifyBackup(backupName string, err error) string {
+	return fmt.Sprintf("Unable to verify backup %s. Error: %v", backupName, err)
+}
+
+func ErrorTextUnableRepairBackup(backupName string, err error) string {
+	return fmt.Sprintf("Unable to repair backup %s. Error: %v", backupName, err)
+}
+
+func ErrorTextUnableCheckPath(path string, err error) string {
+	return fmt.Sprintf("Unable to check path %s. Error: %v", path, err)
+}
+
+func ErrorTextUnableScanBackupDir(path, host string, err error) string {
+	return fmt.Sprintf("Unable to scan backup directory %s on host %s. Error: %v", path, host, err)
+}
+
+func ErrorTextUnableDeleteOrphanBackupDir(path, host string, err error) string {
+	return fmt.Sprintf("Unable to delete orphaned backup directory %s on host %s. Error: %v", path, host, err)
+}
+
+func ErrorTextUnableCloseSSHConnections(err error) string {
+	return fmt.Sprintf("Unable to close ssh connections. Error: %v", err)
+}
+
 func ErrorTextUnableGetBackupReport(backupName string, err error) string {
 	return fmt.Sprintf("Unable to get report for the backup %s. Error: %v", backupName, err)
 }
@@ -74,6 +106,18 @@ func ErrorTextUnableGetBackupPath(value, backupName string, err error) string {
 	return fmt.Sprintf("Unable to get path to %s for the backup %s. Error: %v", value, backupName, err)
 }
 
+func ErrorTextUnableGetBackupFile(fileName, backupName string, err error) string {
+	return fmt.Sprintf("Unable to get file %s for the backup %s. Error: %v", fileName, backupName, err)
+}
+
+func ErrorTextUnableSaveBackupFile(fileName, backupName string, err error) string {
+	return fmt.Sprintf("Unable to save file %s for the backup %s. Error: %v", fileName, backupName, err)
+}
+
+func ErrorTextUnableDisplayOutput(format string, err error) string {
+	return fmt.Sprintf("Unable to display output in %s format. Error: %v", format, err)
+}
+
 // Errors that occur when working with a backup plugin.
 
 func ErrorTextUnableReadPluginConfigFile(err error) string {
@@ -160,6 +204,46 @@ func ErrorBackupNotLocalStorageError() error {
 	return errors.New("is not a local backup")
 }
 
+func ErrorBackupDatabaseFilterError() error {
+	return errors.New("backup database does not match the database filter")
+}
+
+func ErrorBackupNotRepairableError() error {
+	return errors.New("backup deletion is not in progress or failed")
+}
+
+func ErrorBackupStorageStateUnknownError() error {
+	return errors.New("unable to determine the state of backup files")
+}
+
+func ErrorBackupFilesMissingError() error {
+	return errors.New("backup files are missing")
+}
+
+func ErrorUnexpectedCommandOutputError() error {
+	return errors.New("unexpected command output")
+}
+
+func ErrorBackupNotActiveError() error {
+	return errors.New("backup is in progress or deleted")
+}
+
+func ErrorInvalidReportFileError() error {
+	return errors.New("not a gpbackup report file")
+}
+
+func ErrorInvalidReportValueError(value, key string) error {
+	return fmt.Errorf("invalid value %s for report key %s", value, key)
+}
+
+func ErrorSSHAuthMethodNotFoundError() error {
+	return errors.New("no ssh private key files or ssh agent found for authentication")
+}
+
+func ErrorSSHAgentNotAvailableError() error {
+	return errors.New("ssh agent is not available, SSH_AUTH_SOCK is not set")
+}
+
 // Error that is returned when some validation fails.
 
 func ErrorValidationFullPath() error {
@@ -170,6 +254,10 @@ func ErrorFileNotExist() error {
 	return errors.New("file not exist")
 }
 
+func ErrorFileAlreadyExist() error {
+	return errors.New("file already exists")
+}
+
 func ErrorValidationTableFQN() error {
 	return errors.New("not a fully qualified table name")
 }
@@ -178,6 +266,10 @@ func ErrorValidationTimestamp() error {
 	return errors.New("not a timestamp")
 }
 
+func ErrorValidationBackupDirPath() error {
+	return errors.New("not a backup directory path")
+}
+
 func ErrorValidationValue() error {
 	return errors.New("value not set")
 }
@@ -205,3 +297,59 @@ func ErrorNotFoundBackupDirIn(value string) error {
 func ErrorSeveralFoundBackupDirIn(value string) error {
 	return fmt.Errorf("several backup directory found in %s", value)
 }
+
+func ErrorConfirmationRequiredError() error {
+	return errors.New("confirmation is required in non-interactive mode, use --yes option")
+}
+
+func ErrorBackupDeleteNotConfirmedError() error {
+	return errors.New("backup deletion is not confirmed")
+}
+
+func ErrorOrphanBackupDirDeleteNotConfirmedError() error {
+	return errors.New("orphaned backup directories deletion is not confirmed")
+}
+
+func ErrorBackupDeleteListChangedError() error {
+	return errors.New("backup list for deletion was changed after the confirmation, run the command again")
+}
+
+func ErrorHistoryDBLockNotAcquiredError() error {
+	return errors.New("history db lock is not acquired")
+}
+
+func ErrorHistoryDBLockActiveError() error {
+	return errors.New("history db lock is held by an active process, use force option")
+}
+
+func ErrorHistoryDBSnapshotNotFoundError(id string) error {
+	return fmt.Errorf("history db snapshot %s not found", id)
+}
+
+func ErrorUnexpectedDriverConnectionError(value string) error {
+	return fmt.Errorf("unexpected %s driver connection", value)
+}
+
+func ErrorHistoryDBProblemsFoundError(count string) error {
+	return fmt.Errorf("found %s problems in history db", count)
+}
+
+func ErrorHistoryFileProblemsFoundError(count string) error {
+	return fmt.Errorf("found %s problems in history files", count)
+}
+
+func ErrorBackupReportsUnavailableError(count string) error {
+	return fmt.Errorf("unable to get %s backup reports", count)
+}
+
+func ErrorHistoryDBBackupsConflictError(count string) error {
+	return fmt.Errorf("found %s backups that already exist in history db", count)
+}
+
+func ErrorHistoryDBRowsCountError(table, got, want string) error {
+	return fmt.Errorf("unexpected number of rows deleted from table %s: %s, expected: %s", table, got, want)
+}
+
+func ErrorHistoryDBLockedError(pid, hostname, command, started string) error {
+	return fmt.Errorf("history db is locked by process %s on host %s, command: %s, started: %s", pid, hostname, command, started)
+}
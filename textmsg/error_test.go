@@ -43,6 +43,12 @@ func TestErrorTextFunctionsErrorOnly(t *testing.T) {
 			function: ErrorTextUnableConnectLocalCluster,
 			want:     "Unable to connect to the cluster locally. Error: test error",
 		},
+		{
+			name:     "Test ErrorTextUnableCloseSSHConnections",
+			testErr:  testError,
+			function: ErrorTextUnableCloseSSHConnections,
+			want:     "Unable to close ssh connections. Error: test error",
+		},
 		{
 			name:     "Test ErrorTextUnableGetBackupDirLocalClusterConn",
 			testErr:  testError,
@@ -83,6 +89,27 @@ func TestErrorTextFunctionsErrorAndArg(t *testing.T) {
 			function: ErrorTextUnableDeleteBackup,
 			want:     "Unable to delete backup TestBackup. Error: test error",
 		},
+		{
+			name:     "Test ErrorTextUnableVerifyBackup",
+			value:    testBackupName,
+			testErr:  testError,
+			function: ErrorTextUnableVerifyBackup,
+			want:     "Unable to verify backup TestBackup. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableRepairBackup",
+			value:    testBackupName,
+			testErr:  testError,
+			function: ErrorTextUnableRepairBackup,
+			want:     "Unable to repair backup TestBackup. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableCheckPath",
+			value:    "/test/path",
+			testErr:  testError,
+			function: ErrorTextUnableCheckPath,
+			want:     "Unable to check path /test/path. Error: test error",
+		},
 		{
 			name:     "Test ErrorTextUnableDeleteBackupCascade",
 			value:    testBackupName,
@@ -125,6 +152,20 @@ func TestErrorTextFunctionsErrorAndArg(t *testing.T) {
 			function: ErrorTextUnableActionHistoryDB,
 			want:     "Unable to open history db. Error: test error",
 		},
+		{
+			name:     "Test ErrorTextUnableActionHistoryDBLock",
+			value:    "acquire",
+			testErr:  testError,
+			function: ErrorTextUnableActionHistoryDBLock,
+			want:     "Unable to acquire history db lock. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableActionHistoryDBSnapshot",
+			value:    "create",
+			testErr:  testError,
+			function: ErrorTextUnableActionHistoryDBSnapshot,
+			want:     "Unable to create history db snapshot. Error: test error",
+		},
 		{
 			name:     "Test ErrorTextUnableWorkBackup",
 			value:    testBackupName,
@@ -132,6 +173,13 @@ func TestErrorTextFunctionsErrorAndArg(t *testing.T) {
 			function: ErrorTextUnableWorkBackup,
 			want:     "Unable to work with backup TestBackup. Error: test error",
 		},
+		{
+			name:     "Test ErrorTextUnableDisplayOutput",
+			value:    "json",
+			testErr:  testError,
+			function: ErrorTextUnableDisplayOutput,
+			want:     "Unable to display output in json format. Error: test error",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -184,6 +232,38 @@ func TestErrorTextFunctionsErrorAndTwoArgs(t *testing.T) {
 			function: ErrorTextUnableGetBackupPath,
 			want:     "Unable to get path to report for the backup TestBackup. Error: test error",
 		},
+		{
+			name:     "Test ErrorTextUnableGetBackupFile",
+			value1:   "TestFile",
+			value2:   "TestBackup",
+			testErr:  testError,
+			function: ErrorTextUnableGetBackupFile,
+			want:     "Unable to get file TestFile for the backup TestBackup. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableSaveBackupFile",
+			value1:   "TestFile",
+			value2:   "TestBackup",
+			testErr:  testError,
+			function: ErrorTextUnableSaveBackupFile,
+			want:     "Unable to save file TestFile for the backup TestBackup. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableScanBackupDir",
+			value1:   "/test/path",
+			value2:   "sdw1",
+			testErr:  testError,
+			function: ErrorTextUnableScanBackupDir,
+			want:     "Unable to scan backup directory /test/path on host sdw1. Error: test error",
+		},
+		{
+			name:     "Test ErrorTextUnableDeleteOrphanBackupDir",
+			value1:   "/test/path",
+			value2:   "sdw1",
+			testErr:  testError,
+			function: ErrorTextUnableDeleteOrphanBackupDir,
+			want:     "Unable to delete orphaned backup directory /test/path on host sdw1. Error: test error",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -254,13 +334,30 @@ func TestErrorFunctions(t *testing.T) {
 		{"ErrorBackupDeleteCascadeOptionError", ErrorBackupDeleteCascadeOptionError, "use cascade option"},
 		{"ErrorValidationFullPath", ErrorValidationFullPath, "not an absolute path"},
 		{"ErrorValidationTimestamp", ErrorValidationTimestamp, "not a timestamp"},
+		{"ErrorValidationBackupDirPath", ErrorValidationBackupDirPath, "not a backup directory path"},
 		{"ErrorBackupLocalStorageError", ErrorBackupLocalStorageError, "is a local backup"},
 		{"ErrorValidationValue", ErrorValidationValue, "value not set"},
 		{"ErrorValidationTableFQN", ErrorValidationTableFQN, "not a fully qualified table name"},
 		{"ErrorNotIndependentFlagsError", ErrorNotIndependentFlagsError, "not an independent flag"},
 		{"ErrorFileNotExist", ErrorFileNotExist, "file not exist"},
+		{"ErrorFileAlreadyExist", ErrorFileAlreadyExist, "file already exists"},
 		{"ErrorEmptyDatabase", ErrorEmptyDatabase, "database name cannot be empty"},
 		{"ErrorBackupNotLocalStorageError", ErrorBackupNotLocalStorageError, "is not a local backup"},
+		{"ErrorBackupDatabaseFilterError", ErrorBackupDatabaseFilterError, "backup database does not match the database filter"},
+		{"ErrorBackupNotRepairableError", ErrorBackupNotRepairableError, "backup deletion is not in progress or failed"},
+		{"ErrorBackupStorageStateUnknownError", ErrorBackupStorageStateUnknownError, "unable to determine the state of backup files"},
+		{"ErrorBackupFilesMissingError", ErrorBackupFilesMissingError, "backup files are missing"},
+		{"ErrorUnexpectedCommandOutputError", ErrorUnexpectedCommandOutputError, "unexpected command output"},
+		{"ErrorBackupNotActiveError", ErrorBackupNotActiveError, "backup is in progress or deleted"},
+		{"ErrorInvalidReportFileError", ErrorInvalidReportFileError, "not a gpbackup report file"},
+		{"ErrorSSHAuthMethodNotFoundError", ErrorSSHAuthMethodNotFoundError, "no ssh private key files or ssh agent found for authentication"},
+		{"ErrorSSHAgentNotAvailableError", ErrorSSHAgentNotAvailableError, "ssh agent is not available, SSH_AUTH_SOCK is not set"},
+		{"ErrorConfirmationRequiredError", ErrorConfirmationRequiredError, "confirmation is required in non-interactive mode, use --yes option"},
+		{"ErrorBackupDeleteNotConfirmedError", ErrorBackupDeleteNotConfirmedError, "backup deletion is not confirmed"},
+		{"ErrorOrphanBackupDirDeleteNotConfirmedError", ErrorOrphanBackupDirDeleteNotConfirmedError, "orphaned backup directories deletion is not confirmed"},
+		{"ErrorBackupDeleteListChangedError", ErrorBackupDeleteListChangedError, "backup list for deletion was changed after the confirmation, run the command again"},
+		{"ErrorHistoryDBLockNotAcquiredError", ErrorHistoryDBLockNotAcquiredError, "history db lock is not acquired"},
+		{"ErrorHistoryDBLockActiveError", ErrorHistoryDBLockActiveError, "history db lock is held by an active process, use force option"},
 	}
 
 	for _, tt := range tests {
@@ -288,6 +385,13 @@ func TestErrorFunctionsTwoArgs(t *testing.T) {
 			errFunc: ErrorValidationPluginOption,
 			want:    "invalid plugin TestValue1 option value for plugin TestValue2",
 		},
+		{
+			name:    "ErrorInvalidReportValueError",
+			value1:  "TestValue1",
+			value2:  "TestValue2",
+			errFunc: ErrorInvalidReportValueError,
+			want:    "invalid value TestValue1 for report key TestValue2",
+		},
 	}
 	for _, tt := range tests {
 		err := tt.errFunc(tt.value1, tt.value2)
@@ -297,6 +401,50 @@ func TestErrorFunctionsTwoArgs(t *testing.T) {
 	}
 }
 
+func TestErrorFunctionsThreeArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  [3]string
+		errFunc func(string, string, string) error
+		want    string
+	}{
+		{
+			name:    "ErrorHistoryDBRowsCountError",
+			values:  [3]string{"backups", "1", "2"},
+			errFunc: ErrorHistoryDBRowsCountError,
+			want:    "unexpected number of rows deleted from table backups: 1, expected: 2",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.errFunc(tt.values[0], tt.values[1], tt.values[2])
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("\n%s() error:\n%v\nwant:\n%v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestErrorFunctionsFourArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  [4]string
+		errFunc func(string, string, string, string) error
+		want    string
+	}{
+		{
+			name:    "ErrorHistoryDBLockedError",
+			values:  [4]string{"123", "TestHost", "backup-clean", "20240101100000"},
+			errFunc: ErrorHistoryDBLockedError,
+			want:    "history db is locked by process 123 on host TestHost, command: backup-clean, started: 20240101100000",
+		},
+	}
+	for _, tt := range tests {
+		err := tt.errFunc(tt.values[0], tt.values[1], tt.values[2], tt.values[3])
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("\n%s() error:\n%v\nwant:\n%v", tt.name, err, tt.want)
+		}
+	}
+}
+
 func TestErrorFunctionsOneoArg(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -316,6 +464,42 @@ func TestErrorFunctionsOneoArg(t *testing.T) {
 			errFunc: ErrorSeveralFoundBackupDirIn,
 			want:    "several backup directory found in TestValue",
 		},
+		{
+			name:    "ErrorHistoryDBSnapshotNotFoundError",
+			value:   "20240101100000",
+			errFunc: ErrorHistoryDBSnapshotNotFoundError,
+			want:    "history db snapshot 20240101100000 not found",
+		},
+		{
+			name:    "ErrorUnexpectedDriverConnectionError",
+			value:   "snapshot",
+			errFunc: ErrorUnexpectedDriverConnectionError,
+			want:    "unexpected snapshot driver connection",
+		},
+		{
+			name:    "ErrorHistoryDBProblemsFoundError",
+			value:   "2",
+			errFunc: ErrorHistoryDBProblemsFoundError,
+			want:    "found 2 problems in history db",
+		},
+		{
+			name:    "ErrorHistoryFileProblemsFoundError",
+			value:   "2",
+			errFunc: ErrorHistoryFileProblemsFoundError,
+			want:    "found 2 problems in history files",
+		},
+		{
+			name:    "ErrorHistoryDBBackupsConflictError",
+			value:   "2",
+			errFunc: ErrorHistoryDBBackupsConflictError,
+			want:    "found 2 backups that already exist in history db",
+		},
+		{
+			name:    "ErrorBackupReportsUnavailableError",
+			value:   "2",
+			errFunc: ErrorBackupReportsUnavailableError,
+			want:    "unable to get 2 backup reports",
+		},
 	}
 	for _, tt := range tests {
 		err := tt.errFunc(tt.value)
@@ -21,10 +21,42 @@ func InfoTextBackupDeleteSuccess(backupName string) string {
 	return fmt.Sprintf("Backup %s successfully deleted", backupName)
 }
 
+func InfoTextBackupVerifySuccess(backupName string) string {
+	return fmt.Sprintf("Backup %s successfully verified", backupName)
+}
+
+func InfoTextOrphanBackupDirDeleteSuccess(path, host string) string {
+	return fmt.Sprintf("Orphaned backup directory %s on host %s successfully deleted", path, host)
+}
+
+func InfoTextOrphanBackupDirSkipped(path, host string) string {
+	return fmt.Sprintf("Backup directory %s on host %s is skipped, the backup could be in progress", path, host)
+}
+
+func InfoTextOrphanBackupDirDeleteConfirmation(count string) string {
+	return fmt.Sprintf("Do you want to delete %s orphaned backup directories? [y/N]: ", count)
+}
+
+func InfoTextBackupRepairSuccess(backupName string) string {
+	return fmt.Sprintf("Backup %s successfully repaired", backupName)
+}
+
+func InfoTextBackupRepair(backupName, state, action string) string {
+	return fmt.Sprintf("Backup %s files are %s, repair action: %s", backupName, state, action)
+}
+
+func InfoTextDryRunBackupRepair(backupName, state, action string) string {
+	return fmt.Sprintf("Dry run: backup %s files are %s, repair action would be: %s", backupName, state, action)
+}
+
 func InfoTextBackupDependenciesList(backupName string, list []string) string {
 	return fmt.Sprintf("Backup %s has dependent backups: %s", backupName, strings.Join(list, ", "))
 }
 
+func InfoTextBackupChainList(backupName string, list []string) string {
+	return fmt.Sprintf("Backup %s has incremental chain: %s", backupName, strings.Join(list, ", "))
+}
+
 func InfoTextBackupDeleteList(list []string) string {
 	return fmt.Sprintf("The following backups will be deleted: %s", strings.Join(list, ", "))
 }
@@ -56,3 +88,83 @@ func InfoTextNothingToDo() string {
 func InfoTextMigrateHistoryFile(action, file string) string {
 	return fmt.Sprintf("%s file migration to history database: %s", action, file)
 }
+
+func InfoTextMigrateHistoryFileResult(inserted, skipped, conflicting string) string {
+	return fmt.Sprintf("Number of migrated backups: inserted %s, skipped %s, conflicting %s", inserted, skipped, conflicting)
+}
+
+func InfoTextDryRunMigrateHistoryFile(file, count string) string {
+	return fmt.Sprintf("Dry run: history file %s contains %s backups", file, count)
+}
+
+func InfoTextDryRunMigrateBackupType(backupType, count string) string {
+	return fmt.Sprintf("Dry run: number of %s backups: %s", backupType, count)
+}
+
+func InfoTextDryRunMigrateHistoryFileResult(inserted, skipped, conflicting string) string {
+	return fmt.Sprintf("Dry run: number of migrated backups would be: inserted %s, skipped %s, conflicting %s", inserted, skipped, conflicting)
+}
+
+func InfoTextDryRunBackupDelete(backupName string) string {
+	return fmt.Sprintf("Dry run: backup %s would be deleted", backupName)
+}
+
+func InfoTextDryRunCommandExecution(list ...string) string {
+	return fmt.Sprintf("Dry run: would execute command: %s", strings.Join(list, " "))
+}
+
+func InfoTextDryRunBackupDeleteList(list []string) string {
+	return fmt.Sprintf("Dry run: the following backups would be deleted: %s", strings.Join(list, ", "))
+}
+
+func InfoTextBackupDeleteTotal(count string) string {
+	return fmt.Sprintf("Total number of backups to delete: %s", count)
+}
+
+func InfoTextBackupDeleteAffectedDatabases(list []string) string {
+	return fmt.Sprintf("Databases affected by the deletion: %s", strings.Join(list, ", "))
+}
+
+func InfoTextBackupDeleteConfirmation(count string) string {
+	return fmt.Sprintf("Do you want to delete %s backups? [y/N]: ", count)
+}
+
+func InfoTextHistoryDBNotLocked() string {
+	return "History db is not locked"
+}
+
+func InfoTextHistoryDBLockBroken(lockPath string) string {
+	return fmt.Sprintf("History db lock is broken: %s", lockPath)
+}
+
+func InfoTextHistoryDBSnapshotCreated(path string) string {
+	return fmt.Sprintf("History db snapshot is created: %s", path)
+}
+
+func InfoTextHistoryDBSnapshotRemoved(path string) string {
+	return fmt.Sprintf("History db snapshot is removed: %s", path)
+}
+
+func InfoTextHistoryDBSnapshotRestored(id string) string {
+	return fmt.Sprintf("History db is restored from snapshot %s", id)
+}
+
+func InfoTextHistoryDBNoSnapshots() string {
+	return "No history db snapshots found"
+}
+
+func InfoTextHistoryDBCheckSuccess() string {
+	return "History db check completed, no problems found"
+}
+
+func InfoTextHistoryDBProblemsFixed(count string) string {
+	return fmt.Sprintf("Number of fixed problems in history db: %s", count)
+}
+
+func InfoTextBackupFileSaved(fileName, backupName, file string) string {
+	return fmt.Sprintf("File %s for the backup %s saved to: %s", fileName, backupName, file)
+}
+
+func InfoTextHistoryExported(count, file string) string {
+	return fmt.Sprintf("Number of backups exported from history database: %s, file: %s", count, file)
+}
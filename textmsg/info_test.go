@@ -21,6 +21,18 @@ func TestInfoTextFunctionAndArg(t *testing.T) {
 			function: InfoTextBackupDeleteSuccess,
 			want:     "Backup TestBackup successfully deleted",
 		},
+		{
+			name:     "Test InfoTextBackupVerifySuccess",
+			value:    "TestBackup",
+			function: InfoTextBackupVerifySuccess,
+			want:     "Backup TestBackup successfully verified",
+		},
+		{
+			name:     "Test InfoTextBackupRepairSuccess",
+			value:    "TestBackup",
+			function: InfoTextBackupRepairSuccess,
+			want:     "Backup TestBackup successfully repaired",
+		},
 		{
 			name:     "Test InfoTextBackupAlreadyDeleted",
 			value:    "TestBackup",
@@ -39,6 +51,60 @@ func TestInfoTextFunctionAndArg(t *testing.T) {
 			function: InfoTextSegmentPrefix,
 			want:     "Segment Prefix: TestValue",
 		},
+		{
+			name:     "Test InfoTextDryRunBackupDelete",
+			value:    "TestBackup",
+			function: InfoTextDryRunBackupDelete,
+			want:     "Dry run: backup TestBackup would be deleted",
+		},
+		{
+			name:     "Test InfoTextHistoryDBLockBroken",
+			value:    "/tmp/gpbackup_history.db.lock",
+			function: InfoTextHistoryDBLockBroken,
+			want:     "History db lock is broken: /tmp/gpbackup_history.db.lock",
+		},
+		{
+			name:     "Test InfoTextBackupDeleteTotal",
+			value:    "2",
+			function: InfoTextBackupDeleteTotal,
+			want:     "Total number of backups to delete: 2",
+		},
+		{
+			name:     "Test InfoTextBackupDeleteConfirmation",
+			value:    "2",
+			function: InfoTextBackupDeleteConfirmation,
+			want:     "Do you want to delete 2 backups? [y/N]: ",
+		},
+		{
+			name:     "Test InfoTextOrphanBackupDirDeleteConfirmation",
+			value:    "2",
+			function: InfoTextOrphanBackupDirDeleteConfirmation,
+			want:     "Do you want to delete 2 orphaned backup directories? [y/N]: ",
+		},
+		{
+			name:     "Test InfoTextHistoryDBSnapshotCreated",
+			value:    "/tmp/gpbackup_history.db.20240101100000.snapshot",
+			function: InfoTextHistoryDBSnapshotCreated,
+			want:     "History db snapshot is created: /tmp/gpbackup_history.db.20240101100000.snapshot",
+		},
+		{
+			name:     "Test InfoTextHistoryDBSnapshotRemoved",
+			value:    "/tmp/gpbackup_history.db.20240101100000.snapshot",
+			function: InfoTextHistoryDBSnapshotRemoved,
+			want:     "History db snapshot is removed: /tmp/gpbackup_history.db.20240101100000.snapshot",
+		},
+		{
+			name:     "Test InfoTextHistoryDBSnapshotRestored",
+			value:    "20240101100000",
+			function: InfoTextHistoryDBSnapshotRestored,
+			want:     "History db is restored from snapshot 20240101100000",
+		},
+		{
+			name:     "Test InfoTextHistoryDBProblemsFixed",
+			value:    "2",
+			function: InfoTextHistoryDBProblemsFixed,
+			want:     "Number of fixed problems in history db: 2",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -71,6 +137,41 @@ func TestInfoTextFunctionAndTwoArgs(t *testing.T) {
 			function: InfoTextMigrateHistoryFile,
 			want:     "Start file migration to history database: /test/path",
 		},
+		{
+			name:     "Test InfoTextDryRunMigrateHistoryFile",
+			value1:   "/test/path",
+			value2:   "2",
+			function: InfoTextDryRunMigrateHistoryFile,
+			want:     "Dry run: history file /test/path contains 2 backups",
+		},
+		{
+			name:     "Test InfoTextDryRunMigrateBackupType",
+			value1:   "full",
+			value2:   "2",
+			function: InfoTextDryRunMigrateBackupType,
+			want:     "Dry run: number of full backups: 2",
+		},
+		{
+			name:     "Test InfoTextHistoryExported",
+			value1:   "2",
+			value2:   "/test/path",
+			function: InfoTextHistoryExported,
+			want:     "Number of backups exported from history database: 2, file: /test/path",
+		},
+		{
+			name:     "Test InfoTextOrphanBackupDirDeleteSuccess",
+			value1:   "/test/path",
+			value2:   "sdw1",
+			function: InfoTextOrphanBackupDirDeleteSuccess,
+			want:     "Orphaned backup directory /test/path on host sdw1 successfully deleted",
+		},
+		{
+			name:     "Test InfoTextOrphanBackupDirSkipped",
+			value1:   "/test/path",
+			value2:   "sdw1",
+			function: InfoTextOrphanBackupDirSkipped,
+			want:     "Backup directory /test/path on host sdw1 is skipped, the backup could be in progress",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -81,6 +182,65 @@ func TestInfoTextFunctionAndTwoArgs(t *testing.T) {
 	}
 }
 
+func TestInfoTextFunctionAndThreeArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		value1   string
+		value2   string
+		value3   string
+		function func(string, string, string) string
+		want     string
+	}{
+		{
+			name:     "Test InfoTextBackupRepair",
+			value1:   "TestBackup",
+			value2:   "missing",
+			value3:   "mark deleted",
+			function: InfoTextBackupRepair,
+			want:     "Backup TestBackup files are missing, repair action: mark deleted",
+		},
+		{
+			name:     "Test InfoTextDryRunBackupRepair",
+			value1:   "TestBackup",
+			value2:   "present",
+			value3:   "restore",
+			function: InfoTextDryRunBackupRepair,
+			want:     "Dry run: backup TestBackup files are present, repair action would be: restore",
+		},
+		{
+			name:     "Test InfoTextBackupFileSaved",
+			value1:   "TestFile",
+			value2:   "TestBackup",
+			value3:   "/test/path",
+			function: InfoTextBackupFileSaved,
+			want:     "File TestFile for the backup TestBackup saved to: /test/path",
+		},
+		{
+			name:     "Test InfoTextMigrateHistoryFileResult",
+			value1:   "3",
+			value2:   "1",
+			value3:   "1",
+			function: InfoTextMigrateHistoryFileResult,
+			want:     "Number of migrated backups: inserted 3, skipped 1, conflicting 1",
+		},
+		{
+			name:     "Test InfoTextDryRunMigrateHistoryFileResult",
+			value1:   "3",
+			value2:   "1",
+			value3:   "1",
+			function: InfoTextDryRunMigrateHistoryFileResult,
+			want:     "Dry run: number of migrated backups would be: inserted 3, skipped 1, conflicting 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.function(tt.value1, tt.value2, tt.value3); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestInfoTextFunctionAndMultipleArgs(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -96,6 +256,13 @@ func TestInfoTextFunctionAndMultipleArgs(t *testing.T) {
 			function:  InfoTextBackupDependenciesList,
 			want:      "Backup TestBackup1 has dependent backups: TestBackup2, TestBackup3",
 		},
+		{
+			name:      "Test InfoTextBackupChainList",
+			value:     "TestBackup1",
+			valueList: []string{"TestBackup2", "TestBackup3"},
+			function:  InfoTextBackupChainList,
+			want:      "Backup TestBackup1 has incremental chain: TestBackup2, TestBackup3",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -125,6 +292,12 @@ func TestInfoTextFunctionAndMultipleSeparateArgs(t *testing.T) {
 			function: InfoTextCommandExecutionSucceeded,
 			want:     "Command succeeded: execution_command some_argument",
 		},
+		{
+			name:     "Test InfoTextDryRunCommandExecution",
+			values:   []string{"execution_command", "some_argument"},
+			function: InfoTextDryRunCommandExecution,
+			want:     "Dry run: would execute command: execution_command some_argument",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -154,6 +327,18 @@ func TestInfoTextFunctionAndMultipleListArgs(t *testing.T) {
 			function: InfoTextBackupDeleteListFromHistory,
 			want:     "The following backups will be deleted from history: TestBackup1, TestBackup2",
 		},
+		{
+			name:     "Test InfoTextDryRunBackupDeleteList",
+			values:   []string{"TestBackup1", "TestBackup2"},
+			function: InfoTextDryRunBackupDeleteList,
+			want:     "Dry run: the following backups would be deleted: TestBackup1, TestBackup2",
+		},
+		{
+			name:     "Test InfoTextBackupDeleteAffectedDatabases",
+			values:   []string{"test1", "test2"},
+			function: InfoTextBackupDeleteAffectedDatabases,
+			want:     "Databases affected by the deletion: test1, test2",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -175,6 +360,21 @@ func TestInfoTextFunction(t *testing.T) {
 			function: InfoTextNothingToDo,
 			want:     "Nothing to do",
 		},
+		{
+			name:     "Test InfoTextHistoryDBNotLocked",
+			function: InfoTextHistoryDBNotLocked,
+			want:     "History db is not locked",
+		},
+		{
+			name:     "Test InfoTextHistoryDBNoSnapshots",
+			function: InfoTextHistoryDBNoSnapshots,
+			want:     "No history db snapshots found",
+		},
+		{
+			name:     "Test InfoTextHistoryDBCheckSuccess",
+			function: InfoTextHistoryDBCheckSuccess,
+			want:     "History db check completed, no problems found",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,7 +1,22 @@
 package textmsg
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func WarnTextBackupUnableGetReport(backupName string) string {
 	return fmt.Sprintf("Unable to get report for backup %s. Check if backup is active", backupName)
 }
+
+func WarnTextBackupProblem(backupName, problem, details string) string {
+	return fmt.Sprintf("Backup %s has problem: %s, details: %s", backupName, problem, details)
+}
+
+func WarnTextBackupUnableGetToc(backupName string) string {
+	return fmt.Sprintf("Unable to get toc for backup %s. Only tables from the restore plan are used", backupName)
+}
+
+func WarnTextBackupChainNotActive(backupName string, list []string) string {
+	return fmt.Sprintf("Backup %s is skipped, backups from its restore plan are not active: %s", backupName, strings.Join(list, ", "))
+}
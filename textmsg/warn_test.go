@@ -17,6 +17,12 @@ func TestWarnTextFunctionsWarnAndArg(t *testing.T) {
 			function: WarnTextBackupUnableGetReport,
 			want:     "Unable to get report for backup TestBackup. Check if backup is active",
 		},
+		{
+			name:     "Test WarnTextBackupUnableGetToc",
+			value:    "TestBackup",
+			function: WarnTextBackupUnableGetToc,
+			want:     "Unable to get toc for backup TestBackup. Only tables from the restore plan are used",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -26,3 +32,55 @@ func TestWarnTextFunctionsWarnAndArg(t *testing.T) {
 		})
 	}
 }
+
+func TestWarnTextFunctionsWarnAndThreeArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		value1   string
+		value2   string
+		value3   string
+		function func(string, string, string) string
+		want     string
+	}{
+		{
+			name:     "Test WarnTextBackupProblem",
+			value1:   "TestBackup",
+			value2:   "invalid status",
+			value3:   "Unknown",
+			function: WarnTextBackupProblem,
+			want:     "Backup TestBackup has problem: invalid status, details: Unknown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.function(tt.value1, tt.value2, tt.value3); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWarnTextFunctionsWarnAndList(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		list     []string
+		function func(string, []string) string
+		want     string
+	}{
+		{
+			name:     "Test WarnTextBackupChainNotActive",
+			value:    "TestBackup",
+			list:     []string{"TestBackup1", "TestBackup2"},
+			function: WarnTextBackupChainNotActive,
+			want:     "Backup TestBackup is skipped, backups from its restore plan are not active: TestBackup1, TestBackup2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.function(tt.value, tt.list); got != tt.want {
+				t.Errorf("\nVariables do not match:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}